@@ -1,16 +1,26 @@
 package main
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
+	"io/ioutil"
+	"strings"
+	"time"
 
+	"git.containerum.net/ch/user-manager/pkg/models"
 	"git.containerum.net/ch/user-manager/pkg/server"
 	"git.containerum.net/ch/user-manager/pkg/server/impl"
+	"git.containerum.net/ch/user-manager/pkg/utils"
+	"git.containerum.net/ch/user-manager/pkg/validation"
 
 	"fmt"
 
 	"git.containerum.net/ch/user-manager/pkg/clients"
 	"git.containerum.net/ch/user-manager/pkg/db"
 	"git.containerum.net/ch/user-manager/pkg/db/postgres"
+	"git.containerum.net/ch/user-manager/pkg/router"
+	"git.containerum.net/ch/user-manager/pkg/router/middleware"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli"
@@ -21,8 +31,16 @@ const (
 	serviceClientDummy = "dummy"
 )
 
+// captcha providers, "http" means recaptcha for compatibility
+const (
+	captchaReCaptcha = "recaptcha"
+	captchaHCaptcha  = "hcaptcha"
+	captchaTurnstile = "turnstile"
+)
+
 const (
 	portFlag              = "port"
+	debugAddrFlag         = "debug_addr"
 	debugFlag             = "debug"
 	textlogFlag           = "textlog"
 	dbFlag                = "db"
@@ -50,6 +68,78 @@ const (
 	umFlag                = "user_manager"
 	corsFlag              = "cors"
 	adminPwdFlag          = "admin_password"
+	restorePeriodFlag     = "restore_period"
+	janitorIntervalFlag   = "janitor_interval"
+	janitorRetentionFlag  = "janitor_retention"
+	janitorBatchSizeFlag  = "janitor_batch_size"
+
+	memberExpiryNoticeDaysFlag = "member_expiry_notice_days"
+
+	linkConfirmLifetimeFlag         = "link_confirm_lifetime"
+	linkConfirmResendCooldownFlag   = "link_confirm_resend_cooldown"
+	linkConfirmMaxResendsFlag       = "link_confirm_max_resends"
+	linkPwdChangeLifetimeFlag       = "link_pwd_change_lifetime"
+	linkPwdChangeResendCooldownFlag = "link_pwd_change_resend_cooldown"
+	linkPwdChangeMaxResendsFlag     = "link_pwd_change_max_resends"
+	linkSigningKeysFlag             = "link_signing_keys"
+	inviteLifetimeFlag              = "invite_lifetime"
+	inviteSigningKeysFlag           = "invite_signing_keys"
+	impersonationTTLFlag            = "impersonation_ttl"
+
+	passwordMinLengthFlag     = "password_min_length"
+	passwordRequireLowerFlag  = "password_require_lower"
+	passwordRequireUpperFlag  = "password_require_upper"
+	passwordRequireDigitFlag  = "password_require_digit"
+	passwordRequireSymbolFlag = "password_require_symbol"
+	passwordHistorySizeFlag   = "password_history_size"
+	breachedPasswordsFileFlag = "breached_passwords_file"
+
+	passwordMaxAgeDaysFlag       = "password_max_age_days"
+	passwordExpiryNoticeDaysFlag = "password_expiry_notice_days"
+
+	captchaFailureThresholdFlag = "captcha_failure_threshold"
+	captchaFailureWindowFlag    = "captcha_failure_window"
+
+	domainAllowlistFlag = "domain_allowlist"
+	ipAllowlistFlag     = "ip_allowlist"
+
+	rateLimitStoreFlag         = "ratelimit_store"
+	rateLimitMemorySizeFlag    = "ratelimit_memory_size"
+	rateLimitSignUpFlag        = "ratelimit_sign_up"
+	rateLimitResendFlag        = "ratelimit_resend"
+	rateLimitPasswordResetFlag = "ratelimit_password_reset"
+	rateLimitLoginFlag         = "ratelimit_login"
+
+	privacyModeFlag         = "privacy_mode"
+	privacyResponseTimeFlag = "privacy_response_time"
+
+	userCacheTTLFlag    = "user_cache_ttl"
+	userCacheSizeFlag   = "user_cache_size"
+	userCacheNotifyFlag = "user_cache_notify"
+
+	tlsCertFlag     = "tls_cert"
+	tlsKeyFlag      = "tls_key"
+	tlsClientCAFlag = "tls_client_ca"
+
+	internalAuthRoutesFlag          = "internal_auth_routes"
+	internalAuthHMACKeysFlag        = "internal_auth_hmac_keys"
+	internalAuthMaxClockSkewFlag    = "internal_auth_max_clock_skew"
+	internalAuthClientCertNamesFlag = "internal_auth_client_cert_names"
+
+	jwtKeyFlag         = "jwt_key"
+	jwtJWKSURLFlag     = "jwt_jwks_url"
+	jwtIssuerFlag      = "jwt_issuer"
+	jwtAudienceFlag    = "jwt_audience"
+	jwtLeewayFlag      = "jwt_leeway"
+	jwtUserIDClaimFlag = "jwt_user_id_claim"
+	jwtRoleClaimFlag   = "jwt_role_claim"
+)
+
+// rate limit stores
+const (
+	rateLimitStoreMemory   = "memory"
+	rateLimitStorePostgres = "postgres"
+	rateLimitStoreNone     = "none"
 )
 
 var flags = []cli.Flag{
@@ -59,6 +149,12 @@ var flags = []cli.Flag{
 		Value:  "8111",
 		Usage:  "port for solutions server",
 	},
+	cli.StringFlag{
+		EnvVar: "DEBUG_ADDR",
+		Name:   debugAddrFlag,
+		Value:  "localhost:8112",
+		Usage:  "Address of metrics server (/debug/vars), must not be reachable from outside because flag values are exposed, empty to disable",
+	},
 	cli.BoolFlag{
 		EnvVar: "DEBUG",
 		Name:   debugFlag,
@@ -122,12 +218,12 @@ var flags = []cli.Flag{
 		EnvVar: "RECAPTCHA",
 		Name:   recaptchaFlag,
 		Value:  serviceClientHTTP,
-		Usage:  "Recaptcha kind",
+		Usage:  "Captcha provider (recaptcha, hcaptcha, turnstile or dummy, http means recaptcha)",
 	},
 	cli.StringFlag{
 		EnvVar: "RECAPTCHA_KEY",
 		Name:   recaptchaKeyFlag,
-		Usage:  "Recaptcha key",
+		Usage:  "Captcha provider secret key",
 	},
 	cli.StringFlag{
 		EnvVar: "OAUTH_CLIENTS",
@@ -206,6 +302,302 @@ var flags = []cli.Flag{
 		Name:   adminPwdFlag,
 		Usage:  "Admin password",
 	},
+	cli.DurationFlag{
+		EnvVar: "RESTORE_PERIOD",
+		Name:   restorePeriodFlag,
+		Value:  30 * 24 * time.Hour,
+		Usage:  "Period during which partially deleted account can be restored",
+	},
+	cli.DurationFlag{
+		EnvVar: "JANITOR_INTERVAL",
+		Name:   janitorIntervalFlag,
+		Value:  time.Hour,
+		Usage:  "Interval between expired links and tokens cleanups (0 to disable)",
+	},
+	cli.DurationFlag{
+		EnvVar: "JANITOR_RETENTION",
+		Name:   janitorRetentionFlag,
+		Value:  7 * 24 * time.Hour,
+		Usage:  "How long expired links and inactive tokens are kept before removal",
+	},
+	cli.IntFlag{
+		EnvVar: "JANITOR_BATCH_SIZE",
+		Name:   janitorBatchSizeFlag,
+		Value:  1000,
+		Usage:  "Maximum number of rows removed by one cleanup query",
+	},
+	cli.DurationFlag{
+		EnvVar: "LINK_CONFIRM_LIFETIME",
+		Name:   linkConfirmLifetimeFlag,
+		Value:  24 * time.Hour,
+		Usage:  "Lifetime of account confirmation link",
+	},
+	cli.DurationFlag{
+		EnvVar: "LINK_CONFIRM_RESEND_COOLDOWN",
+		Name:   linkConfirmResendCooldownFlag,
+		Value:  5 * time.Minute,
+		Usage:  "Minimal interval between account confirmation link sends",
+	},
+	cli.IntFlag{
+		EnvVar: "LINK_CONFIRM_MAX_RESENDS",
+		Name:   linkConfirmMaxResendsFlag,
+		Value:  10,
+		Usage:  "Maximum number of account confirmation link resends (0 for unlimited)",
+	},
+	cli.DurationFlag{
+		EnvVar: "LINK_PWD_CHANGE_LIFETIME",
+		Name:   linkPwdChangeLifetimeFlag,
+		Value:  24 * time.Hour,
+		Usage:  "Lifetime of password reset link",
+	},
+	cli.DurationFlag{
+		EnvVar: "LINK_PWD_CHANGE_RESEND_COOLDOWN",
+		Name:   linkPwdChangeResendCooldownFlag,
+		Value:  time.Minute,
+		Usage:  "Minimal interval between password reset link sends",
+	},
+	cli.IntFlag{
+		EnvVar: "LINK_PWD_CHANGE_MAX_RESENDS",
+		Name:   linkPwdChangeMaxResendsFlag,
+		Value:  10,
+		Usage:  "Maximum number of password reset link resends (0 for unlimited)",
+	},
+	cli.StringFlag{
+		EnvVar: "LINK_SIGNING_KEYS",
+		Name:   linkSigningKeysFlag,
+		Usage:  "Comma-separated key_id:secret pairs to sign links with. First key signs new links, others are used only for verification. Empty value disables signed links",
+	},
+	cli.IntFlag{
+		EnvVar: "MEMBER_EXPIRY_NOTICE_DAYS",
+		Name:   memberExpiryNoticeDaysFlag,
+		Value:  3,
+		Usage:  "Notify group owners by mail this number of days before group membership expires (0 to disable)",
+	},
+	cli.DurationFlag{
+		EnvVar: "INVITE_LIFETIME",
+		Name:   inviteLifetimeFlag,
+		Value:  7 * 24 * time.Hour,
+		Usage:  "Lifetime of group invite",
+	},
+	cli.StringFlag{
+		EnvVar: "INVITE_SIGNING_KEYS",
+		Name:   inviteSigningKeysFlag,
+		Usage:  "Comma-separated key_id:secret pairs to sign group invites with. Link signing keys are used if empty. Group invites are disabled if no keys provided",
+	},
+	cli.DurationFlag{
+		EnvVar: "IMPERSONATION_TTL",
+		Name:   impersonationTTLFlag,
+		Value:  15 * time.Minute,
+		Usage:  "Lifetime of tokens issued by admin impersonation",
+	},
+	cli.IntFlag{
+		EnvVar: "PASSWORD_MIN_LENGTH",
+		Name:   passwordMinLengthFlag,
+		Usage:  "Minimal password length (0 to disable)",
+	},
+	cli.BoolFlag{
+		EnvVar: "PASSWORD_REQUIRE_LOWER",
+		Name:   passwordRequireLowerFlag,
+		Usage:  "Require lowercase letter in password",
+	},
+	cli.BoolFlag{
+		EnvVar: "PASSWORD_REQUIRE_UPPER",
+		Name:   passwordRequireUpperFlag,
+		Usage:  "Require uppercase letter in password",
+	},
+	cli.BoolFlag{
+		EnvVar: "PASSWORD_REQUIRE_DIGIT",
+		Name:   passwordRequireDigitFlag,
+		Usage:  "Require digit in password",
+	},
+	cli.BoolFlag{
+		EnvVar: "PASSWORD_REQUIRE_SYMBOL",
+		Name:   passwordRequireSymbolFlag,
+		Usage:  "Require special character in password",
+	},
+	cli.IntFlag{
+		EnvVar: "PASSWORD_HISTORY_SIZE",
+		Name:   passwordHistorySizeFlag,
+		Usage:  "Number of last passwords which can't be reused (0 to disable)",
+	},
+	cli.StringFlag{
+		EnvVar: "BREACHED_PASSWORDS_FILE",
+		Name:   breachedPasswordsFileFlag,
+		Usage:  "File with breached passwords SHA-1 hashes sorted by hash, one per line (Have I Been Pwned \"ordered by hash\" format), " +
+			"or directory of range files named by 5 first hash characters (k-anonymity range format). Check is disabled if empty",
+	},
+	cli.IntFlag{
+		EnvVar: "PASSWORD_MAX_AGE_DAYS",
+		Name:   passwordMaxAgeDaysFlag,
+		Usage:  "Number of days after which user must change password (0 to disable)",
+	},
+	cli.IntFlag{
+		EnvVar: "PASSWORD_EXPIRY_NOTICE_DAYS",
+		Name:   passwordExpiryNoticeDaysFlag,
+		Value:  7,
+		Usage:  "Notify users by mail this number of days before password expires",
+	},
+	cli.IntFlag{
+		EnvVar: "CAPTCHA_FAILURE_THRESHOLD",
+		Name:   captchaFailureThresholdFlag,
+		Value:  5,
+		Usage:  "Require captcha on login and password reset after this number of failed attempts from login or IP (0 to disable)",
+	},
+	cli.DurationFlag{
+		EnvVar: "CAPTCHA_FAILURE_WINDOW",
+		Name:   captchaFailureWindowFlag,
+		Value:  time.Hour,
+		Usage:  "Period during which failed login attempts are counted",
+	},
+	cli.BoolFlag{
+		EnvVar: "DOMAIN_ALLOWLIST",
+		Name:   domainAllowlistFlag,
+		Usage:  "Allow registration only for emails matching allow domain rules (for private installs)",
+	},
+	cli.BoolFlag{
+		EnvVar: "IP_ALLOWLIST",
+		Name:   ipAllowlistFlag,
+		Usage:  "Allow registration and login only from networks matching allow IP rules",
+	},
+	cli.StringFlag{
+		EnvVar: "RATELIMIT_STORE",
+		Name:   rateLimitStoreFlag,
+		Value:  rateLimitStoreMemory,
+		Usage:  "Rate limit buckets store: memory (per replica), postgres (shared between replicas) or none. Limits by ip trust X-Client-IP header set by gateway",
+	},
+	cli.IntFlag{
+		EnvVar: "RATELIMIT_MEMORY_SIZE",
+		Name:   rateLimitMemorySizeFlag,
+		Value:  100000,
+		Usage:  "Maximum number of rate limit buckets kept by memory store, least recently used are evicted",
+	},
+	cli.StringFlag{
+		EnvVar: "RATELIMIT_SIGN_UP",
+		Name:   rateLimitSignUpFlag,
+		Value:  "ip:10/1h",
+		Usage:  "Sign up rate limits in format key:burst/period, keys are ip and login",
+	},
+	cli.StringFlag{
+		EnvVar: "RATELIMIT_RESEND",
+		Name:   rateLimitResendFlag,
+		Value:  "ip:10/1h,login:3/1h",
+		Usage:  "Activation link resend rate limits in format key:burst/period, keys are ip and login",
+	},
+	cli.StringFlag{
+		EnvVar: "RATELIMIT_PASSWORD_RESET",
+		Name:   rateLimitPasswordResetFlag,
+		Value:  "ip:10/1h,login:3/1h",
+		Usage:  "Password reset and restore rate limits in format key:burst/period, keys are ip and login",
+	},
+	cli.StringFlag{
+		EnvVar: "RATELIMIT_LOGIN",
+		Name:   rateLimitLoginFlag,
+		Value:  "ip:30/1m",
+		Usage:  "Login rate limits in format key:burst/period, keys are ip and login",
+	},
+	cli.BoolFlag{
+		EnvVar: "PRIVACY_MODE",
+		Name:   privacyModeFlag,
+		Usage:  "Hide account existence in sign up, password reset and link resend responses, restrict user lookups to services",
+	},
+	cli.DurationFlag{
+		EnvVar: "PRIVACY_RESPONSE_TIME",
+		Name:   privacyResponseTimeFlag,
+		Value:  time.Second,
+		Usage:  "Minimal response time of endpoints affected by privacy mode",
+	},
+	cli.DurationFlag{
+		EnvVar: "USER_CACHE_TTL",
+		Name:   userCacheTTLFlag,
+		Value:  10 * time.Second,
+		Usage:  "Time during which users loaded for permission checks are cached, 0 to disable",
+	},
+	cli.IntFlag{
+		EnvVar: "USER_CACHE_SIZE",
+		Name:   userCacheSizeFlag,
+		Value:  10000,
+		Usage:  "Maximum number of cached users",
+	},
+	cli.BoolFlag{
+		EnvVar: "USER_CACHE_NOTIFY",
+		Name:   userCacheNotifyFlag,
+		Usage:  "Broadcast user changes to other replicas using Postgres LISTEN/NOTIFY",
+	},
+	cli.StringFlag{
+		EnvVar: "TLS_CERT",
+		Name:   tlsCertFlag,
+		Usage:  "Server certificate file, server listens HTTPS if set",
+	},
+	cli.StringFlag{
+		EnvVar: "TLS_KEY",
+		Name:   tlsKeyFlag,
+		Usage:  "Server certificate key file",
+	},
+	cli.StringFlag{
+		EnvVar: "TLS_CLIENT_CA",
+		Name:   tlsClientCAFlag,
+		Usage:  "CA certificates file to verify internal services client certificates",
+	},
+	cli.StringFlag{
+		EnvVar: "INTERNAL_AUTH_ROUTES",
+		Name:   internalAuthRoutesFlag,
+		Value:  "POST /user/loginid,GET /user/info/id/:user_id,GET /user/info/login/:login,POST /groups/:group",
+		Usage:  "Comma separated routes which require internal services authentication, \"*\" at the end matches any path suffix",
+	},
+	cli.StringFlag{
+		EnvVar: "INTERNAL_AUTH_HMAC_KEYS",
+		Name:   internalAuthHMACKeysFlag,
+		Usage:  "Comma separated shared keys for internal requests signatures, several keys may be set for rotation. Identity headers of not authenticated requests are ignored if internal auth is enabled",
+	},
+	cli.DurationFlag{
+		EnvVar: "INTERNAL_AUTH_MAX_CLOCK_SKEW",
+		Name:   internalAuthMaxClockSkewFlag,
+		Value:  5 * time.Minute,
+		Usage:  "Maximal age of signed internal request, each signed request is accepted once during this period",
+	},
+	cli.StringFlag{
+		EnvVar: "INTERNAL_AUTH_CLIENT_CERT_NAMES",
+		Name:   internalAuthClientCertNamesFlag,
+		Usage:  "Comma separated accepted internal services client certificate names, any certificate signed by client CA is accepted if empty",
+	},
+	cli.StringFlag{
+		EnvVar: "JWT_KEY",
+		Name:   jwtKeyFlag,
+		Usage:  "Shared key of access tokens, identity is taken from tokens instead of headers if set",
+	},
+	cli.StringFlag{
+		EnvVar: "JWT_JWKS_URL",
+		Name:   jwtJWKSURLFlag,
+		Usage:  "URL of access tokens public keys set, identity is taken from tokens instead of headers if set",
+	},
+	cli.StringFlag{
+		EnvVar: "JWT_ISSUER",
+		Name:   jwtIssuerFlag,
+		Usage:  "Expected access tokens issuer",
+	},
+	cli.StringFlag{
+		EnvVar: "JWT_AUDIENCE",
+		Name:   jwtAudienceFlag,
+		Usage:  "Expected access tokens audience",
+	},
+	cli.DurationFlag{
+		EnvVar: "JWT_LEEWAY",
+		Name:   jwtLeewayFlag,
+		Value:  30 * time.Second,
+		Usage:  "Allowed clock difference with access tokens issuer",
+	},
+	cli.StringFlag{
+		EnvVar: "JWT_USER_ID_CLAIM",
+		Name:   jwtUserIDClaimFlag,
+		Value:  "user_id",
+		Usage:  "Access token claim containing user ID",
+	},
+	cli.StringFlag{
+		EnvVar: "JWT_ROLE_CLAIM",
+		Name:   jwtRoleClaimFlag,
+		Value:  "role",
+		Usage:  "Access token claim containing user role",
+	},
 }
 
 func setupLogs(c *cli.Context) {
@@ -231,12 +623,124 @@ func getDB(c *cli.Context) (db.DB, error) {
 		if c.Bool(dbPGNoSSLFlag) {
 			url = url + "?sslmode=disable"
 		}
-		return postgres.DBConnect(url, c.String(dbMigrationsFlag))
+		keyring, err := utils.NewLinkKeyring(c.String(linkSigningKeysFlag))
+		if err != nil {
+			return nil, err
+		}
+		return postgres.DBConnect(url, c.String(dbMigrationsFlag), keyring)
 	default:
 		return nil, errors.New("invalid db")
 	}
 }
 
+func getRateLimiter(c *cli.Context, database db.DB) (*middleware.RateLimiter, error) {
+	rl := &middleware.RateLimiter{
+		Limits: make(map[string][]middleware.RateLimit),
+	}
+	switch c.String(rateLimitStoreFlag) {
+	case rateLimitStoreMemory:
+		size := c.Int(rateLimitMemorySizeFlag)
+		if size <= 0 {
+			return nil, fmt.Errorf("%v must be positive", rateLimitMemorySizeFlag)
+		}
+		rl.Store = middleware.NewMemoryRateLimitStore(size)
+	case rateLimitStorePostgres:
+		rl.Store = middleware.NewDBRateLimitStore(database)
+	case rateLimitStoreNone:
+		return nil, nil
+	default:
+		return nil, errors.New("invalid rate limit store")
+	}
+	for group, flag := range map[string]string{
+		router.RateLimitGroupSignUp:        rateLimitSignUpFlag,
+		router.RateLimitGroupResend:        rateLimitResendFlag,
+		router.RateLimitGroupPasswordReset: rateLimitPasswordResetFlag,
+		router.RateLimitGroupLogin:         rateLimitLoginFlag,
+	} {
+		limits, err := middleware.ParseRateLimits(c.String(flag))
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", flag, err)
+		}
+		rl.Limits[group] = limits
+	}
+	return rl, nil
+}
+
+// splitList splits comma separated list skipping empty elements.
+func splitList(s string) []string {
+	var ret []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
+
+func getTLSConfig(c *cli.Context) (*tls.Config, error) {
+	if c.String(tlsCertFlag) == "" {
+		return nil, nil
+	}
+	cfg := &tls.Config{}
+	if caFile := c.String(tlsClientCAFlag); caFile != "" {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.ClientCAs = x509.NewCertPool()
+		if !cfg.ClientCAs.AppendCertsFromPEM(pem) {
+			return nil, errors.New("no certificates found in client CA file")
+		}
+		// clients without certificates are allowed, internal routes are protected by middleware
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return cfg, nil
+}
+
+func getInternalAuth(c *cli.Context) (*middleware.InternalAuth, error) {
+	ia := &middleware.InternalAuth{
+		MaxClockSkew:    c.Duration(internalAuthMaxClockSkewFlag),
+		ClientCertNames: splitList(c.String(internalAuthClientCertNamesFlag)),
+		Routes:          splitList(c.String(internalAuthRoutesFlag)),
+	}
+	for _, key := range splitList(c.String(internalAuthHMACKeysFlag)) {
+		ia.HMACKeys = append(ia.HMACKeys, []byte(key))
+	}
+	if len(ia.HMACKeys) == 0 && c.String(tlsClientCAFlag) == "" {
+		logrus.Warnln("Internal auth is not configured, internal routes are not protected")
+		return nil, nil
+	}
+	if c.String(tlsClientCAFlag) != "" && c.String(tlsCertFlag) == "" {
+		return nil, errors.New("client certificates verification requires TLS server certificate")
+	}
+	return ia, nil
+}
+
+func getJWTIdentity(c *cli.Context) (*middleware.JWTIdentity, error) {
+	var verifier *utils.JWTVerifier
+	switch {
+	case c.String(jwtKeyFlag) != "" && c.String(jwtJWKSURLFlag) != "":
+		return nil, errors.New("only one of JWT key and JWKS URL may be set")
+	case c.String(jwtKeyFlag) != "":
+		verifier = utils.NewSharedKeyJWTVerifier([]byte(c.String(jwtKeyFlag)))
+	case c.String(jwtJWKSURLFlag) != "":
+		var err error
+		if verifier, err = utils.NewJWKSVerifier(c.String(jwtJWKSURLFlag)); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, nil
+	}
+	verifier.Issuer = c.String(jwtIssuerFlag)
+	verifier.Audience = c.String(jwtAudienceFlag)
+	verifier.Leeway = c.Duration(jwtLeewayFlag)
+	return &middleware.JWTIdentity{
+		Verifier:    verifier,
+		UserIDClaim: c.String(jwtUserIDClaimFlag),
+		RoleClaim:   c.String(jwtRoleClaimFlag),
+	}, nil
+}
+
 func getMailClient(c *cli.Context) (clients.MailClient, error) {
 	switch c.String(mailFlag) {
 	case serviceClientHTTP:
@@ -246,14 +750,18 @@ func getMailClient(c *cli.Context) (clients.MailClient, error) {
 	}
 }
 
-func getReCaptchaClient(c *cli.Context) (clients.ReCaptchaClient, error) {
+func getCaptchaClient(c *cli.Context) (clients.CaptchaClient, error) {
 	switch c.String(recaptchaFlag) {
-	case serviceClientHTTP:
+	case serviceClientHTTP, captchaReCaptcha:
 		return clients.NewHTTPReCaptchaClient(c.String(recaptchaKeyFlag)), nil
+	case captchaHCaptcha:
+		return clients.NewHTTPHCaptchaClient(c.String(recaptchaKeyFlag)), nil
+	case captchaTurnstile:
+		return clients.NewHTTPTurnstileClient(c.String(recaptchaKeyFlag)), nil
 	case serviceClientDummy:
-		return clients.NewDummyReCaptchaClient(), nil
+		return clients.NewDummyCaptchaClient(), nil
 	default:
-		return nil, errors.New("invalid reCaptcha client")
+		return nil, errors.New("invalid captcha client")
 	}
 }
 
@@ -308,7 +816,65 @@ func getEventsClient(c *cli.Context) (clients.EventsClient, error) {
 func getUserManager(c *cli.Context, services server.Services) (server.UserManager, error) {
 	switch c.String(umFlag) {
 	case "impl":
-		return impl.NewUserManagerImpl(services), nil
+		if c.Int(janitorBatchSizeFlag) <= 0 {
+			return nil, errors.New("janitor batch size must be positive")
+		}
+		inviteKeys := c.String(inviteSigningKeysFlag)
+		if inviteKeys == "" {
+			inviteKeys = c.String(linkSigningKeysFlag)
+		}
+		inviteKeyring, err := utils.NewLinkKeyring(inviteKeys)
+		if err != nil {
+			return nil, err
+		}
+		return impl.NewUserManagerImpl(services, server.Config{
+			RestorePeriod:    c.Duration(restorePeriodFlag),
+			JanitorInterval:  c.Duration(janitorIntervalFlag),
+			JanitorRetention: c.Duration(janitorRetentionFlag),
+			JanitorBatchSize: c.Int(janitorBatchSizeFlag),
+			LinkPolicies: map[models.LinkType]server.LinkPolicy{
+				models.LinkTypeConfirm: {
+					Lifetime:       c.Duration(linkConfirmLifetimeFlag),
+					ResendCooldown: c.Duration(linkConfirmResendCooldownFlag),
+					MaxResends:     c.Int(linkConfirmMaxResendsFlag),
+				},
+				models.LinkTypePwdChange: {
+					Lifetime:       c.Duration(linkPwdChangeLifetimeFlag),
+					ResendCooldown: c.Duration(linkPwdChangeResendCooldownFlag),
+					MaxResends:     c.Int(linkPwdChangeMaxResendsFlag),
+				},
+				models.LinkTypeDelete: {
+					Lifetime: c.Duration(restorePeriodFlag),
+				},
+			},
+			MemberExpiryNotice: time.Duration(c.Int(memberExpiryNoticeDaysFlag)) * 24 * time.Hour,
+			InviteLifetime:     c.Duration(inviteLifetimeFlag),
+			InviteKeyring:      inviteKeyring,
+			ImpersonationTTL:   c.Duration(impersonationTTLFlag),
+			PasswordPolicy: validation.PasswordPolicy{
+				MinLength:     c.Int(passwordMinLengthFlag),
+				RequireLower:  c.Bool(passwordRequireLowerFlag),
+				RequireUpper:  c.Bool(passwordRequireUpperFlag),
+				RequireDigit:  c.Bool(passwordRequireDigitFlag),
+				RequireSymbol: c.Bool(passwordRequireSymbolFlag),
+				HistorySize:   c.Int(passwordHistorySizeFlag),
+			},
+			PasswordMaxAge:       time.Duration(c.Int(passwordMaxAgeDaysFlag)) * 24 * time.Hour,
+			PasswordExpiryNotice: time.Duration(c.Int(passwordExpiryNoticeDaysFlag)) * 24 * time.Hour,
+
+			CaptchaFailureThreshold: c.Int(captchaFailureThresholdFlag),
+			CaptchaFailureWindow:    c.Duration(captchaFailureWindowFlag),
+
+			DomainAllowlist: c.Bool(domainAllowlistFlag),
+			IPAllowlist:     c.Bool(ipAllowlistFlag),
+
+			PrivacyMode:         c.Bool(privacyModeFlag),
+			PrivacyResponseTime: c.Duration(privacyResponseTimeFlag),
+
+			UserCacheTTL:    c.Duration(userCacheTTLFlag),
+			UserCacheSize:   c.Int(userCacheSizeFlag),
+			UserCacheNotify: c.Bool(userCacheNotifyFlag),
+		}), nil
 	default:
 		return nil, errors.New("invalid user manager impl")
 	}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"git.containerum.net/ch/user-manager/pkg/db"
+	"git.containerum.net/ch/user-manager/pkg/models"
+	"git.containerum.net/ch/user-manager/pkg/utils"
+	"git.containerum.net/ch/user-manager/pkg/validation"
+	"github.com/urfave/cli"
+)
+
+const (
+	importFileFlag   = "file"
+	importKindFlag   = "kind"
+	importListFlag   = "list"
+	importReasonFlag = "reason"
+)
+
+var importDomainsCommand = cli.Command{
+	Name:  "import-domains",
+	Usage: "import domain rules from file with one domain per line (public disposable domains lists format)",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  importFileFlag,
+			Usage: "domains list file, \"-\" to read from stdin",
+		},
+		cli.StringFlag{
+			Name:  importKindFlag,
+			Value: models.DomainRuleSuffix,
+			Usage: "rule kind: exact or suffix",
+		},
+		cli.StringFlag{
+			Name:  importListFlag,
+			Value: models.DomainListBlock,
+			Usage: "list to import to: block or allow",
+		},
+		cli.StringFlag{
+			Name:  importReasonFlag,
+			Usage: "reason saved with every imported rule",
+		},
+	},
+	Action: importDomains,
+}
+
+func importDomains(c *cli.Context) error {
+	// database flags are global
+	setupLogs(c.Parent())
+
+	var src io.Reader
+	switch file := c.String(importFileFlag); file {
+	case "":
+		return fmt.Errorf("flag %v is required", importFileFlag)
+	case "-":
+		src = os.Stdin
+	default:
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		src = f
+	}
+
+	domains, err := utils.ParseDomainList(src)
+	if err != nil {
+		return err
+	}
+	request := models.DomainImportRequest{
+		Domains: domains,
+		Kind:    c.String(importKindFlag),
+		List:    c.String(importListFlag),
+		Reason:  c.String(importReasonFlag),
+	}
+	if errs := validation.ValidateDomainImport(request); errs != nil {
+		return fmt.Errorf("invalid import request: %v", errs)
+	}
+
+	entries := make([]db.DomainBlacklistEntry, 0, len(domains))
+	for _, domain := range domains {
+		entries = append(entries, db.NewDomainRule(domain, request.Kind, request.List, request.Reason, "", nil))
+	}
+
+	database, err := getDB(c.Parent())
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	var imported int
+	err = database.Transactional(context.Background(), func(ctx context.Context, tx db.DB) (err error) {
+		imported, err = tx.ImportDomains(ctx, entries)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Imported %d domains, skipped %d already existing\n", imported, len(entries)-imported)
+	return nil
+}
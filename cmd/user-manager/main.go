@@ -23,6 +23,7 @@ func main() {
 	fmt.Printf("Starting %v %v\n", app.Name, app.Version)
 
 	app.Action = initServer
+	app.Commands = []cli.Command{importDomainsCommand}
 
 	if err := app.Run(os.Args); err != nil {
 		fmt.Fprintln(os.Stderr, err)
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"context"
+	"expvar"
 	"net/http"
 	"os/signal"
 
@@ -16,6 +17,7 @@ import (
 	"git.containerum.net/ch/user-manager/pkg/db"
 	"git.containerum.net/ch/user-manager/pkg/router"
 	"git.containerum.net/ch/user-manager/pkg/server"
+	"git.containerum.net/ch/user-manager/pkg/utils"
 	"github.com/containerum/kube-client/pkg/model"
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli"
@@ -36,14 +38,23 @@ func initServer(c *cli.Context) error {
 	tgClient, err := getTelegramClient(c)
 	exitOnErr(err)
 
+	breachedPasswords, err := utils.OpenBreachedPasswords(c.String(breachedPasswordsFileFlag))
+	exitOnErr(err)
+
+	database := getService(getDB(c)).(db.DB)
+
+	rateLimiter, err := getRateLimiter(c, database)
+	exitOnErr(err)
+
 	userManager, err := getUserManager(c, server.Services{
 		MailClient:        getService(getMailClient(c)).(clients.MailClient),
-		DB:                getService(getDB(c)).(db.DB),
+		DB:                database,
 		AuthClient:        getService(getAuthClient(c)).(clients.AuthClient),
-		ReCaptchaClient:   getService(getReCaptchaClient(c)).(clients.ReCaptchaClient),
+		CaptchaClient:     getService(getCaptchaClient(c)).(clients.CaptchaClient),
 		PermissionsClient: getService(getPermissionsClient(c)).(clients.PermissionsClient),
 		EventsClient:      getService(getEventsClient(c)).(clients.EventsClient),
 		TelegramClient:    tgClient,
+		BreachedPasswords: breachedPasswords,
 	})
 	exitOnErr(err)
 	defer userManager.Close()
@@ -54,28 +65,75 @@ func initServer(c *cli.Context) error {
 		StatusOK: true,
 	}
 
-	app := router.CreateRouter(&userManager, &status, c.Bool(corsFlag))
+	internalAuth, err := getInternalAuth(c)
+	exitOnErr(err)
+
+	jwtIdentity, err := getJWTIdentity(c)
+	exitOnErr(err)
+
+	app := router.CreateRouter(&userManager, &status, router.Options{
+		EnableCORS:   c.Bool(corsFlag),
+		RateLimiter:  rateLimiter,
+		PrivacyMode:  c.Bool(privacyModeFlag),
+		InternalAuth: internalAuth,
+		JWTIdentity:  jwtIdentity,
+	})
 
 	if c.String(adminPwdFlag) != "" {
 		err := userManager.CreateFirstAdmin(c.String(adminPwdFlag))
 		exitOnErr(err)
 	}
 
+	tlsConfig, err := getTLSConfig(c)
+	exitOnErr(err)
+
 	// graceful shutdown support
 	srv := http.Server{
-		Addr:    ":" + c.String(portFlag),
-		Handler: app,
+		Addr:      ":" + c.String(portFlag),
+		Handler:   app,
+		TLSConfig: tlsConfig,
 	}
 
-	go exitOnErr(srv.ListenAndServe())
+	go func() {
+		var err error
+		if tlsConfig != nil {
+			err = srv.ListenAndServeTLS(c.String(tlsCertFlag), c.String(tlsKeyFlag))
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != http.ErrServerClosed {
+			exitOnErr(err)
+		}
+	}()
+
+	// metrics are served separately: expvar exposes command line with secrets
+	var debugSrv *http.Server
+	if addr := c.String(debugAddrFlag); addr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/debug/vars", expvar.Handler())
+		debugSrv = &http.Server{Addr: addr, Handler: mux}
+		go func() {
+			if err := debugSrv.ListenAndServe(); err != http.ErrServerClosed {
+				exitOnErr(err)
+			}
+		}()
+	}
+
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	go userManager.RunJanitor(backgroundCtx)
+	go userManager.RunUserCacheListener(backgroundCtx)
 
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt)
 	<-quit
 	logrus.Infoln("shutting down server...")
+	stopBackground()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
+	if debugSrv != nil {
+		debugSrv.Shutdown(ctx)
+	}
 	return srv.Shutdown(ctx)
 }
 
@@ -15,6 +15,35 @@ import (
 	"gopkg.in/resty.v1"
 )
 
+// UserOrgXHeader contains ID of organization user belongs to
+const UserOrgXHeader = "X-User-Org-ID"
+
+// ImpersonatorXHeader contains ID of admin acting as user. Auth service stores it in token, so it comes back with requests made in impersonated session.
+const ImpersonatorXHeader = "X-Impersonator-ID"
+
+// TokenTTLXHeader contains lifetime of created token if it differs from default one.
+// Impersonation tokens are rejected if auth service ignores it or ImpersonatorXHeader.
+const TokenTTLXHeader = "X-Token-TTL"
+
+type orgIDContextKey struct{}
+
+type impersonationContextKey struct{}
+
+type impersonation struct {
+	impersonatorID string
+	ttl            time.Duration
+}
+
+// WithOrganization returns context carrying organization ID which is sent to auth service on token creation.
+func WithOrganization(ctx context.Context, orgID string) context.Context {
+	return context.WithValue(ctx, orgIDContextKey{}, orgID)
+}
+
+// WithImpersonation returns context for creation of short-living token which is marked with impersonator ID.
+func WithImpersonation(ctx context.Context, impersonatorID string, ttl time.Duration) context.Context {
+	return context.WithValue(ctx, impersonationContextKey{}, impersonation{impersonatorID: impersonatorID, ttl: ttl})
+}
+
 // AuthClient is an extension of AuthClient interface with Closer interface to close connection
 type AuthClient interface {
 	CreateToken(ctx context.Context, in *authProto.CreateTokenRequest) (*authProto.CreateTokenResponse, error)
@@ -53,6 +82,13 @@ func (c *httpAuthClient) CreateToken(ctx context.Context, in *authProto.CreateTo
 	headersMap[http.CanonicalHeaderKey(httputil.UserIDXHeader)] = in.GetUserId()
 	headersMap[http.CanonicalHeaderKey(httputil.UserIPXHeader)] = in.GetUserIp()
 	headersMap[http.CanonicalHeaderKey(httputil.UserRoleXHeader)] = in.GetUserRole()
+	if orgID, ok := ctx.Value(orgIDContextKey{}).(string); ok && orgID != "" {
+		headersMap[http.CanonicalHeaderKey(UserOrgXHeader)] = orgID
+	}
+	if imp, ok := ctx.Value(impersonationContextKey{}).(impersonation); ok {
+		headersMap[http.CanonicalHeaderKey(ImpersonatorXHeader)] = imp.impersonatorID
+		headersMap[http.CanonicalHeaderKey(TokenTTLXHeader)] = imp.ttl.String()
+	}
 	resp, err := c.client.R().
 		SetContext(ctx).
 		SetHeaders(headersMap).
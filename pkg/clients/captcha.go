@@ -0,0 +1,115 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/json-iterator/go"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/resty.v1"
+)
+
+// Captcha providers verification endpoints. All of them accept the same form and return the same response.
+const (
+	reCaptchaVerifyURL = "https://www.google.com/recaptcha/api/siteverify"
+	hCaptchaVerifyURL  = "https://api.hcaptcha.com/siteverify"
+	turnstileVerifyURL = "https://challenges.cloudflare.com/turnstile/v0/siteverify"
+)
+
+// CaptchaClient is an interface to captcha verification service
+type CaptchaClient interface {
+	Check(ctx context.Context, remoteIP, clientResponse string) (r *CaptchaResponse, err error)
+}
+
+// CaptchaResponse describes response from captcha verification service.
+// It`s enough to check only "Success" field. Other fields is for logging purposes.
+type CaptchaResponse struct {
+	Success     bool     `json:"success"`
+	ChallengeTS string   `json:"challenge_ts"`
+	Hostname    string   `json:"hostname"`
+	ErrorCodes  []string `json:"error-codes"`
+}
+
+type httpCaptchaClient struct {
+	client     *resty.Client
+	log        *logrus.Entry
+	provider   string
+	verifyURL  string
+	privateKey string
+}
+
+func newHTTPCaptchaClient(provider, verifyURL, privateKey string) CaptchaClient {
+	log := logrus.WithField("component", provider)
+	client := resty.New().SetLogger(log.WriterLevel(logrus.DebugLevel)).SetDebug(true).SetTimeout(5 * time.Second)
+	client.JSONMarshal = jsoniter.Marshal
+	client.JSONUnmarshal = jsoniter.Unmarshal
+	return &httpCaptchaClient{
+		log:        log,
+		client:     client,
+		provider:   provider,
+		verifyURL:  verifyURL,
+		privateKey: privateKey,
+	}
+}
+
+// NewHTTPReCaptchaClient returns client for Google`s ReCaptcha service working via HTTP
+func NewHTTPReCaptchaClient(privateKey string) CaptchaClient {
+	return newHTTPCaptchaClient("recaptcha", reCaptchaVerifyURL, privateKey)
+}
+
+// NewHTTPHCaptchaClient returns client for hCaptcha service working via HTTP
+func NewHTTPHCaptchaClient(privateKey string) CaptchaClient {
+	return newHTTPCaptchaClient("hcaptcha", hCaptchaVerifyURL, privateKey)
+}
+
+// NewHTTPTurnstileClient returns client for Cloudflare Turnstile service working via HTTP
+func NewHTTPTurnstileClient(privateKey string) CaptchaClient {
+	return newHTTPCaptchaClient("turnstile", turnstileVerifyURL, privateKey)
+}
+
+func (c *httpCaptchaClient) Check(ctx context.Context, remoteIP, clientResponse string) (r *CaptchaResponse, err error) {
+	c.log.Infoln("Checking captcha from", remoteIP)
+	r = new(CaptchaResponse)
+	form := url.Values{
+		"secret":   {c.privateKey},
+		"response": {clientResponse},
+	}
+	// remote IP is optional
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+	resp, err := c.client.R().SetContext(ctx).SetResult(r).SetMultiValueFormData(form).Post(c.verifyURL)
+	if err != nil {
+		return nil, err
+	}
+	if resp.IsError() {
+		return nil, fmt.Errorf("%s verification failed with status %s", c.provider, resp.Status())
+	}
+	if !r.Success {
+		c.log.WithField("error_codes", r.ErrorCodes).Debugln("Captcha check failed")
+	}
+	return r, nil
+}
+
+type dummyCaptchaClient struct {
+	log *logrus.Entry
+}
+
+// NewDummyCaptchaClient returns a dummy client.
+// It returns success on any request and log actions. Useful for testing purposes.
+func NewDummyCaptchaClient() CaptchaClient {
+	return &dummyCaptchaClient{
+		log: logrus.WithField("component", "dummy_captcha_client"),
+	}
+}
+
+func (c *dummyCaptchaClient) Check(ctx context.Context, remoteIP, clientResponse string) (r *CaptchaResponse, err error) {
+	c.log.Infoln("Checking captcha from", remoteIP)
+	return &CaptchaResponse{
+		Success:     true,
+		ChallengeTS: time.Now().Format(time.RFC3339),
+		Hostname:    "dummy",
+	}, nil
+}
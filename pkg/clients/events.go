@@ -19,10 +19,14 @@ type EventsClient interface {
 	UserDeleted(ctx context.Context, userName string) error
 	GroupCreated(ctx context.Context, groupName string) error
 	GroupDeleted(ctx context.Context, groupName string) error
+	GroupUpdated(ctx context.Context, oldGroupName, groupName string) error
 	UserAddedToGroup(ctx context.Context, userName, groupName string) error
 	UserRemovedFromGroup(ctx context.Context, userName, groupName string) error
 }
 
+// GroupUpdated is not defined in kube-client yet
+const GroupUpdated = "GroupUpdated"
+
 type httpEventsClient struct {
 	rest *resty.Client
 	log  *logrus.Entry
@@ -107,6 +111,23 @@ func (c *httpEventsClient) GroupDeleted(ctx context.Context, groupName string) e
 	return sendUserEvent(c, ctx, event)
 }
 
+func (c *httpEventsClient) GroupUpdated(ctx context.Context, oldGroupName, groupName string) error {
+	c.log.WithField("groupname", groupName).Debugln("Group updated")
+	var event = model.Event{
+		Kind:         model.EventInfo,
+		Time:         time.Now().Format(time.RFC3339),
+		Name:         GroupUpdated,
+		ResourceType: model.TypeUser,
+		ResourceName: groupName,
+	}
+	if oldGroupName != groupName {
+		event.Details = map[string]string{
+			"old_groupname": oldGroupName,
+		}
+	}
+	return sendUserEvent(c, ctx, event)
+}
+
 func (c *httpEventsClient) UserAddedToGroup(ctx context.Context, userName, groupName string) error {
 	c.log.WithField("groupname", groupName).WithField("username", userName).Debugln("User added to group")
 	var event = model.Event{
@@ -190,6 +211,11 @@ func (c *dummyEventsClient) GroupDeleted(ctx context.Context, groupName string)
 	return nil
 }
 
+func (c *dummyEventsClient) GroupUpdated(ctx context.Context, oldGroupName, groupName string) error {
+	c.log.WithField("groupname", groupName).Debugln("Group updated")
+	return nil
+}
+
 func (c *dummyEventsClient) UserAddedToGroup(ctx context.Context, userName, groupName string) error {
 	c.log.WithField("groupname", groupName).WithField("username", userName).Debugln("User added to group")
 	return nil
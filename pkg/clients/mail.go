@@ -21,7 +21,14 @@ type MailClient interface {
 	SendUnBlockedMail(ctx context.Context, recipient *mttypes.Recipient) error
 	SendPasswordChangedMail(ctx context.Context, recipient *mttypes.Recipient) error
 	SendPasswordResetMail(ctx context.Context, recipient *mttypes.Recipient) error
+	SendPasswordExpiringMail(ctx context.Context, recipient *mttypes.Recipient) error
 	SendAccDeletedMail(ctx context.Context, recipient *mttypes.Recipient) error
+	SendGroupMemberExpiringMail(ctx context.Context, recipient *mttypes.Recipient) error
+	SendImpersonationMail(ctx context.Context, recipient *mttypes.Recipient) error
+	// SendAlreadyRegisteredMail notifies account owner about sign up attempt with registered email
+	SendAlreadyRegisteredMail(ctx context.Context, recipient *mttypes.Recipient) error
+	// SendGroupInviteMail sends mail to recipient which may be not registered yet, so only recipient email is required
+	SendGroupInviteMail(ctx context.Context, recipient *mttypes.Recipient) error
 }
 
 type httpMailClient struct {
@@ -66,6 +73,24 @@ func (mc *httpMailClient) sendOneTemplate(ctx context.Context, tmplName string,
 	return nil
 }
 
+// sendTemplateToEmail sends mail addressed by email instead of user id
+func (mc *httpMailClient) sendTemplateToEmail(ctx context.Context, tmplName string, recipient *mttypes.Recipient) error {
+	req := &mttypes.SendRequest{}
+	req.Message.Recipients = []mttypes.Recipient{*recipient}
+	resp, err := mc.rest.R().
+		SetHeaders(httputil.RequestHeadersMap(ctx)). // forward request headers to other our service
+		SetBody(req).
+		SetResult(mttypes.SendResponse{}).
+		Post("/templates/" + tmplName)
+	if err != nil {
+		return err
+	}
+	if resp.Error() != nil {
+		return resp.Error().(*cherry.Err)
+	}
+	return nil
+}
+
 func (mc *httpMailClient) SendConfirmationMail(ctx context.Context, recipient *mttypes.Recipient) error {
 	mc.log.Infoln("Sending confirmation mail to", recipient.Email)
 	return mc.sendOneTemplate(ctx, "confirm_reg", recipient)
@@ -96,7 +121,32 @@ func (mc *httpMailClient) SendPasswordResetMail(ctx context.Context, recipient *
 	return mc.sendOneTemplate(ctx, "reset_pwd", recipient)
 }
 
+func (mc *httpMailClient) SendPasswordExpiringMail(ctx context.Context, recipient *mttypes.Recipient) error {
+	mc.log.Infoln("Sending password expiring mail to", recipient.Email)
+	return mc.sendOneTemplate(ctx, "pwd_expiring", recipient)
+}
+
 func (mc *httpMailClient) SendAccDeletedMail(ctx context.Context, recipient *mttypes.Recipient) error {
 	mc.log.Infoln("Sending account deleted mail to", recipient.Email)
 	return mc.sendOneTemplate(ctx, "delete_acc", recipient)
 }
+
+func (mc *httpMailClient) SendGroupMemberExpiringMail(ctx context.Context, recipient *mttypes.Recipient) error {
+	mc.log.Infoln("Sending group member expiring mail to", recipient.Email)
+	return mc.sendOneTemplate(ctx, "group_member_expiring", recipient)
+}
+
+func (mc *httpMailClient) SendImpersonationMail(ctx context.Context, recipient *mttypes.Recipient) error {
+	mc.log.Infoln("Sending impersonation mail to", recipient.Email)
+	return mc.sendOneTemplate(ctx, "impersonation", recipient)
+}
+
+func (mc *httpMailClient) SendAlreadyRegisteredMail(ctx context.Context, recipient *mttypes.Recipient) error {
+	mc.log.Infoln("Sending already registered mail to", recipient.Email)
+	return mc.sendOneTemplate(ctx, "already_registered", recipient)
+}
+
+func (mc *httpMailClient) SendGroupInviteMail(ctx context.Context, recipient *mttypes.Recipient) error {
+	mc.log.Infoln("Sending group invite mail to", recipient.Email)
+	return mc.sendTemplateToEmail(ctx, "group_invite", recipient)
+}
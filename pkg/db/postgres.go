@@ -11,11 +11,16 @@ import (
 
 	"errors"
 
+	"database/sql/driver"
+	"fmt"
+
 	"git.containerum.net/ch/user-manager/pkg/models"
+	"git.containerum.net/ch/user-manager/pkg/utils"
+	"github.com/json-iterator/go"
 	"github.com/lib/pq"
 )
 
-//UserProfileAccounts descrobes full information about user
+// UserProfileAccounts descrobes full information about user
 type UserProfileAccounts struct {
 	User     *User
 	Profile  *Profile
@@ -32,6 +37,21 @@ type User struct {
 	IsActive      bool   `db:"is_active"`
 	IsDeleted     bool   `db:"is_deleted"`
 	IsInBlacklist bool   `db:"is_in_blacklist"`
+	OrgID         string `db:"org_id"`
+	IsOrgAdmin    bool   `db:"is_org_admin"`
+	// PasswordChangedAt is used to find out if password expired
+	PasswordChangedAt  time.Time `db:"password_changed_at"`
+	MustChangePassword bool      `db:"must_change_password"`
+}
+
+// DefaultOrganizationID is an ID of organization which users and groups belong to if other is not specified.
+const DefaultOrganizationID = "00000000-0000-0000-0000-000000000001"
+
+// Organization describes customer organization (tenant). It should be used only inside this project.
+type Organization struct {
+	ID        string    `db:"id"`
+	Name      string    `db:"name"`
+	CreatedAt time.Time `db:"created_at"`
 }
 
 // Profile describes user`s profile model. It should be used only inside this project.
@@ -61,12 +81,13 @@ type Accounts struct {
 
 // Link describes link (for activation, password change, etc.) model. It should be used only inside this project.
 type Link struct {
-	Link      string
-	Type      models.LinkType
-	CreatedAt time.Time
-	ExpiredAt time.Time
-	IsActive  bool
-	SentAt    pq.NullTime
+	Link        string
+	Type        models.LinkType
+	CreatedAt   time.Time
+	ExpiredAt   time.Time
+	IsActive    bool
+	SentAt      pq.NullTime
+	ResendCount int
 
 	User *User
 }
@@ -81,31 +102,203 @@ type Token struct {
 	User *User
 }
 
-// DomainBlacklistEntry describes one blacklisted email domain.
-// Registration with email for this domain must be rejected. It should be used only inside this project.
+// DomainBlacklistEntry describes one email domain rule.
+// Registration with email matching block rule must be rejected. It should be used only inside this project.
 type DomainBlacklistEntry struct {
 	Domain    string         `db:"domain"`
+	Kind      string         `db:"kind"`
+	List      string         `db:"list"`
+	Reason    string         `db:"reason"`
+	ExpiresAt pq.NullTime    `db:"expires_at"`
+	CreatedAt time.Time      `db:"created_at"`
+	AddedBy   sql.NullString `db:"added_by"`
+}
+
+// NewDomainRule returns normalized domain rule. Rules are added to block list if list is not specified.
+func NewDomainRule(domain, kind, list, reason, addedBy string, expiresAt *time.Time) DomainBlacklistEntry {
+	domain, kind = utils.NormalizeDomainRule(domain, kind)
+	if list == "" {
+		list = models.DomainListBlock
+	}
+	entry := DomainBlacklistEntry{
+		Domain:  domain,
+		Kind:    kind,
+		List:    list,
+		Reason:  reason,
+		AddedBy: sql.NullString{String: addedBy, Valid: addedBy != ""},
+	}
+	if expiresAt != nil {
+		entry.ExpiresAt = pq.NullTime{Time: expiresAt.UTC(), Valid: true}
+	}
+	return entry
+}
+
+// IPRule describes blocked or allowed client network.
+type IPRule struct {
+	CIDR      string         `db:"cidr"`
+	List      string         `db:"list"`
+	Reason    string         `db:"reason"`
+	ExpiresAt pq.NullTime    `db:"expires_at"`
 	CreatedAt time.Time      `db:"created_at"`
 	AddedBy   sql.NullString `db:"added_by"`
 }
 
+// GroupLabels is a set of arbitrary group annotations stored as jsonb.
+type GroupLabels map[string]string
+
+// Scan implements sql.Scanner
+func (l *GroupLabels) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		return jsoniter.Unmarshal(v, l)
+	case string:
+		return jsoniter.UnmarshalFromString(v, l)
+	default:
+		return fmt.Errorf("unable to scan %T into group labels", src)
+	}
+}
+
+// Value implements driver.Valuer
+func (l GroupLabels) Value() (driver.Value, error) {
+	if l == nil {
+		return "{}", nil
+	}
+	return jsoniter.MarshalToString(l)
+}
+
 // UserGroup describes user group model. It should be used only inside this project.
 type UserGroup struct {
-	ID         string      `db:"id"`
-	Label      string      `db:"label"`
-	OwnerID    string      `db:"owner_user_id"`
-	OwnerLogin string      `db:"owner_login"`
-	CreatedAt  pq.NullTime `db:"created_at"`
+	ID          string      `db:"id"`
+	Label       string      `db:"label"`
+	OwnerID     string      `db:"owner_user_id"`
+	OwnerLogin  string      `db:"owner_login"`
+	CreatedAt   pq.NullTime `db:"created_at"`
+	Description string      `db:"description"`
+	Labels      GroupLabels `db:"labels"`
+	UpdatedAt   pq.NullTime `db:"updated_at"`
+	OrgID       string      `db:"org_id"`
 }
 
 // UserGroupMember describes user group member model. It should be used only inside this project.
 type UserGroupMember struct {
-	ID      string      `db:"id"`
-	Login   string      `db:"login"`
-	GroupID string      `db:"group_id"`
-	UserID  string      `db:"user_id"`
-	Access  string      `db:"default_access"`
-	AddedAt pq.NullTime `db:"added_at"`
+	ID        string      `db:"id"`
+	Login     string      `db:"login"`
+	GroupID   string      `db:"group_id"`
+	UserID    string      `db:"user_id"`
+	Access    string      `db:"default_access"`
+	AddedAt   pq.NullTime `db:"added_at"`
+	ExpiresAt pq.NullTime `db:"expires_at"`
+}
+
+// GroupMemberDetails describes group member with user account info. It should be used only inside this project.
+type GroupMemberDetails struct {
+	UserGroupMember
+	IsActive      bool
+	IsInBlacklist bool
+	LastLogin     pq.NullTime
+}
+
+// GroupMembersFilter restricts group members list. Empty fields are ignored. It should be used only inside this project.
+type GroupMembersFilter struct {
+	Access string
+	// Login is a case-insensitive substring of member login
+	Login string
+}
+
+// UserGroupMembership describes user direct membership in group. It should be used only inside this project.
+type UserGroupMembership struct {
+	GroupID    string
+	GroupLabel string
+	OwnerID    string
+	OwnerLogin string
+	Access     string
+	AddedAt    pq.NullTime
+	ExpiresAt  pq.NullTime
+}
+
+// ExpiringGroupMember describes group membership with limited lifetime. It should be used only inside this project.
+type ExpiringGroupMember struct {
+	GroupID    string
+	GroupLabel string
+	OwnerID    string
+	OwnerLogin string
+	UserID     string
+	Login      string
+	ExpiresAt  time.Time
+}
+
+// Subgroup describes group which members are members of parent group. It should be used only inside this project.
+type Subgroup struct {
+	ParentID string
+	ChildID  string
+	Label    string
+	Access   string
+	AddedAt  time.Time
+}
+
+// GroupAccess describes user effective access to group.
+// Inherited is true if user is not a direct member of group and got access through subgroups. It should be used only inside this project.
+type GroupAccess struct {
+	Access    string
+	Inherited bool
+	// inherited access expires with the earliest membership it depends on
+	ExpiresAt pq.NullTime
+}
+
+// GroupInvite describes pending invitation of not registered user to group. It should be used only inside this project.
+type GroupInvite struct {
+	ID         string
+	GroupID    string
+	GroupLabel string
+	Email      string
+	Access     string
+	InvitedBy  string
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+}
+
+// ServiceAccount describes non-human user owned by another user. It should be used only inside this project.
+type ServiceAccount struct {
+	OwnerID   string
+	CreatedAt time.Time
+
+	User *User
+}
+
+// APIKey describes long-lived service account key. Only key hash is stored. It should be used only inside this project.
+type APIKey struct {
+	ID         string
+	UserID     string
+	Name       string
+	Prefix     string
+	KeyHash    string
+	CreatedAt  time.Time
+	ExpiresAt  pq.NullTime
+	LastUsedAt pq.NullTime
+}
+
+// AdminRole describes named set of admin API permissions. It should be used only inside this project.
+type AdminRole struct {
+	Name        string
+	Description string
+	Permissions []string
+	CreatedAt   time.Time
+}
+
+// Impersonation is an audit record of admin acting as user. It should be used only inside this project.
+type Impersonation struct {
+	ID                string    `db:"id"`
+	ImpersonatorID    string    `db:"impersonator_id"`
+	ImpersonatorLogin string    `db:"impersonator_login"`
+	UserID            string    `db:"user_id"`
+	UserLogin         string    `db:"user_login"`
+	Reason            string    `db:"reason"`
+	IP                string    `db:"ip"`
+	CreatedAt         time.Time `db:"created_at"`
+	ExpiresAt         time.Time `db:"expires_at"`
 }
 
 // Errors which may occur in transactional operations
@@ -115,6 +308,18 @@ var (
 	ErrTransactionCommit   = errors.New("transaction commit error")
 )
 
+// ErrGroupCycle returned if group can't be added as subgroup because it already contains parent group
+var ErrGroupCycle = errors.New("group membership cycle")
+
+// ErrUserOwnsGroups returned if user can't be moved to other organization because he owns groups of current one
+var ErrUserOwnsGroups = errors.New("user owns groups")
+
+// Errors which may occur in admin roles operations
+var (
+	ErrAdminRoleNotExist   = errors.New("role not found")
+	ErrAdminRoleNotGranted = errors.New("role not granted")
+)
+
 // DB is an interface for persistent data storage (also sometimes called DAO).
 type DB interface {
 	GetUserByLogin(ctx context.Context, login string) (*User, error)
@@ -124,55 +329,161 @@ type DB interface {
 	GetUsersLoginID(ctx context.Context, ids []string) ([]User, error)
 	CreateUser(ctx context.Context, user *User) error
 	UpdateUser(ctx context.Context, user *User) error
-	GetBlacklistedUsers(ctx context.Context, limit, offset int) ([]User, error)
+	// Returns hashes of last user passwords, newest first
+	GetPasswordHistory(ctx context.Context, userID string, limit int) ([]string, error)
+	// Saves password hash to history keeping only given number of last passwords
+	AddPasswordHistory(ctx context.Context, userID, passwordHash string, keep int) error
+	// Returns users which changed password before given time and were not notified about password expiration
+	GetUsersWithExpiringPasswords(ctx context.Context, changedBefore time.Time, limit int) ([]User, error)
+	MarkPasswordExpiryNotified(ctx context.Context, userID string) error
+	// Returns blacklisted users of organization, all blacklisted users if orgID is empty
+	GetBlacklistedUsers(ctx context.Context, orgID string, limit, offset int) ([]User, error)
 	BlacklistUser(ctx context.Context, user *User) error
 	UnBlacklistUser(ctx context.Context, user *User) error
 
+	GetAdminRoles(ctx context.Context) ([]AdminRole, error)
+	GetAdminRole(ctx context.Context, name string) (*AdminRole, error)
+	// Creates role or replaces description and permissions of existing one
+	SetAdminRole(ctx context.Context, role *AdminRole) error
+	// Returns ErrAdminRoleNotExist if role doesn't exist
+	DeleteAdminRole(ctx context.Context, name string) error
+	GrantAdminRole(ctx context.Context, userID, roleName string) error
+	// Returns ErrAdminRoleNotGranted if role wasn't granted to user
+	RevokeAdminRole(ctx context.Context, userID, roleName string) error
+	// Returns names of roles granted to user
+	GetUserAdminRoles(ctx context.Context, userID string) ([]string, error)
+	// Returns permissions from all roles granted to user
+	GetUserPermissions(ctx context.Context, userID string) ([]string, error)
+
+	CreateImpersonation(ctx context.Context, impersonation *Impersonation) error
+	// Returns impersonations of user, newest first
+	GetUserImpersonations(ctx context.Context, userID string, limit, offset int) ([]Impersonation, error)
+
+	CreateOrganization(ctx context.Context, org *Organization) error
+	GetOrganization(ctx context.Context, orgID string) (*Organization, error)
+	GetOrganizations(ctx context.Context) ([]Organization, error)
+	// Moves user to organization and removes his memberships in groups of other organizations.
+	// Returns ErrUserOwnsGroups if user owns groups of other organizations.
+	SetUserOrganization(ctx context.Context, userID, orgID string, isOrgAdmin bool) error
+
 	CreateProfile(ctx context.Context, profile *Profile) error
 	GetProfileByID(ctx context.Context, id string) (*Profile, error)
 	GetProfileByUser(ctx context.Context, user *User) (*Profile, error)
 	UpdateProfile(ctx context.Context, profile *Profile) error
-	GetAllProfiles(ctx context.Context, perPage, offset uint) ([]UserProfileAccounts, uint, error)
+	// Returns profiles of organization users, all profiles if orgID is empty
+	GetAllProfiles(ctx context.Context, orgID string, perPage, offset uint) ([]UserProfileAccounts, uint, error)
 
 	GetUserByBoundAccount(ctx context.Context, service models.OAuthResource, accountID string) (*User, error)
 	GetUserBoundAccounts(ctx context.Context, user *User) (*Accounts, error)
 	BindAccount(ctx context.Context, user *User, service models.OAuthResource, accountID string) error
 	DeleteBoundAccount(ctx context.Context, user *User, service models.OAuthResource) error
 
-	BlacklistDomain(ctx context.Context, domain string, userID string) error
-	UnBlacklistDomain(ctx context.Context, domain string) error
-	IsDomainBlacklisted(ctx context.Context, domain string) (bool, error)
-	GetBlacklistedDomain(ctx context.Context, domain string) (*DomainBlacklistEntry, error)
-	GetBlacklistedDomainsList(ctx context.Context) ([]DomainBlacklistEntry, error)
+	BlacklistDomain(ctx context.Context, entry DomainBlacklistEntry) error
+	// ImportDomains adds rules skipping already existing ones, returns number of added rules.
+	ImportDomains(ctx context.Context, entries []DomainBlacklistEntry) (int, error)
+	UnBlacklistDomain(ctx context.Context, list, domain string) error
+	// MatchDomain returns first not expired rule from list matching domain or nil if no rules match.
+	MatchDomain(ctx context.Context, list, domain string) (*DomainBlacklistEntry, error)
+	GetBlacklistedDomain(ctx context.Context, list, domain string) (*DomainBlacklistEntry, error)
+	GetBlacklistedDomainsList(ctx context.Context, list string) ([]DomainBlacklistEntry, error)
+	DeleteExpiredDomains(ctx context.Context, before time.Time, limit int) (int64, error)
+
+	AddIPRule(ctx context.Context, rule IPRule) error
+	DeleteIPRule(ctx context.Context, cidr string) error
+	GetIPRule(ctx context.Context, cidr string) (*IPRule, error)
+	GetIPRules(ctx context.Context, list string) ([]IPRule, error)
+	// MatchIPRule returns not expired rule for most specific network containing ip, allow rules take precedence.
+	MatchIPRule(ctx context.Context, ip string) (*IPRule, error)
+	DeleteExpiredIPRules(ctx context.Context, before time.Time, limit int) (int64, error)
+
+	// TakeRateLimitToken refills token bucket for key and takes one token if available.
+	// Returns tokens left in bucket and if token was taken.
+	TakeRateLimitToken(ctx context.Context, key string, capacity, refillPerSecond float64) (tokens float64, allowed bool, err error)
+	DeleteFullRateLimits(ctx context.Context, before time.Time, limit int) (int64, error)
 
 	CreateLink(ctx context.Context, linkType models.LinkType, lifeTime time.Duration, user *User) (*Link, error)
 	GetLinkForUser(ctx context.Context, linkType models.LinkType, user *User) (*Link, error)
 	GetLinkFromString(ctx context.Context, strLink string) (*Link, error)
 	UpdateLink(ctx context.Context, link *Link) error
 	GetUserLinks(ctx context.Context, user *User) ([]Link, error)
+	DeleteExpiredLinks(ctx context.Context, before time.Time, limit int) (int64, error)
 
 	GetTokenObject(ctx context.Context, token string) (*Token, error)
 	CreateToken(ctx context.Context, user *User, sessionID string) (*Token, error)
 	GetTokenBySessionID(ctx context.Context, sessionID string) (*Token, error)
 	DeleteToken(ctx context.Context, token string) error
 	UpdateToken(ctx context.Context, token *Token) error
+	DeleteInactiveTokens(ctx context.Context, before time.Time, limit int) (int64, error)
+
+	// Increments failures counters. Counters which were not incremented since windowStart are restarted.
+	AddLoginFailure(ctx context.Context, keys []string, windowStart time.Time) error
+	// Returns maximal failures count of given keys counted since windowStart
+	GetLoginFailures(ctx context.Context, keys []string, windowStart time.Time) (int, error)
+	ResetLoginFailures(ctx context.Context, key string) error
+	DeleteStaleLoginFailures(ctx context.Context, before time.Time, limit int) (int64, error)
 
 	GetGroupByLabel(ctx context.Context, groupID string) (*UserGroup, error)
 	GetGroupByID(ctx context.Context, groupID string) (*UserGroup, error)
 	GetGroupMembers(ctx context.Context, groupID string) ([]UserGroupMember, error)
-	GetUserGroupsLabelsAccesses(ctx context.Context, userID string, isAdmin bool) (map[string]string, error)
+	// Locks group and its members until transaction end and returns members. Must be called inside transaction.
+	LockGroupMembers(ctx context.Context, groupID string) ([]UserGroupMember, error)
+	// Returns page of group members ordered by login and total number of members satisfying filter
+	GetGroupMembersPage(ctx context.Context, groupID string, filter GroupMembersFilter, perPage, offset uint) ([]GroupMemberDetails, uint, error)
+	// Returns page of groups user is direct member of ordered by group label and total number of such groups
+	GetUserGroupMemberships(ctx context.Context, userID string, perPage, offset uint) ([]UserGroupMembership, uint, error)
+	// Returns effective user access to groups including inherited through subgroups. Highest access wins.
+	GetUserGroupsLabelsAccesses(ctx context.Context, userID string, isAdmin bool) (map[string]GroupAccess, error)
 	GetGroupListLabelID(ctx context.Context, ids []string) ([]UserGroup, error)
 	GetGroupListByIDs(ctx context.Context, ids []string) ([]UserGroup, error)
+	GetOrganizationGroups(ctx context.Context, orgID string) ([]UserGroup, error)
 	CreateGroup(ctx context.Context, group *UserGroup) error
 	DeleteGroup(ctx context.Context, groupID string) error
+	// Updates group label, description and labels
+	UpdateGroup(ctx context.Context, group *UserGroup) error
 
 	AddGroupMembers(ctx context.Context, member *UserGroupMember) error
 	DeleteGroupMember(ctx context.Context, userID string, groupID string) error
 	DeleteGroupMemberFromAllGroups(ctx context.Context, userID string) error
-	UpdateGroupMember(ctx context.Context, userID string, groupID string, access string) error
+	UpdateGroupMember(ctx context.Context, userID string, groupID string, access string, expiresAt pq.NullTime) error
 	CountGroupMembers(ctx context.Context, groupID string) (*uint, error)
+	GetGroupMember(ctx context.Context, groupID string, userID string) (*UserGroupMember, error)
+	// Makes newOwner an owner of group. Previous owner stays in group with previousOwnerAccess.
+	TransferGroupOwnership(ctx context.Context, groupID string, newOwner *User, previousOwnerAccess string) error
+	// Removes memberships expired before now and returns them
+	DeleteExpiredGroupMembers(ctx context.Context, limit int) ([]ExpiringGroupMember, error)
+	// Returns memberships expiring before `before` which owners were not notified about yet
+	GetExpiringGroupMembers(ctx context.Context, before time.Time, limit int) ([]ExpiringGroupMember, error)
+	MarkGroupMemberExpiryNotified(ctx context.Context, groupID, userID string) error
+	SaveDeletedGroupMembers(ctx context.Context, userID string) error
+	RestoreDeletedGroupMembers(ctx context.Context, userID string) (map[string]string, error)
+	DropDeletedGroupMembers(ctx context.Context, userID string) error
+
+	// Adds child group to parent group. Returns ErrGroupCycle if parent group is already inside child group.
+	AddSubgroup(ctx context.Context, parentID, childID, access string) error
+	GetSubgroups(ctx context.Context, parentID string) ([]Subgroup, error)
+	DeleteSubgroup(ctx context.Context, parentID, childID string) error
+
+	// Creates invite or renews existing invite for the same group and email
+	CreateGroupInvite(ctx context.Context, invite *GroupInvite) error
+	GetGroupInvite(ctx context.Context, inviteID string) (*GroupInvite, error)
+	GetGroupInvites(ctx context.Context, groupID string) ([]GroupInvite, error)
+	// Returns not expired invites sent to email
+	GetPendingGroupInvites(ctx context.Context, email string) ([]GroupInvite, error)
+	DeleteGroupInvite(ctx context.Context, inviteID string) error
+
+	CreateServiceAccount(ctx context.Context, account *ServiceAccount) error
+	GetServiceAccount(ctx context.Context, userID string) (*ServiceAccount, error)
+	GetServiceAccountsByOwner(ctx context.Context, ownerID string) ([]ServiceAccount, error)
+	CreateAPIKey(ctx context.Context, key *APIKey) error
+	GetAPIKeyByPrefix(ctx context.Context, prefix string) (*APIKey, error)
+	GetAPIKeys(ctx context.Context, userID string) ([]APIKey, error)
+	DeleteAPIKey(ctx context.Context, userID, keyID string) error
+	UpdateAPIKeyLastUsed(ctx context.Context, keyID string) error
 
 	UpdateLastLogin(ctx context.Context, profileID, lastlogin string) error
+	UpdateDeletedAt(ctx context.Context, userID string, deletedAt pq.NullTime) error
+	// Invalidates all signed links issued for user and deactivates stored links
+	RevokeUserLinks(ctx context.Context, userID string) error
 
 	CountAdmins(ctx context.Context) (*int, error)
 
@@ -185,6 +496,14 @@ type DB interface {
 	// May return ErrTransactionBegin if transaction start failed,
 	// ErrTransactionCommit if commit failed, ErrTransactionRollback if rollback failed
 	Transactional(ctx context.Context, f func(ctx context.Context, tx DB) error) error
+	// Runs `f` while holding cluster-wide advisory lock identified by `key`.
+	// Returns false without calling `f` if lock is already held by another session.
+	WithAdvisoryLock(ctx context.Context, key int64, f func(ctx context.Context) error) (bool, error)
+	// Broadcasts user change to all ListenUserChanges subscribers. Inside transaction notification is delivered on commit.
+	NotifyUserChanged(ctx context.Context, userID string) error
+	// Calls `f` with ID of every user passed to NotifyUserChanged until ctx is done or connection is lost.
+	// Empty ID means that some notifications may be missed.
+	ListenUserChanges(ctx context.Context, f func(userID string)) error
 
 	io.Closer
 }
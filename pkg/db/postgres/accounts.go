@@ -26,7 +26,7 @@ func (pgdb *pgDB) GetUserByBoundAccount(ctx context.Context, service models.OAut
 
 	var ret db.User
 
-	rows, err := pgdb.qLog.QueryxContext(ctx, fmt.Sprintf(`SELECT users.id, users.login, users.password_hash, users.salt, users.role, users.is_active, users.is_deleted, users.is_in_blacklist
+	rows, err := pgdb.qLog.QueryxContext(ctx, fmt.Sprintf(`SELECT users.id, users.login, users.password_hash, users.salt, users.role, users.is_active, users.is_deleted, users.is_in_blacklist, users.org_id, users.is_org_admin, users.password_changed_at, users.must_change_password
 	FROM accounts JOIN users ON accounts.user_id = users.id WHERE accounts.%v = '%v'`, service, accountID))
 
 	if err != nil {
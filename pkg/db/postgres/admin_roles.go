@@ -0,0 +1,147 @@
+package postgres
+
+import (
+	"context"
+
+	"git.containerum.net/ch/user-manager/pkg/db"
+	"github.com/lib/pq"
+)
+
+func (pgdb *pgDB) GetAdminRoles(ctx context.Context) ([]db.AdminRole, error) {
+	pgdb.log.Infoln("Get admin roles")
+	ret := make([]db.AdminRole, 0)
+	rows, err := pgdb.qLog.QueryxContext(ctx, "SELECT r.name, r.description, r.created_at, "+
+		"COALESCE(array_agg(p.permission ORDER BY p.permission) FILTER (WHERE p.permission IS NOT NULL), '{}') "+
+		"FROM admin_roles r LEFT JOIN admin_roles_permissions p ON r.name = p.role_name "+
+		"GROUP BY r.name ORDER BY r.name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var role db.AdminRole
+		if err := rows.Scan(&role.Name, &role.Description, &role.CreatedAt, pq.Array(&role.Permissions)); err != nil {
+			return nil, err
+		}
+		ret = append(ret, role)
+	}
+	return ret, rows.Err()
+}
+
+func (pgdb *pgDB) GetAdminRole(ctx context.Context, name string) (*db.AdminRole, error) {
+	pgdb.log.Infoln("Get admin role", name)
+	rows, err := pgdb.qLog.QueryxContext(ctx, "SELECT r.name, r.description, r.created_at, "+
+		"COALESCE(array_agg(p.permission ORDER BY p.permission) FILTER (WHERE p.permission IS NOT NULL), '{}') "+
+		"FROM admin_roles r LEFT JOIN admin_roles_permissions p ON r.name = p.role_name "+
+		"WHERE r.name = $1 GROUP BY r.name", name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	var role db.AdminRole
+	err = rows.Scan(&role.Name, &role.Description, &role.CreatedAt, pq.Array(&role.Permissions))
+	return &role, err
+}
+
+func (pgdb *pgDB) SetAdminRole(ctx context.Context, role *db.AdminRole) error {
+	pgdb.log.Infoln("Set admin role", role.Name)
+	rows, err := pgdb.qLog.QueryxContext(ctx, "INSERT INTO admin_roles (name, description) VALUES ($1, $2) "+
+		"ON CONFLICT (name) DO UPDATE SET description = EXCLUDED.description RETURNING created_at",
+		role.Name, role.Description)
+	if err != nil {
+		return err
+	}
+	if rows.Next() {
+		err = rows.Scan(&role.CreatedAt)
+	} else {
+		err = rows.Err()
+	}
+	rows.Close()
+	if err != nil {
+		return err
+	}
+
+	if _, err := pgdb.eLog.ExecContext(ctx, "DELETE FROM admin_roles_permissions WHERE role_name = $1", role.Name); err != nil {
+		return err
+	}
+	_, err = pgdb.eLog.ExecContext(ctx, "INSERT INTO admin_roles_permissions (role_name, permission) "+
+		"SELECT $1, unnest($2::TEXT[]) ON CONFLICT DO NOTHING", role.Name, pq.Array(role.Permissions))
+	return err
+}
+
+func (pgdb *pgDB) DeleteAdminRole(ctx context.Context, name string) error {
+	pgdb.log.Infoln("Delete admin role", name)
+	res, err := pgdb.eLog.ExecContext(ctx, "DELETE FROM admin_roles WHERE name = $1", name)
+	if err != nil {
+		return err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	} else if rows == 0 {
+		return db.ErrAdminRoleNotExist
+	}
+	return nil
+}
+
+func (pgdb *pgDB) GrantAdminRole(ctx context.Context, userID, roleName string) error {
+	pgdb.log.WithField("role", roleName).Infoln("Grant admin role", userID)
+	_, err := pgdb.eLog.ExecContext(ctx, "INSERT INTO users_admin_roles (user_id, role_name) VALUES ($1, $2) "+
+		"ON CONFLICT DO NOTHING", userID, roleName)
+	return err
+}
+
+func (pgdb *pgDB) RevokeAdminRole(ctx context.Context, userID, roleName string) error {
+	pgdb.log.WithField("role", roleName).Infoln("Revoke admin role", userID)
+	res, err := pgdb.eLog.ExecContext(ctx, "DELETE FROM users_admin_roles WHERE user_id = $1 AND role_name = $2", userID, roleName)
+	if err != nil {
+		return err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	} else if rows == 0 {
+		return db.ErrAdminRoleNotGranted
+	}
+	return nil
+}
+
+func (pgdb *pgDB) GetUserAdminRoles(ctx context.Context, userID string) ([]string, error) {
+	pgdb.log.Infoln("Get user admin roles", userID)
+	ret := make([]string, 0)
+	rows, err := pgdb.qLog.QueryxContext(ctx, "SELECT role_name FROM users_admin_roles WHERE user_id = $1 ORDER BY role_name", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			return nil, err
+		}
+		ret = append(ret, role)
+	}
+	return ret, rows.Err()
+}
+
+func (pgdb *pgDB) GetUserPermissions(ctx context.Context, userID string) ([]string, error) {
+	pgdb.log.Infoln("Get user permissions", userID)
+	ret := make([]string, 0)
+	rows, err := pgdb.qLog.QueryxContext(ctx, "SELECT DISTINCT p.permission FROM users_admin_roles ur "+
+		"JOIN admin_roles_permissions p ON ur.role_name = p.role_name WHERE ur.user_id = $1", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var permission string
+		if err := rows.Scan(&permission); err != nil {
+			return nil, err
+		}
+		ret = append(ret, permission)
+	}
+	return ret, rows.Err()
+}
@@ -8,6 +8,7 @@ import (
 	"context"
 
 	"git.containerum.net/ch/user-manager/pkg/db"
+	"git.containerum.net/ch/user-manager/pkg/utils"
 	sqlxutil "github.com/containerum/utils/sqlxutil"
 	"github.com/golang-migrate/migrate"
 	"github.com/golang-migrate/migrate/database"
@@ -19,17 +20,21 @@ import (
 )
 
 type pgDB struct {
-	conn *sqlx.DB // do not use it in select/exec operations
-	log  *logrus.Entry
-	qLog sqlx.QueryerContext
-	eLog sqlx.ExecerContext
+	conn    *sqlx.DB // do not use it in select/exec operations
+	connStr string   // used for listening notifications
+	log     *logrus.Entry
+	qLog    sqlx.QueryerContext
+	eLog    sqlx.ExecerContext
+
+	linkKeyring *utils.LinkKeyring // nil if signed links disabled
 }
 
 // DBConnect initializes connection to postgresql database.
 // github.com/jmoiron/sqlx used to to get work with database.
 // Function tries to ping database and apply migrations using github.com/mattes/migrate.
 // If migrations applying failed database goes to dirty state and requires manual conflict resolution.
-func DBConnect(pgConnStr string, migrationsPath string) (db.DB, error) {
+// If linkKeyring is not nil new links will be created in signed format.
+func DBConnect(pgConnStr string, migrationsPath string, linkKeyring *utils.LinkKeyring) (db.DB, error) {
 	log := logrus.WithField("component", "db")
 	log.Infoln("Connecting to ", pgConnStr)
 	conn, err := sqlx.Open("postgres", pgConnStr)
@@ -42,10 +47,13 @@ func DBConnect(pgConnStr string, migrationsPath string) (db.DB, error) {
 	}
 
 	ret := &pgDB{
-		conn: conn,
-		log:  log,
-		qLog: sqlxutil.NewSQLXContextQueryLogger(conn, log),
-		eLog: sqlxutil.NewSQLXContextExecLogger(conn, log),
+		conn:    conn,
+		connStr: pgConnStr,
+		log:     log,
+		qLog:    sqlxutil.NewSQLXContextQueryLogger(conn, log),
+		eLog:    sqlxutil.NewSQLXContextExecLogger(conn, log),
+
+		linkKeyring: linkKeyring,
 	}
 
 	m, err := ret.migrateUp(migrationsPath)
@@ -100,10 +108,13 @@ func (pgdb *pgDB) Transactional(ctx context.Context, f func(ctx context.Context,
 	}
 
 	arg := &pgDB{
-		conn: pgdb.conn,
-		log:  e,
-		eLog: sqlxutil.NewSQLXContextExecLogger(tx, e),
-		qLog: sqlxutil.NewSQLXContextQueryLogger(tx, e),
+		conn:    pgdb.conn,
+		connStr: pgdb.connStr,
+		log:     e,
+		eLog:    sqlxutil.NewSQLXContextExecLogger(tx, e),
+		qLog:    sqlxutil.NewSQLXContextQueryLogger(tx, e),
+
+		linkKeyring: pgdb.linkKeyring,
 	}
 
 	// needed for recovering panics in transactions.
@@ -133,6 +144,35 @@ func (pgdb *pgDB) Transactional(ctx context.Context, f func(ctx context.Context,
 	return nil
 }
 
+func (pgdb *pgDB) WithAdvisoryLock(ctx context.Context, key int64, f func(ctx context.Context) error) (bool, error) {
+	e := pgdb.log.WithField("lock_key", key)
+	// session-level lock must be acquired and released on the same connection
+	conn, err := pgdb.conn.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&acquired); err != nil {
+		return false, err
+	}
+	if !acquired {
+		e.Debugln("Advisory lock is held by another session")
+		return false, nil
+	}
+	e.Debugln("Advisory lock acquired")
+
+	defer func() {
+		// use separate context: lock must be released even if ctx was cancelled
+		if _, unlockErr := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", key); unlockErr != nil {
+			e.WithError(unlockErr).Errorln("Advisory lock release failed")
+		}
+	}()
+
+	return true, f(ctx)
+}
+
 func (pgdb *pgDB) Close() error {
 	return pgdb.conn.Close()
 }
@@ -3,20 +3,64 @@ package postgres
 import (
 	"context"
 	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
 
 	"git.containerum.net/ch/user-manager/pkg/db"
+	"github.com/lib/pq"
 )
 
-func (pgdb *pgDB) BlacklistDomain(ctx context.Context, domain string, userID string) error {
-	pgdb.log.Infoln("Blacklisting domain", domain)
-	_, err := pgdb.eLog.ExecContext(ctx, "INSERT INTO domains (domain, added_by) VALUES ($1, $2) ON CONFLICT DO NOTHING",
-		domain, userID)
+const domainColumns = "domain, kind, list, reason, expires_at, created_at, added_by"
+
+// domainsImportBatch limits number of rows in one insert statement, each row takes 6 parameters
+const domainsImportBatch = 1000
+
+func (pgdb *pgDB) BlacklistDomain(ctx context.Context, entry db.DomainBlacklistEntry) error {
+	pgdb.log.Infoln("Adding domain rule", entry.Domain, entry.Kind, entry.List)
+	_, err := pgdb.eLog.ExecContext(ctx, "INSERT INTO domains (domain, kind, list, reason, expires_at, added_by) "+
+		"VALUES ($1, $2, $3, $4, $5, $6) "+
+		"ON CONFLICT (domain, list) DO UPDATE SET kind = EXCLUDED.kind, reason = EXCLUDED.reason, "+
+		"expires_at = EXCLUDED.expires_at, added_by = EXCLUDED.added_by",
+		entry.Domain, entry.Kind, entry.List, entry.Reason, entry.ExpiresAt, entry.AddedBy)
 	return err
 }
 
-func (pgdb *pgDB) UnBlacklistDomain(ctx context.Context, domain string) error {
-	pgdb.log.Infoln("UnBlacklisting domain", domain)
-	res, err := pgdb.eLog.ExecContext(ctx, "DELETE FROM domains WHERE domain = $1", domain)
+func (pgdb *pgDB) ImportDomains(ctx context.Context, entries []db.DomainBlacklistEntry) (int, error) {
+	pgdb.log.Infoln("Importing domain rules", len(entries))
+	imported := 0
+	for len(entries) > 0 {
+		batch := entries
+		if len(batch) > domainsImportBatch {
+			batch = batch[:domainsImportBatch]
+		}
+		entries = entries[len(batch):]
+
+		values := make([]string, 0, len(batch))
+		args := make([]interface{}, 0, 6*len(batch))
+		for _, entry := range batch {
+			n := len(args)
+			values = append(values, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5, n+6))
+			args = append(args, entry.Domain, entry.Kind, entry.List, entry.Reason, entry.ExpiresAt, entry.AddedBy)
+		}
+		res, err := pgdb.eLog.ExecContext(ctx, "INSERT INTO domains (domain, kind, list, reason, expires_at, added_by) "+
+			"VALUES "+strings.Join(values, ", ")+" ON CONFLICT (domain, list) DO NOTHING", args...)
+		if err != nil {
+			return imported, err
+		}
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return imported, err
+		}
+		imported += int(rows)
+	}
+	return imported, nil
+}
+
+func (pgdb *pgDB) UnBlacklistDomain(ctx context.Context, list, domain string) error {
+	pgdb.log.Infoln("UnBlacklisting domain", domain, list)
+	res, err := pgdb.eLog.ExecContext(ctx, "DELETE FROM domains WHERE domain = $1 AND list = $2", domain, list)
 	if err != nil {
 		return err
 	}
@@ -29,25 +73,67 @@ func (pgdb *pgDB) UnBlacklistDomain(ctx context.Context, domain string) error {
 	return nil
 }
 
-func (pgdb *pgDB) IsDomainBlacklisted(ctx context.Context, domain string) (bool, error) {
-	pgdb.log.Infof("Checking if domain %s in blacklist", domain)
-	rows, err := pgdb.qLog.QueryxContext(ctx, "SELECT COUNT(*) FROM domains WHERE domain = $1", domain)
+// domainSuffixes returns domain itself and all its parent domains: "a.b.com" -> ["a.b.com", "b.com", "com"].
+func domainSuffixes(domain string) []string {
+	suffixes := []string{domain}
+	for i, c := range domain {
+		if c == '.' && i+1 < len(domain) {
+			suffixes = append(suffixes, domain[i+1:])
+		}
+	}
+	return suffixes
+}
+
+func (pgdb *pgDB) MatchDomain(ctx context.Context, list, domain string) (*db.DomainBlacklistEntry, error) {
+	pgdb.log.Infof("Matching domain %s with %s list", domain, list)
+	domain = strings.ToLower(domain)
+
+	rows, err := pgdb.qLog.QueryxContext(ctx, "SELECT "+domainColumns+" FROM domains "+
+		"WHERE list = $1 AND (expires_at IS NULL OR expires_at > NOW()) "+
+		"AND ((kind = 'exact' AND domain = $2) OR (kind = 'suffix' AND domain = ANY($3))) "+
+		"ORDER BY length(domain) DESC LIMIT 1",
+		list, domain, pq.Array(domainSuffixes(domain)))
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 	defer rows.Close()
-	count := 0
-	if !rows.Next() {
-		return false, rows.Err()
+	if rows.Next() {
+		var ret db.DomainBlacklistEntry
+		err = rows.StructScan(&ret)
+		return &ret, err
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
 	}
-	err = rows.Scan(&count)
-	return count > 0, err
+
+	// regular expressions are matched here because postgres regex syntax differs from one used for validation
+	regexRows, err := pgdb.qLog.QueryxContext(ctx, "SELECT "+domainColumns+" FROM domains "+
+		"WHERE list = $1 AND kind = 'regex' AND (expires_at IS NULL OR expires_at > NOW())", list)
+	if err != nil {
+		return nil, err
+	}
+	defer regexRows.Close()
+	for regexRows.Next() {
+		var rule db.DomainBlacklistEntry
+		if err = regexRows.StructScan(&rule); err != nil {
+			return nil, err
+		}
+		re, err := regexp.Compile(rule.Domain)
+		if err != nil {
+			pgdb.log.WithError(err).Warnf("Invalid domain regex %s", rule.Domain)
+			continue
+		}
+		if re.MatchString(domain) {
+			return &rule, nil
+		}
+	}
+	return nil, regexRows.Err()
 }
 
-func (pgdb *pgDB) GetBlacklistedDomain(ctx context.Context, domain string) (*db.DomainBlacklistEntry, error) {
-	pgdb.log.Infof("Getting info about domain %s", domain)
+func (pgdb *pgDB) GetBlacklistedDomain(ctx context.Context, list, domain string) (*db.DomainBlacklistEntry, error) {
+	pgdb.log.Infof("Getting info about domain %s in %s list", domain, list)
 
-	rows, err := pgdb.qLog.QueryxContext(ctx, "SELECT domain, created_at, added_by FROM domains WHERE domain = $1", domain)
+	rows, err := pgdb.qLog.QueryxContext(ctx, "SELECT "+domainColumns+" FROM domains WHERE domain = $1 AND list = $2", domain, list)
 	if err != nil {
 		return nil, err
 	}
@@ -57,15 +143,15 @@ func (pgdb *pgDB) GetBlacklistedDomain(ctx context.Context, domain string) (*db.
 	defer rows.Close()
 
 	ret := db.DomainBlacklistEntry{}
-	err = rows.Scan(&ret.Domain, &ret.CreatedAt, &ret.AddedBy)
+	err = rows.StructScan(&ret)
 	return &ret, err
 }
 
-func (pgdb *pgDB) GetBlacklistedDomainsList(ctx context.Context) ([]db.DomainBlacklistEntry, error) {
-	pgdb.log.Infof("Checking domains list")
+func (pgdb *pgDB) GetBlacklistedDomainsList(ctx context.Context, list string) ([]db.DomainBlacklistEntry, error) {
+	pgdb.log.Infof("Checking domains list %s", list)
 	resp := make([]db.DomainBlacklistEntry, 0)
 
-	rows, err := pgdb.qLog.QueryxContext(ctx, "SELECT domain, created_at, added_by FROM domains")
+	rows, err := pgdb.qLog.QueryxContext(ctx, "SELECT "+domainColumns+" FROM domains WHERE list = $1 ORDER BY domain", list)
 	if err != nil {
 		return nil, err
 	}
@@ -81,3 +167,13 @@ func (pgdb *pgDB) GetBlacklistedDomainsList(ctx context.Context) ([]db.DomainBla
 
 	return resp, rows.Err()
 }
+
+func (pgdb *pgDB) DeleteExpiredDomains(ctx context.Context, before time.Time, limit int) (int64, error) {
+	pgdb.log.Infoln("Delete domain rules expired before", before.Format(time.ANSIC))
+	res, err := pgdb.eLog.ExecContext(ctx, "DELETE FROM domains WHERE (domain, list) IN "+
+		"(SELECT domain, list FROM domains WHERE expires_at < $1 LIMIT $2)", before, limit)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,96 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"git.containerum.net/ch/user-manager/pkg/db"
+	"github.com/jmoiron/sqlx"
+)
+
+const groupInviteQueryColumns = "group_invites.id, group_invites.group_id, groups.label, group_invites.email, group_invites.access, " +
+	"group_invites.invited_by, group_invites.created_at, group_invites.expires_at"
+
+func scanGroupInvites(rows *sqlx.Rows) ([]db.GroupInvite, error) {
+	ret := make([]db.GroupInvite, 0)
+	for rows.Next() {
+		var invite db.GroupInvite
+		if err := rows.Scan(&invite.ID, &invite.GroupID, &invite.GroupLabel, &invite.Email, &invite.Access,
+			&invite.InvitedBy, &invite.CreatedAt, &invite.ExpiresAt); err != nil {
+			return nil, err
+		}
+		ret = append(ret, invite)
+	}
+	return ret, rows.Err()
+}
+
+func (pgdb *pgDB) CreateGroupInvite(ctx context.Context, invite *db.GroupInvite) error {
+	pgdb.log.WithField("group_id", invite.GroupID).Infoln("Create group invite for", invite.Email)
+	rows, err := pgdb.qLog.QueryxContext(ctx, "INSERT INTO group_invites (group_id, email, access, invited_by, expires_at) "+
+		"VALUES ($1, $2, $3, $4, $5) "+
+		"ON CONFLICT (group_id, email) DO UPDATE SET access = $3, invited_by = $4, created_at = NOW(), expires_at = $5 "+
+		"RETURNING id, created_at",
+		invite.GroupID, invite.Email, invite.Access, invite.InvitedBy, invite.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return rows.Err()
+	}
+	return rows.Scan(&invite.ID, &invite.CreatedAt)
+}
+
+func (pgdb *pgDB) GetGroupInvite(ctx context.Context, inviteID string) (*db.GroupInvite, error) {
+	pgdb.log.Infoln("Get group invite", inviteID)
+	rows, err := pgdb.qLog.QueryxContext(ctx, "SELECT "+groupInviteQueryColumns+" FROM group_invites "+
+		"JOIN groups ON group_invites.group_id = groups.id WHERE group_invites.id = $1", inviteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	invites, err := scanGroupInvites(rows)
+	if err != nil || len(invites) == 0 {
+		return nil, err
+	}
+	return &invites[0], nil
+}
+
+func (pgdb *pgDB) GetGroupInvites(ctx context.Context, groupID string) ([]db.GroupInvite, error) {
+	pgdb.log.Infoln("Get group invites", groupID)
+	rows, err := pgdb.qLog.QueryxContext(ctx, "SELECT "+groupInviteQueryColumns+" FROM group_invites "+
+		"JOIN groups ON group_invites.group_id = groups.id WHERE group_invites.group_id = $1 "+
+		"ORDER BY group_invites.created_at", groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanGroupInvites(rows)
+}
+
+func (pgdb *pgDB) GetPendingGroupInvites(ctx context.Context, email string) ([]db.GroupInvite, error) {
+	pgdb.log.Infoln("Get pending group invites for", email)
+	rows, err := pgdb.qLog.QueryxContext(ctx, "SELECT "+groupInviteQueryColumns+" FROM group_invites "+
+		"JOIN groups ON group_invites.group_id = groups.id WHERE group_invites.email = $1 AND group_invites.expires_at > NOW() "+
+		"ORDER BY group_invites.created_at", email)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanGroupInvites(rows)
+}
+
+func (pgdb *pgDB) DeleteGroupInvite(ctx context.Context, inviteID string) error {
+	pgdb.log.Infoln("Delete group invite", inviteID)
+	res, err := pgdb.eLog.ExecContext(ctx, "DELETE FROM group_invites WHERE id = $1", inviteID)
+	if err != nil {
+		return err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	} else if rows == 0 {
+		return errors.New("invite not found")
+	}
+	return nil
+}
@@ -3,16 +3,23 @@ package postgres
 import (
 	"context"
 	"errors"
+	"strings"
+	"time"
 
 	"git.containerum.net/ch/user-manager/pkg/db"
+	kube_types "github.com/containerum/kube-client/pkg/model"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 func (pgdb *pgDB) CreateGroup(ctx context.Context, group *db.UserGroup) error {
 	pgdb.log.Infoln("Create group", group.Label)
-	rows, err := pgdb.qLog.QueryxContext(ctx, "INSERT INTO groups (label, owner_login, owner_user_id) "+
-		"VALUES ($1, $2, $3) RETURNING id",
-		group.Label, group.OwnerLogin, group.OwnerID)
+	if group.OrgID == "" {
+		group.OrgID = db.DefaultOrganizationID
+	}
+	rows, err := pgdb.qLog.QueryxContext(ctx, "INSERT INTO groups (label, owner_login, owner_user_id, org_id) "+
+		"VALUES ($1, $2, $3, $4) RETURNING id",
+		group.Label, group.OwnerLogin, group.OwnerID, group.OrgID)
 	if err != nil {
 		return err
 	}
@@ -25,9 +32,9 @@ func (pgdb *pgDB) CreateGroup(ctx context.Context, group *db.UserGroup) error {
 
 func (pgdb *pgDB) AddGroupMembers(ctx context.Context, member *db.UserGroupMember) error {
 	pgdb.log.Infoln("Adding group member", member.UserID)
-	rows, err := pgdb.qLog.QueryxContext(ctx, "INSERT INTO groups_members (group_id, user_id, default_access) "+
-		"VALUES ($1, $2, $3) RETURNING id",
-		member.GroupID, member.UserID, member.Access)
+	rows, err := pgdb.qLog.QueryxContext(ctx, "INSERT INTO groups_members (group_id, user_id, default_access, expires_at) "+
+		"VALUES ($1, $2, $3, $4) RETURNING id",
+		member.GroupID, member.UserID, member.Access, member.ExpiresAt)
 	if err != nil {
 		return err
 	}
@@ -68,11 +75,27 @@ func (pgdb *pgDB) GetGroupByID(ctx context.Context, groupLabel string) (*db.User
 	return &group, err
 }
 
+const groupMembersQuery = "SELECT groups_members.user_id, groups_members.default_access, groups_members.expires_at, users.login " +
+	"FROM groups_members JOIN users ON groups_members.user_id = users.id WHERE group_id = $1"
+
 func (pgdb *pgDB) GetGroupMembers(ctx context.Context, groupID string) ([]db.UserGroupMember, error) {
 	pgdb.log.Infoln("Get group users", groupID)
+	return pgdb.queryGroupMembers(ctx, groupMembersQuery, groupID)
+}
+
+func (pgdb *pgDB) LockGroupMembers(ctx context.Context, groupID string) ([]db.UserGroupMember, error) {
+	pgdb.log.Infoln("Lock group users", groupID)
+	// group row lock serializes concurrent member additions which are not covered by row locks of existing members
+	if _, err := pgdb.eLog.ExecContext(ctx, "SELECT id FROM groups WHERE id = $1 FOR UPDATE", groupID); err != nil {
+		return nil, err
+	}
+	return pgdb.queryGroupMembers(ctx, groupMembersQuery+" FOR UPDATE OF groups_members", groupID)
+}
+
+func (pgdb *pgDB) queryGroupMembers(ctx context.Context, query string, groupID string) ([]db.UserGroupMember, error) {
 	resp := make([]db.UserGroupMember, 0)
 
-	rows, err := pgdb.qLog.QueryxContext(ctx, "SELECT groups_members.user_id, groups_members.default_access, users.login FROM groups_members JOIN users ON groups_members.user_id = users.id WHERE group_id = $1", groupID)
+	rows, err := pgdb.qLog.QueryxContext(ctx, query, groupID)
 	if err != nil {
 		return nil, err
 	}
@@ -88,32 +111,113 @@ func (pgdb *pgDB) GetGroupMembers(ctx context.Context, groupID string) ([]db.Use
 	return resp, err
 }
 
-func (pgdb *pgDB) GetUserGroupsLabelsAccesses(ctx context.Context, userID string, isAdmin bool) (map[string]string, error) {
-	pgdb.log.Infoln("Get users groups", userID)
-	resp := make(map[string]string)
+// escapeLike escapes LIKE pattern special characters.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
 
-	var rows *sqlx.Rows
-	var err error
-	if isAdmin {
-		rows, err = pgdb.qLog.QueryxContext(ctx, "SELECT groups.label, default_access FROM groups_members JOIN groups ON group_id = groups.id")
-	} else {
-		rows, err = pgdb.qLog.QueryxContext(ctx, "SELECT groups.label, default_access FROM groups_members JOIN groups ON group_id = groups.id WHERE user_id = $1", userID)
+func (pgdb *pgDB) GetGroupMembersPage(ctx context.Context, groupID string, filter db.GroupMembersFilter, perPage, offset uint) ([]db.GroupMemberDetails, uint, error) {
+	pgdb.log.WithField("access", filter.Access).WithField("login", filter.Login).Infoln("Get group members page", groupID)
+	resp := make([]db.GroupMemberDetails, 0)
+	var total uint
+
+	const where = "WHERE groups_members.group_id = $1 " +
+		"AND ($2 = '' OR groups_members.default_access = $2) " +
+		"AND ($3 = '' OR users.login ILIKE '%' || $3 || '%') "
+	login := escapeLike(filter.Login)
+	// total is counted separately so it is known even if requested page is past the end
+	if err := pgdb.qLog.QueryRowxContext(ctx, "SELECT count(*) FROM groups_members JOIN users ON groups_members.user_id = users.id "+
+		where, groupID, filter.Access, login).Scan(&total); err != nil {
+		return nil, total, err
 	}
+
+	rows, err := pgdb.qLog.QueryxContext(ctx, "SELECT groups_members.user_id, users.login, groups_members.default_access, "+
+		"groups_members.added_at, groups_members.expires_at, users.is_active, users.is_in_blacklist, profiles.last_login "+
+		"FROM groups_members JOIN users ON groups_members.user_id = users.id "+
+		"LEFT JOIN profiles ON users.id = profiles.user_id "+
+		where+
+		"ORDER BY users.login LIMIT $4 OFFSET $5",
+		groupID, filter.Access, login, perPage, offset)
 	if err != nil {
-		return nil, err
+		return nil, total, err
 	}
 	defer rows.Close()
 	for rows.Next() {
-		var groupLabel string
-		var access string
-		err := rows.Scan(&groupLabel, &access)
+		member := db.GroupMemberDetails{UserGroupMember: db.UserGroupMember{GroupID: groupID}}
+		if err := rows.Scan(&member.UserID, &member.Login, &member.Access,
+			&member.AddedAt, &member.ExpiresAt, &member.IsActive, &member.IsInBlacklist, &member.LastLogin); err != nil {
+			return nil, total, err
+		}
+		resp = append(resp, member)
+	}
+	return resp, total, rows.Err()
+}
+
+func (pgdb *pgDB) GetUserGroupMemberships(ctx context.Context, userID string, perPage, offset uint) ([]db.UserGroupMembership, uint, error) {
+	pgdb.log.Infoln("Get user group memberships", userID)
+	resp := make([]db.UserGroupMembership, 0)
+	var total uint
+
+	if err := pgdb.qLog.QueryRowxContext(ctx, "SELECT count(*) FROM groups_members WHERE user_id = $1", userID).Scan(&total); err != nil {
+		return nil, total, err
+	}
+
+	rows, err := pgdb.qLog.QueryxContext(ctx, "SELECT groups.id, groups.label, groups.owner_user_id, owners.login, "+
+		"groups_members.default_access, groups_members.added_at, groups_members.expires_at "+
+		"FROM groups_members JOIN groups ON groups_members.group_id = groups.id "+
+		"JOIN users owners ON groups.owner_user_id = owners.id "+
+		"WHERE groups_members.user_id = $1 "+
+		"ORDER BY groups.label LIMIT $2 OFFSET $3",
+		userID, perPage, offset)
+	if err != nil {
+		return nil, total, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var membership db.UserGroupMembership
+		if err := rows.Scan(&membership.GroupID, &membership.GroupLabel, &membership.OwnerID, &membership.OwnerLogin,
+			&membership.Access, &membership.AddedAt, &membership.ExpiresAt); err != nil {
+			return nil, total, err
+		}
+		resp = append(resp, membership)
+	}
+	return resp, total, rows.Err()
+}
+
+func (pgdb *pgDB) GetUserGroupsLabelsAccesses(ctx context.Context, userID string, isAdmin bool) (map[string]db.GroupAccess, error) {
+	pgdb.log.Infoln("Get users groups", userID)
+	resp := make(map[string]db.GroupAccess)
+
+	if isAdmin {
+		rows, err := pgdb.qLog.QueryxContext(ctx, "SELECT groups.label, default_access FROM groups_members JOIN groups ON group_id = groups.id")
 		if err != nil {
 			return nil, err
 		}
-		resp[groupLabel] = access
+		defer rows.Close()
+		for rows.Next() {
+			var groupLabel string
+			var access string
+			if err := rows.Scan(&groupLabel, &access); err != nil {
+				return nil, err
+			}
+			resp[groupLabel] = db.GroupAccess{Access: access}
+		}
+		return resp, rows.Err()
 	}
 
-	return resp, err
+	direct, err := pgdb.getDirectGroupsAccesses(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	edges, err := pgdb.getAncestorSubgroupEdges(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range resolveEffectiveAccess(direct, edges) {
+		resp[v.label] = v.GroupAccess
+	}
+	return resp, nil
 }
 
 func (pgdb *pgDB) CountGroupMembers(ctx context.Context, groupName string) (*uint, error) {
@@ -160,9 +264,11 @@ func (pgdb *pgDB) DeleteGroupMemberFromAllGroups(ctx context.Context, userID str
 	return nil
 }
 
-func (pgdb *pgDB) UpdateGroupMember(ctx context.Context, userID string, groupID string, access string) error {
+func (pgdb *pgDB) UpdateGroupMember(ctx context.Context, userID string, groupID string, access string, expiresAt pq.NullTime) error {
 	pgdb.log.WithField("userID", userID).WithField("access", access).Infoln("Update member access")
-	res, err := pgdb.eLog.ExecContext(ctx, "UPDATE groups_members SET default_access = $3 WHERE group_id = $1 AND user_id = $2", groupID, userID, access)
+	res, err := pgdb.eLog.ExecContext(ctx, "UPDATE groups_members SET default_access = $3, expires_at = $4, "+
+		"expiry_notified_at = CASE WHEN expires_at IS NOT DISTINCT FROM $4 THEN expiry_notified_at END "+
+		"WHERE group_id = $1 AND user_id = $2", groupID, userID, access, expiresAt)
 	if err != nil {
 		return err
 	}
@@ -175,6 +281,23 @@ func (pgdb *pgDB) UpdateGroupMember(ctx context.Context, userID string, groupID
 	return nil
 }
 
+func (pgdb *pgDB) UpdateGroup(ctx context.Context, group *db.UserGroup) error {
+	pgdb.log.Infoln("Update group", group.ID)
+	rows, err := pgdb.qLog.QueryxContext(ctx, "UPDATE groups SET label = $2, description = $3, labels = $4, updated_at = NOW() "+
+		"WHERE id = $1 RETURNING updated_at", group.ID, group.Label, group.Description, group.Labels)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return errors.New("group not found")
+	}
+	return rows.Scan(&group.UpdatedAt)
+}
+
 func (pgdb *pgDB) DeleteGroup(ctx context.Context, groupID string) error {
 	pgdb.log.Infoln("Delete group", groupID)
 	if _, err := pgdb.eLog.ExecContext(ctx, "DELETE FROM groups WHERE id = $1", groupID); err != nil {
@@ -228,3 +351,127 @@ func (pgdb *pgDB) GetGroupListByIDs(ctx context.Context, ids []string) ([]db.Use
 
 	return groups, rows.Err()
 }
+
+const expiringGroupMemberQueryColumns = "groups.id, groups.label, groups.owner_user_id, groups.owner_login, " +
+	"users.id, users.login, groups_members.expires_at"
+
+func scanExpiringGroupMembers(rows *sqlx.Rows) ([]db.ExpiringGroupMember, error) {
+	ret := make([]db.ExpiringGroupMember, 0)
+	for rows.Next() {
+		var member db.ExpiringGroupMember
+		if err := rows.Scan(&member.GroupID, &member.GroupLabel, &member.OwnerID, &member.OwnerLogin,
+			&member.UserID, &member.Login, &member.ExpiresAt); err != nil {
+			return nil, err
+		}
+		ret = append(ret, member)
+	}
+	return ret, rows.Err()
+}
+
+func (pgdb *pgDB) DeleteExpiredGroupMembers(ctx context.Context, limit int) ([]db.ExpiringGroupMember, error) {
+	pgdb.log.Debugln("Delete expired group members")
+	rows, err := pgdb.qLog.QueryxContext(ctx, "DELETE FROM groups_members USING groups, users "+
+		"WHERE groups_members.group_id = groups.id AND groups_members.user_id = users.id AND groups_members.id IN ("+
+		"SELECT groups_members.id FROM groups_members JOIN groups ON groups_members.group_id = groups.id "+
+		"WHERE groups_members.expires_at <= NOW() AND groups_members.user_id != groups.owner_user_id LIMIT $1) "+
+		"RETURNING "+expiringGroupMemberQueryColumns, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanExpiringGroupMembers(rows)
+}
+
+func (pgdb *pgDB) GetExpiringGroupMembers(ctx context.Context, before time.Time, limit int) ([]db.ExpiringGroupMember, error) {
+	pgdb.log.Debugln("Get expiring group members")
+	rows, err := pgdb.qLog.QueryxContext(ctx, "SELECT "+expiringGroupMemberQueryColumns+" FROM groups_members "+
+		"JOIN groups ON groups_members.group_id = groups.id JOIN users ON groups_members.user_id = users.id "+
+		"WHERE groups_members.expires_at <= $1 AND groups_members.expiry_notified_at IS NULL "+
+		"AND groups_members.user_id != groups.owner_user_id "+
+		"ORDER BY groups_members.expires_at LIMIT $2", before, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanExpiringGroupMembers(rows)
+}
+
+func (pgdb *pgDB) MarkGroupMemberExpiryNotified(ctx context.Context, groupID, userID string) error {
+	pgdb.log.WithField("userID", userID).Debugln("Mark group member expiry notified", groupID)
+	_, err := pgdb.eLog.ExecContext(ctx, "UPDATE groups_members SET expiry_notified_at = NOW() WHERE group_id = $1 AND user_id = $2",
+		groupID, userID)
+	return err
+}
+
+func (pgdb *pgDB) SaveDeletedGroupMembers(ctx context.Context, userID string) error {
+	pgdb.log.Infoln("Save deleted member groups", userID)
+	_, err := pgdb.eLog.ExecContext(ctx, "INSERT INTO groups_members_deleted (group_id, user_id, default_access, added_at, expires_at) "+
+		"SELECT group_id, user_id, default_access, added_at, expires_at FROM groups_members WHERE user_id = $1 "+
+		"ON CONFLICT (group_id, user_id) DO UPDATE SET default_access = EXCLUDED.default_access, expires_at = EXCLUDED.expires_at, deleted_at = NOW()", userID)
+	return err
+}
+
+func (pgdb *pgDB) RestoreDeletedGroupMembers(ctx context.Context, userID string) (map[string]string, error) {
+	pgdb.log.Infoln("Restore deleted member groups", userID)
+	resp := make(map[string]string)
+
+	rows, err := pgdb.qLog.QueryxContext(ctx, "WITH restored AS ("+
+		"INSERT INTO groups_members (group_id, user_id, default_access, added_at, expires_at) "+
+		"SELECT group_id, user_id, default_access, added_at, expires_at FROM groups_members_deleted WHERE user_id = $1 "+
+		"ON CONFLICT (group_id, user_id) DO NOTHING RETURNING group_id, default_access) "+
+		"SELECT groups.label, restored.default_access FROM restored JOIN groups ON restored.group_id = groups.id", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var groupLabel string
+		var access string
+		if err := rows.Scan(&groupLabel, &access); err != nil {
+			return nil, err
+		}
+		resp[groupLabel] = access
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return resp, pgdb.DropDeletedGroupMembers(ctx, userID)
+}
+
+func (pgdb *pgDB) DropDeletedGroupMembers(ctx context.Context, userID string) error {
+	pgdb.log.Infoln("Drop deleted member groups", userID)
+	_, err := pgdb.eLog.ExecContext(ctx, "DELETE FROM groups_members_deleted WHERE user_id = $1", userID)
+	return err
+}
+
+func (pgdb *pgDB) GetGroupMember(ctx context.Context, groupID string, userID string) (*db.UserGroupMember, error) {
+	pgdb.log.WithField("userID", userID).Infoln("Get group member", groupID)
+	rows, err := pgdb.qLog.QueryxContext(ctx, "SELECT id, group_id, user_id, default_access, added_at, expires_at FROM groups_members "+
+		"WHERE group_id = $1 AND user_id = $2", groupID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	var member db.UserGroupMember
+	err = rows.StructScan(&member)
+	return &member, err
+}
+
+func (pgdb *pgDB) TransferGroupOwnership(ctx context.Context, groupID string, newOwner *db.User, previousOwnerAccess string) error {
+	pgdb.log.WithField("new_owner", newOwner.Login).Infoln("Transfer group ownership", groupID)
+	if _, err := pgdb.eLog.ExecContext(ctx, "UPDATE groups_members SET default_access = $2 "+
+		"WHERE group_id = $1 AND user_id = (SELECT owner_user_id FROM groups WHERE id = $1)", groupID, previousOwnerAccess); err != nil {
+		return err
+	}
+	if _, err := pgdb.eLog.ExecContext(ctx, "INSERT INTO groups_members (group_id, user_id, default_access) VALUES ($1, $2, $3) "+
+		"ON CONFLICT (group_id, user_id) DO UPDATE SET default_access = $3, expires_at = NULL, expiry_notified_at = NULL", groupID, newOwner.ID, string(kube_types.OwnerAccess)); err != nil {
+		return err
+	}
+	_, err := pgdb.eLog.ExecContext(ctx, "UPDATE groups SET owner_user_id = $2, owner_login = $3 WHERE id = $1",
+		groupID, newOwner.ID, newOwner.Login)
+	return err
+}
@@ -0,0 +1,43 @@
+package postgres
+
+import (
+	"context"
+
+	"git.containerum.net/ch/user-manager/pkg/db"
+)
+
+func (pgdb *pgDB) CreateImpersonation(ctx context.Context, impersonation *db.Impersonation) error {
+	pgdb.log.WithField("impersonator_id", impersonation.ImpersonatorID).Infoln("Create impersonation of", impersonation.UserID)
+	rows, err := pgdb.qLog.QueryxContext(ctx, "INSERT INTO impersonations "+
+		"(impersonator_id, impersonator_login, user_id, user_login, reason, ip, expires_at) "+
+		"VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at",
+		impersonation.ImpersonatorID, impersonation.ImpersonatorLogin, impersonation.UserID, impersonation.UserLogin,
+		impersonation.Reason, impersonation.IP, impersonation.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return rows.Err()
+	}
+	return rows.Scan(&impersonation.ID, &impersonation.CreatedAt)
+}
+
+func (pgdb *pgDB) GetUserImpersonations(ctx context.Context, userID string, limit, offset int) ([]db.Impersonation, error) {
+	pgdb.log.Infoln("Get user impersonations", userID)
+	ret := make([]db.Impersonation, 0)
+	rows, err := pgdb.qLog.QueryxContext(ctx, "SELECT id, impersonator_id, impersonator_login, user_id, user_login, reason, ip, created_at, expires_at "+
+		"FROM impersonations WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2 OFFSET $3", userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var impersonation db.Impersonation
+		if err := rows.StructScan(&impersonation); err != nil {
+			return nil, err
+		}
+		ret = append(ret, impersonation)
+	}
+	return ret, rows.Err()
+}
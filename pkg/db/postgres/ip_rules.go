@@ -0,0 +1,96 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"git.containerum.net/ch/user-manager/pkg/db"
+)
+
+const ipRuleColumns = "cidr, list, reason, expires_at, created_at, added_by"
+
+func (pgdb *pgDB) AddIPRule(ctx context.Context, rule db.IPRule) error {
+	pgdb.log.Infoln("Adding IP rule", rule.CIDR, rule.List)
+	_, err := pgdb.eLog.ExecContext(ctx, "INSERT INTO ip_rules (cidr, list, reason, expires_at, added_by) "+
+		"VALUES ($1, $2, $3, $4, $5) "+
+		"ON CONFLICT (cidr) DO UPDATE SET list = EXCLUDED.list, reason = EXCLUDED.reason, "+
+		"expires_at = EXCLUDED.expires_at, added_by = EXCLUDED.added_by",
+		rule.CIDR, rule.List, rule.Reason, rule.ExpiresAt, rule.AddedBy)
+	return err
+}
+
+func (pgdb *pgDB) DeleteIPRule(ctx context.Context, cidr string) error {
+	pgdb.log.Infoln("Deleting IP rule", cidr)
+	res, err := pgdb.eLog.ExecContext(ctx, "DELETE FROM ip_rules WHERE cidr = $1", cidr)
+	if err != nil {
+		return err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	} else if rows == 0 {
+		return errors.New("ip rule not found")
+	}
+	return nil
+}
+
+func (pgdb *pgDB) GetIPRule(ctx context.Context, cidr string) (*db.IPRule, error) {
+	pgdb.log.Infoln("Getting IP rule", cidr)
+	rows, err := pgdb.qLog.QueryxContext(ctx, "SELECT "+ipRuleColumns+" FROM ip_rules WHERE cidr = $1", cidr)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	var ret db.IPRule
+	err = rows.StructScan(&ret)
+	return &ret, err
+}
+
+func (pgdb *pgDB) GetIPRules(ctx context.Context, list string) ([]db.IPRule, error) {
+	pgdb.log.Infoln("Getting IP rules", list)
+	resp := make([]db.IPRule, 0)
+	rows, err := pgdb.qLog.QueryxContext(ctx, "SELECT "+ipRuleColumns+" FROM ip_rules WHERE list = $1 ORDER BY cidr", list)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var rule db.IPRule
+		if err := rows.StructScan(&rule); err != nil {
+			return nil, err
+		}
+		resp = append(resp, rule)
+	}
+	return resp, rows.Err()
+}
+
+func (pgdb *pgDB) MatchIPRule(ctx context.Context, ip string) (*db.IPRule, error) {
+	pgdb.log.Infoln("Matching IP rules", ip)
+	rows, err := pgdb.qLog.QueryxContext(ctx, "SELECT "+ipRuleColumns+" FROM ip_rules "+
+		"WHERE cidr >>= $1::inet AND (expires_at IS NULL OR expires_at > NOW()) "+
+		"ORDER BY list = 'allow' DESC, masklen(cidr) DESC LIMIT 1", ip)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	var ret db.IPRule
+	err = rows.StructScan(&ret)
+	return &ret, err
+}
+
+func (pgdb *pgDB) DeleteExpiredIPRules(ctx context.Context, before time.Time, limit int) (int64, error) {
+	pgdb.log.Infoln("Delete IP rules expired before", before.Format(time.ANSIC))
+	res, err := pgdb.eLog.ExecContext(ctx, "DELETE FROM ip_rules WHERE cidr IN "+
+		"(SELECT cidr FROM ip_rules WHERE expires_at < $1 LIMIT $2)", before, limit)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
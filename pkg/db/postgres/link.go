@@ -11,12 +11,13 @@ import (
 
 	"git.containerum.net/ch/user-manager/pkg/db"
 	"git.containerum.net/ch/user-manager/pkg/models"
+	"git.containerum.net/ch/user-manager/pkg/utils"
 	"github.com/sirupsen/logrus"
 )
 
-const linkQueryColumnsWithUser = "links.link, links.type, links.created_at, links.expired_at, links.is_active, links.sent_at, " +
-	"users.id, users.login, users.password_hash, users.salt, users.role, users.is_active, users.is_deleted, users.is_in_blacklist"
-const linkQueryColumns = "link, type, created_at, expired_at, is_active, sent_at"
+const linkQueryColumnsWithUser = "links.link, links.type, links.created_at, links.expired_at, links.is_active, links.sent_at, links.resend_count, " +
+	"users.id, users.login, users.password_hash, users.salt, users.role, users.is_active, users.is_deleted, users.is_in_blacklist, users.org_id, users.is_org_admin, users.password_changed_at, users.must_change_password"
+const linkQueryColumns = "link, type, created_at, expired_at, is_active, sent_at, resend_count"
 
 func (pgdb *pgDB) CreateLink(ctx context.Context, linkType models.LinkType, lifeTime time.Duration, user *db.User) (*db.Link, error) {
 	now := time.Now().UTC()
@@ -36,8 +37,24 @@ func (pgdb *pgDB) CreateLink(ctx context.Context, linkType models.LinkType, life
 		ExpiredAt: now.Add(lifeTime),
 		IsActive:  true,
 	}
+	if pgdb.linkKeyring != nil {
+		nonce, err := pgdb.getLinkNonce(ctx, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		ret.Link = pgdb.linkKeyring.Sign(utils.LinkClaims{
+			UserID:    user.ID,
+			Type:      string(linkType),
+			IssuedAt:  ret.CreatedAt,
+			ExpiresAt: ret.ExpiredAt,
+			Nonce:     nonce,
+		})
+	}
 	rows, err := pgdb.qLog.QueryxContext(ctx, "INSERT INTO links (link, type, created_at, expired_at, is_active, user_id) VALUES "+
-		"($1, $2, $3, $4, $5, $6) ON CONFLICT (type, user_id) DO UPDATE SET link = $1, is_active = true, created_at = $3, expired_at = $4 RETURNING "+linkQueryColumns, ret.Link, ret.Type, ret.CreatedAt, ret.ExpiredAt, ret.IsActive, ret.User.ID)
+		"($1, $2, $3, $4, $5, $6) ON CONFLICT (type, user_id) DO UPDATE SET link = $1, is_active = true, created_at = $3, expired_at = $4, "+
+		// resends counter is kept while previous link is still valid so limits can't be bypassed by link re-creation
+		"resend_count = CASE WHEN links.is_active AND links.expired_at > NOW() THEN links.resend_count ELSE 0 END, "+
+		"sent_at = CASE WHEN links.is_active AND links.expired_at > NOW() THEN links.sent_at ELSE NULL END RETURNING "+linkQueryColumns, ret.Link, ret.Type, ret.CreatedAt, ret.ExpiredAt, ret.IsActive, ret.User.ID)
 	if err != nil {
 		return nil, err
 	}
@@ -47,7 +64,7 @@ func (pgdb *pgDB) CreateLink(ctx context.Context, linkType models.LinkType, life
 		return nil, rows.Err()
 	}
 
-	if err = rows.Scan(&ret.Link, &ret.Type, &ret.CreatedAt, &ret.ExpiredAt, &ret.IsActive, &ret.SentAt); err != nil {
+	if err = rows.Scan(&ret.Link, &ret.Type, &ret.CreatedAt, &ret.ExpiredAt, &ret.IsActive, &ret.SentAt, &ret.ResendCount); err != nil {
 		return nil, err
 	}
 
@@ -66,13 +83,16 @@ func (pgdb *pgDB) GetLinkForUser(ctx context.Context, linkType models.LinkType,
 		return nil, rows.Err()
 	}
 	link := db.Link{User: user}
-	err = rows.Scan(&link.Link, &link.Type, &link.CreatedAt, &link.ExpiredAt, &link.IsActive, &link.SentAt)
+	err = rows.Scan(&link.Link, &link.Type, &link.CreatedAt, &link.ExpiredAt, &link.IsActive, &link.SentAt, &link.ResendCount)
 
 	return &link, err
 }
 
 func (pgdb *pgDB) GetLinkFromString(ctx context.Context, strLink string) (*db.Link, error) {
 	pgdb.log.Infoln("Get link", strLink)
+	if utils.IsSignedLink(strLink) {
+		return pgdb.getSignedLink(ctx, strLink)
+	}
 	rows, err := pgdb.qLog.QueryxContext(ctx, "SELECT "+linkQueryColumnsWithUser+" FROM links "+
 		"JOIN users ON links.user_id = users.id "+
 		"WHERE link = $1 AND links.is_active AND links.expired_at > NOW()", strLink)
@@ -84,18 +104,66 @@ func (pgdb *pgDB) GetLinkFromString(ctx context.Context, strLink string) (*db.Li
 	}
 	defer rows.Close()
 	link := db.Link{User: &db.User{}}
-	err = rows.Scan(&link.Link, &link.Type, &link.CreatedAt, &link.ExpiredAt, &link.IsActive, &link.SentAt,
+	err = rows.Scan(&link.Link, &link.Type, &link.CreatedAt, &link.ExpiredAt, &link.IsActive, &link.SentAt, &link.ResendCount,
 		&link.User.ID, &link.User.Login, &link.User.PasswordHash, &link.User.Salt, &link.User.Role,
-		&link.User.IsActive, &link.User.IsDeleted, &link.User.IsInBlacklist)
+		&link.User.IsActive, &link.User.IsDeleted, &link.User.IsInBlacklist, &link.User.OrgID, &link.User.IsOrgAdmin, &link.User.PasswordChangedAt, &link.User.MustChangePassword)
 
 	return &link, err
 }
 
+// getSignedLink verifies signed link without links table access and then checks that it was not revoked by nonce bump.
+func (pgdb *pgDB) getSignedLink(ctx context.Context, strLink string) (*db.Link, error) {
+	if pgdb.linkKeyring == nil {
+		pgdb.log.Warnln("Signed link received but link signing is disabled")
+		return nil, nil
+	}
+	claims, err := pgdb.linkKeyring.Verify(strLink)
+	if err != nil {
+		pgdb.log.WithError(err).Infoln("Signed link rejected")
+		return nil, nil
+	}
+
+	rows, err := pgdb.qLog.QueryxContext(ctx, "SELECT "+userQueryColumns+", link_nonce FROM users WHERE id = $1", claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	user := &db.User{}
+	var nonce int64
+	if err := rows.Scan(&user.ID, &user.Login, &user.PasswordHash, &user.Salt, &user.Role,
+		&user.IsActive, &user.IsDeleted, &user.IsInBlacklist, &user.OrgID, &user.IsOrgAdmin, &user.PasswordChangedAt, &user.MustChangePassword, &nonce); err != nil {
+		return nil, err
+	}
+	if nonce != claims.Nonce {
+		pgdb.log.WithField("user_id", user.ID).Infoln("Signed link revoked")
+		return nil, nil
+	}
+
+	return &db.Link{
+		Link:      strLink,
+		Type:      models.LinkType(claims.Type),
+		CreatedAt: claims.IssuedAt,
+		ExpiredAt: claims.ExpiresAt,
+		IsActive:  true,
+		User:      user,
+	}, nil
+}
+
 func (pgdb *pgDB) UpdateLink(ctx context.Context, link *db.Link) error {
 	pgdb.log.Infof("Update link %#v", link)
-	_, err := pgdb.eLog.ExecContext(ctx, "UPDATE links set type = $2, expired_at = $3, is_active = $4, sent_at = $5 "+
-		"WHERE link = $1", link.Link, link.Type, link.ExpiredAt, link.IsActive, link.SentAt)
-	return err
+	_, err := pgdb.eLog.ExecContext(ctx, "UPDATE links set type = $2, expired_at = $3, is_active = $4, sent_at = $5, resend_count = $6 "+
+		"WHERE link = $1", link.Link, link.Type, link.ExpiredAt, link.IsActive, link.SentAt, link.ResendCount)
+	if err != nil {
+		return err
+	}
+	// signed links are verified without links table so deactivation is done by nonce bump
+	if !link.IsActive && utils.IsSignedLink(link.Link) && link.User != nil {
+		return pgdb.RevokeUserLinks(ctx, link.User.ID)
+	}
+	return nil
 }
 
 func (pgdb *pgDB) GetUserLinks(ctx context.Context, user *db.User) ([]db.Link, error) {
@@ -109,7 +177,7 @@ func (pgdb *pgDB) GetUserLinks(ctx context.Context, user *db.User) ([]db.Link, e
 	defer rows.Close()
 	for rows.Next() {
 		link := db.Link{User: user}
-		err := rows.Scan(&link.Link, &link.Type, &link.CreatedAt, &link.ExpiredAt, &link.IsActive, &link.SentAt)
+		err := rows.Scan(&link.Link, &link.Type, &link.CreatedAt, &link.ExpiredAt, &link.IsActive, &link.SentAt, &link.ResendCount)
 		if err != nil {
 			return nil, err
 		}
@@ -118,3 +186,16 @@ func (pgdb *pgDB) GetUserLinks(ctx context.Context, user *db.User) ([]db.Link, e
 
 	return ret, rows.Err()
 }
+
+func (pgdb *pgDB) DeleteExpiredLinks(ctx context.Context, before time.Time, limit int) (int64, error) {
+	pgdb.log.WithFields(logrus.Fields{
+		"before": before.Format(time.ANSIC),
+		"limit":  limit,
+	}).Infoln("Delete expired links")
+	res, err := pgdb.eLog.ExecContext(ctx, "DELETE FROM links WHERE link IN "+
+		"(SELECT link FROM links WHERE expired_at < $1 OR (NOT is_active AND created_at < $1) LIMIT $2)", before, limit)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
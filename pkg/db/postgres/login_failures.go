@@ -0,0 +1,49 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+func (pgdb *pgDB) AddLoginFailure(ctx context.Context, keys []string, windowStart time.Time) error {
+	pgdb.log.Infoln("Add login failure", keys)
+	_, err := pgdb.eLog.ExecContext(ctx, "INSERT INTO login_failures (key, failures) SELECT unnest($1::TEXT[]), 1 "+
+		"ON CONFLICT (key) DO UPDATE SET "+
+		"failures = CASE WHEN login_failures.last_failure_at < $2 THEN 1 ELSE login_failures.failures + 1 END, "+
+		"last_failure_at = NOW()", pq.Array(keys), windowStart)
+	return err
+}
+
+func (pgdb *pgDB) GetLoginFailures(ctx context.Context, keys []string, windowStart time.Time) (int, error) {
+	pgdb.log.Infoln("Get login failures", keys)
+	rows, err := pgdb.qLog.QueryxContext(ctx, "SELECT COALESCE(MAX(failures), 0) FROM login_failures "+
+		"WHERE key = ANY($1) AND last_failure_at >= $2", pq.Array(keys), windowStart)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return 0, rows.Err()
+	}
+	var failures int
+	err = rows.Scan(&failures)
+	return failures, err
+}
+
+func (pgdb *pgDB) ResetLoginFailures(ctx context.Context, key string) error {
+	pgdb.log.Infoln("Reset login failures", key)
+	_, err := pgdb.eLog.ExecContext(ctx, "DELETE FROM login_failures WHERE key = $1", key)
+	return err
+}
+
+func (pgdb *pgDB) DeleteStaleLoginFailures(ctx context.Context, before time.Time, limit int) (int64, error) {
+	pgdb.log.Infoln("Delete login failures last updated before", before.Format(time.ANSIC))
+	res, err := pgdb.eLog.ExecContext(ctx, "DELETE FROM login_failures WHERE key IN "+
+		"(SELECT key FROM login_failures WHERE last_failure_at < $1 LIMIT $2)", before, limit)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,108 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"git.containerum.net/ch/user-manager/pkg/db"
+)
+
+func (pgdb *pgDB) CreateOrganization(ctx context.Context, org *db.Organization) error {
+	pgdb.log.Infoln("Create organization", org.Name)
+	rows, err := pgdb.qLog.QueryxContext(ctx, "INSERT INTO organizations (name) VALUES ($1) RETURNING id, created_at", org.Name)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return rows.Err()
+	}
+	return rows.Scan(&org.ID, &org.CreatedAt)
+}
+
+func (pgdb *pgDB) GetOrganization(ctx context.Context, orgID string) (*db.Organization, error) {
+	pgdb.log.Infoln("Get organization", orgID)
+	rows, err := pgdb.qLog.QueryxContext(ctx, "SELECT id, name, created_at FROM organizations WHERE id = $1", orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	var org db.Organization
+	err = rows.StructScan(&org)
+	return &org, err
+}
+
+func (pgdb *pgDB) GetOrganizations(ctx context.Context) ([]db.Organization, error) {
+	pgdb.log.Infoln("Get organizations")
+	ret := make([]db.Organization, 0)
+	rows, err := pgdb.qLog.QueryxContext(ctx, "SELECT id, name, created_at FROM organizations ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var org db.Organization
+		if err := rows.StructScan(&org); err != nil {
+			return nil, err
+		}
+		ret = append(ret, org)
+	}
+	return ret, rows.Err()
+}
+
+func (pgdb *pgDB) SetUserOrganization(ctx context.Context, userID, orgID string, isOrgAdmin bool) error {
+	pgdb.log.WithField("org_id", orgID).Infoln("Set user organization", userID)
+	rows, err := pgdb.qLog.QueryxContext(ctx, "SELECT count(*) FROM groups WHERE owner_user_id = $1 AND org_id <> $2", userID, orgID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	var owned int
+	if rows.Next() {
+		if err := rows.Scan(&owned); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if owned > 0 {
+		return db.ErrUserOwnsGroups
+	}
+
+	res, err := pgdb.eLog.ExecContext(ctx, "UPDATE users SET org_id = $2, is_org_admin = $3 WHERE id = $1", userID, orgID, isOrgAdmin)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	} else if affected == 0 {
+		return errors.New("user not found")
+	}
+
+	_, err = pgdb.eLog.ExecContext(ctx, "DELETE FROM groups_members WHERE user_id = $1 "+
+		"AND group_id IN (SELECT id FROM groups WHERE org_id <> $2)", userID, orgID)
+	return err
+}
+
+func (pgdb *pgDB) GetOrganizationGroups(ctx context.Context, orgID string) ([]db.UserGroup, error) {
+	pgdb.log.Infoln("Get organization groups", orgID)
+	ret := make([]db.UserGroup, 0)
+	rows, err := pgdb.qLog.QueryxContext(ctx, "SELECT * FROM groups WHERE org_id = $1 ORDER BY label", orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var group db.UserGroup
+		if err := rows.StructScan(&group); err != nil {
+			return nil, err
+		}
+		ret = append(ret, group)
+	}
+	return ret, rows.Err()
+}
@@ -0,0 +1,35 @@
+package postgres
+
+import (
+	"context"
+)
+
+func (pgdb *pgDB) GetPasswordHistory(ctx context.Context, userID string, limit int) ([]string, error) {
+	pgdb.log.Infoln("Get password history", userID)
+	ret := make([]string, 0)
+	rows, err := pgdb.qLog.QueryxContext(ctx, "SELECT password_hash FROM users_password_history "+
+		"WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2", userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, err
+		}
+		ret = append(ret, hash)
+	}
+	return ret, rows.Err()
+}
+
+func (pgdb *pgDB) AddPasswordHistory(ctx context.Context, userID, passwordHash string, keep int) error {
+	pgdb.log.Infoln("Add password history", userID)
+	_, err := pgdb.eLog.ExecContext(ctx, "INSERT INTO users_password_history (user_id, password_hash) VALUES ($1, $2)", userID, passwordHash)
+	if err != nil {
+		return err
+	}
+	_, err = pgdb.eLog.ExecContext(ctx, "DELETE FROM users_password_history WHERE user_id = $1 AND ctid NOT IN "+
+		"(SELECT ctid FROM users_password_history WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2)", userID, keep)
+	return err
+}
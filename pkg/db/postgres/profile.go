@@ -7,12 +7,14 @@ import (
 	"context"
 
 	"database/sql"
+
+	"github.com/lib/pq"
 )
 
 const profileQueryColumnsWithUserAndAccounts = "profiles.id, profiles.referral, profiles.access, profiles.created_at, profiles.blacklist_at, profiles.deleted_at, profiles.last_login, " +
-	"users.id, users.login, users.password_hash, users.salt, users.role, users.is_active, users.is_deleted, users.is_in_blacklist, profiles.data, accounts.github, accounts.google, accounts.facebook"
+	"users.id, users.login, users.password_hash, users.salt, users.role, users.is_active, users.is_deleted, users.is_in_blacklist, users.org_id, users.is_org_admin, users.password_changed_at, users.must_change_password, profiles.data, accounts.github, accounts.google, accounts.facebook"
 const profileQueryColumnsWithUser = "profiles.id, profiles.referral, profiles.access, profiles.created_at, profiles.blacklist_at, profiles.deleted_at, profiles.last_login, " +
-	"users.id, users.login, users.password_hash, users.salt, users.role, users.is_active, users.is_deleted, users.is_in_blacklist, profiles.data"
+	"users.id, users.login, users.password_hash, users.salt, users.role, users.is_active, users.is_deleted, users.is_in_blacklist, users.org_id, users.is_org_admin, users.password_changed_at, users.must_change_password, profiles.data"
 
 const profileQueryColumns = "id, referral, access, created_at, blacklist_at, deleted_at, last_login, data"
 
@@ -73,7 +75,7 @@ func (pgdb *pgDB) GetProfileByID(ctx context.Context, id string) (*db.Profile, e
 	err = rows.Scan(
 		&profile.ID, &profile.Referral, &profile.Access, &profile.CreatedAt, &profile.BlacklistAt, &profile.DeletedAt, &profile.LastLogin,
 		&profile.User.ID, &profile.User.Login, &profile.User.PasswordHash, &profile.User.Salt, &profile.User.Role,
-		&profile.User.IsActive, &profile.User.IsDeleted, &profile.User.IsInBlacklist,
+		&profile.User.IsActive, &profile.User.IsDeleted, &profile.User.IsInBlacklist, &profile.User.OrgID, &profile.User.IsOrgAdmin, &profile.User.PasswordChangedAt, &profile.User.MustChangePassword,
 		&profileData,
 	)
 	if err != nil {
@@ -129,15 +131,23 @@ func (pgdb *pgDB) UpdateLastLogin(ctx context.Context, profileID, lastlogin stri
 	return err
 }
 
-func (pgdb *pgDB) GetAllProfiles(ctx context.Context, perPage, offset uint) ([]db.UserProfileAccounts, uint, error) {
-	pgdb.log.Infoln("Get all profiles")
+func (pgdb *pgDB) UpdateDeletedAt(ctx context.Context, userID string, deletedAt pq.NullTime) error {
+	pgdb.log.Infof("Update profile deleted at %v", deletedAt.Time)
+	_, err := pgdb.eLog.ExecContext(ctx, "UPDATE profiles SET deleted_at = $2 WHERE user_id = $1",
+		userID, deletedAt)
+	return err
+}
+
+func (pgdb *pgDB) GetAllProfiles(ctx context.Context, orgID string, perPage, offset uint) ([]db.UserProfileAccounts, uint, error) {
+	pgdb.log.Infoln("Get all profiles", orgID)
 	profiles := make([]db.UserProfileAccounts, 0) // return empty slice instead of nil if no records found
 	var totalUsers uint
 	rows, err := pgdb.qLog.QueryxContext(ctx, "SELECT "+profileQueryColumnsWithUserAndAccounts+" , count(*) OVER() FROM users "+
 		"LEFT JOIN profiles ON users.id = profiles.user_id "+
 		"LEFT JOIN accounts ON users.id = accounts.user_id WHERE users.is_deleted!='true' "+
+		"AND ($3 = '' OR users.org_id::TEXT = $3) "+
 		"ORDER BY users.role, users.login "+
-		"LIMIT $1 OFFSET $2", perPage, offset)
+		"LIMIT $1 OFFSET $2", perPage, offset, orgID)
 	if err != nil {
 		return nil, totalUsers, err
 	}
@@ -148,7 +158,7 @@ func (pgdb *pgDB) GetAllProfiles(ctx context.Context, perPage, offset uint) ([]d
 		if err := rows.Scan(
 			&profile.Profile.ID, &profile.Profile.Referral, &profile.Profile.Access, &profile.Profile.CreatedAt, &profile.Profile.BlacklistAt, &profile.Profile.DeletedAt, &profile.Profile.LastLogin,
 			&profile.User.ID, &profile.User.Login, &profile.User.PasswordHash, &profile.User.Salt, &profile.User.Role,
-			&profile.User.IsActive, &profile.User.IsDeleted, &profile.User.IsInBlacklist,
+			&profile.User.IsActive, &profile.User.IsDeleted, &profile.User.IsInBlacklist, &profile.User.OrgID, &profile.User.IsOrgAdmin, &profile.User.PasswordChangedAt, &profile.User.MustChangePassword,
 			&profileData, &profile.Accounts.Github, &profile.Accounts.Google, &profile.Accounts.Facebook, &totalUsers,
 		); err != nil {
 			return nil, totalUsers, err
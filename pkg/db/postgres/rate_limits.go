@@ -0,0 +1,48 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// refilledTokens is a number of tokens in existing bucket after refill, $2 is capacity and $3 is refill rate.
+const refilledTokens = "LEAST($2::float8, rate_limits.tokens + EXTRACT(EPOCH FROM (NOW() - rate_limits.updated_at)) * $3::float8)"
+
+func (pgdb *pgDB) TakeRateLimitToken(ctx context.Context, key string, capacity, refillPerSecond float64) (float64, bool, error) {
+	pgdb.log.Debugln("Take rate limit token", key)
+	// upsert locks row, so concurrent requests from other replicas are serialized
+	rows, err := pgdb.qLog.QueryxContext(ctx, "INSERT INTO rate_limits AS rate_limits (key, tokens, allowed, updated_at, full_at) "+
+		"VALUES ($1, $2::float8 - 1, TRUE, NOW(), NOW() + make_interval(secs => 1 / $3::float8)) "+
+		"ON CONFLICT (key) DO UPDATE SET "+
+		"tokens = CASE WHEN "+refilledTokens+" >= 1 THEN "+refilledTokens+" - 1 ELSE "+refilledTokens+" END, "+
+		"allowed = "+refilledTokens+" >= 1, "+
+		"full_at = NOW() + make_interval(secs => ($2::float8 - "+refilledTokens+" + CASE WHEN "+refilledTokens+" >= 1 THEN 1 ELSE 0 END) / $3::float8), "+
+		"updated_at = NOW() "+
+		"RETURNING tokens, allowed",
+		key, capacity, refillPerSecond)
+	if err != nil {
+		return 0, false, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return 0, false, err
+		}
+		return 0, false, errors.New("rate limit bucket was not returned")
+	}
+	var tokens float64
+	var allowed bool
+	err = rows.Scan(&tokens, &allowed)
+	return tokens, allowed, err
+}
+
+func (pgdb *pgDB) DeleteFullRateLimits(ctx context.Context, before time.Time, limit int) (int64, error) {
+	pgdb.log.Infoln("Delete rate limit buckets full before", before.Format(time.ANSIC))
+	res, err := pgdb.eLog.ExecContext(ctx, "DELETE FROM rate_limits WHERE key IN "+
+		"(SELECT key FROM rate_limits WHERE full_at < $1 LIMIT $2)", before, limit)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,134 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"git.containerum.net/ch/user-manager/pkg/db"
+)
+
+const serviceAccountQueryColumns = "service_accounts.owner_id, service_accounts.created_at, " +
+	"users.id, users.login, users.password_hash, users.salt, users.role, users.is_active, users.is_deleted, users.is_in_blacklist, users.org_id, users.is_org_admin, users.password_changed_at, users.must_change_password"
+const apiKeyQueryColumns = "id, user_id, name, prefix, key_hash, created_at, expires_at, last_used_at"
+
+func (pgdb *pgDB) CreateServiceAccount(ctx context.Context, account *db.ServiceAccount) error {
+	pgdb.log.Infoln("Create service account", account.User.Login)
+	rows, err := pgdb.qLog.QueryxContext(ctx, "INSERT INTO service_accounts (user_id, owner_id) VALUES ($1, $2) RETURNING created_at",
+		account.User.ID, account.OwnerID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return rows.Err()
+	}
+	return rows.Scan(&account.CreatedAt)
+}
+
+func (pgdb *pgDB) GetServiceAccount(ctx context.Context, userID string) (*db.ServiceAccount, error) {
+	pgdb.log.Infoln("Get service account", userID)
+	rows, err := pgdb.qLog.QueryxContext(ctx, "SELECT "+serviceAccountQueryColumns+" FROM service_accounts "+
+		"JOIN users ON service_accounts.user_id = users.id WHERE service_accounts.user_id = $1 AND NOT users.is_deleted", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	account := db.ServiceAccount{User: &db.User{}}
+	err = rows.Scan(&account.OwnerID, &account.CreatedAt,
+		&account.User.ID, &account.User.Login, &account.User.PasswordHash, &account.User.Salt, &account.User.Role,
+		&account.User.IsActive, &account.User.IsDeleted, &account.User.IsInBlacklist, &account.User.OrgID, &account.User.IsOrgAdmin, &account.User.PasswordChangedAt, &account.User.MustChangePassword)
+	return &account, err
+}
+
+func (pgdb *pgDB) GetServiceAccountsByOwner(ctx context.Context, ownerID string) ([]db.ServiceAccount, error) {
+	pgdb.log.Infoln("Get service accounts owned by", ownerID)
+	ret := make([]db.ServiceAccount, 0)
+	rows, err := pgdb.qLog.QueryxContext(ctx, "SELECT "+serviceAccountQueryColumns+" FROM service_accounts "+
+		"JOIN users ON service_accounts.user_id = users.id WHERE service_accounts.owner_id = $1 AND NOT users.is_deleted "+
+		"ORDER BY users.login", ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		account := db.ServiceAccount{User: &db.User{}}
+		if err := rows.Scan(&account.OwnerID, &account.CreatedAt,
+			&account.User.ID, &account.User.Login, &account.User.PasswordHash, &account.User.Salt, &account.User.Role,
+			&account.User.IsActive, &account.User.IsDeleted, &account.User.IsInBlacklist, &account.User.OrgID, &account.User.IsOrgAdmin, &account.User.PasswordChangedAt, &account.User.MustChangePassword); err != nil {
+			return nil, err
+		}
+		ret = append(ret, account)
+	}
+	return ret, rows.Err()
+}
+
+func (pgdb *pgDB) CreateAPIKey(ctx context.Context, key *db.APIKey) error {
+	pgdb.log.WithField("prefix", key.Prefix).Infoln("Create API key", key.Name, "for", key.UserID)
+	rows, err := pgdb.qLog.QueryxContext(ctx, "INSERT INTO api_keys (user_id, name, prefix, key_hash, expires_at) "+
+		"VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at", key.UserID, key.Name, key.Prefix, key.KeyHash, key.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return rows.Err()
+	}
+	return rows.Scan(&key.ID, &key.CreatedAt)
+}
+
+func (pgdb *pgDB) GetAPIKeyByPrefix(ctx context.Context, prefix string) (*db.APIKey, error) {
+	pgdb.log.Infoln("Get API key by prefix", prefix)
+	rows, err := pgdb.qLog.QueryxContext(ctx, "SELECT "+apiKeyQueryColumns+" FROM api_keys WHERE prefix = $1", prefix)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	var key db.APIKey
+	err = rows.Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.KeyHash, &key.CreatedAt, &key.ExpiresAt, &key.LastUsedAt)
+	return &key, err
+}
+
+func (pgdb *pgDB) GetAPIKeys(ctx context.Context, userID string) ([]db.APIKey, error) {
+	pgdb.log.Infoln("Get API keys for", userID)
+	ret := make([]db.APIKey, 0)
+	rows, err := pgdb.qLog.QueryxContext(ctx, "SELECT "+apiKeyQueryColumns+" FROM api_keys WHERE user_id = $1 ORDER BY created_at", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key db.APIKey
+		if err := rows.Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.KeyHash, &key.CreatedAt, &key.ExpiresAt, &key.LastUsedAt); err != nil {
+			return nil, err
+		}
+		ret = append(ret, key)
+	}
+	return ret, rows.Err()
+}
+
+func (pgdb *pgDB) DeleteAPIKey(ctx context.Context, userID, keyID string) error {
+	pgdb.log.Infoln("Delete API key", keyID, "of", userID)
+	res, err := pgdb.eLog.ExecContext(ctx, "DELETE FROM api_keys WHERE id = $1 AND user_id = $2", keyID, userID)
+	if err != nil {
+		return err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	} else if rows == 0 {
+		return errors.New("api key not found")
+	}
+	return nil
+}
+
+func (pgdb *pgDB) UpdateAPIKeyLastUsed(ctx context.Context, keyID string) error {
+	pgdb.log.Infoln("Update API key last usage", keyID)
+	_, err := pgdb.eLog.ExecContext(ctx, "UPDATE api_keys SET last_used_at = NOW() WHERE id = $1", keyID)
+	return err
+}
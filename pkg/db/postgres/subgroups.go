@@ -0,0 +1,194 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"git.containerum.net/ch/user-manager/pkg/db"
+	kube_types "github.com/containerum/kube-client/pkg/model"
+	"github.com/lib/pq"
+)
+
+// accessRanks orders group access levels, higher rank gives more permissions.
+var accessRanks = map[string]int{
+	string(kube_types.NoAccess):     0,
+	string(kube_types.GuestAccess):  1,
+	string(kube_types.MemberAccess): 2,
+	string(kube_types.MasterAccess): 3,
+	string(kube_types.AdminAccess):  4,
+	string(kube_types.OwnerAccess):  5,
+}
+
+type groupAccessEntry struct {
+	db.GroupAccess
+	label string
+}
+
+type subgroupEdge struct {
+	parentID    string
+	parentLabel string
+	childID     string
+	access      string
+}
+
+// lowerAccess returns access which gives less permissions.
+func lowerAccess(a, b string) string {
+	if accessRanks[b] < accessRanks[a] {
+		return b
+	}
+	return a
+}
+
+// earlierExpiration returns expiration which comes first, not set expiration means never.
+func earlierExpiration(a, b pq.NullTime) pq.NullTime {
+	if !a.Valid || (b.Valid && b.Time.Before(a.Time)) {
+		return b
+	}
+	return a
+}
+
+// resolveEffectiveAccess computes user access to all groups reachable from groups where user is direct member.
+// Access along a path is limited by the lowest access on it and the highest access along any path wins.
+func resolveEffectiveAccess(direct map[string]groupAccessEntry, edges []subgroupEdge) map[string]groupAccessEntry {
+	ret := make(map[string]groupAccessEntry, len(direct))
+	for k, v := range direct {
+		ret[k] = v
+	}
+	// ranks are only increased so loop terminates even if groups have cycles
+	for changed := true; changed; {
+		changed = false
+		for _, edge := range edges {
+			child, ok := ret[edge.childID]
+			if !ok {
+				continue
+			}
+			candidate := lowerAccess(child.Access, edge.access)
+			parent, ok := ret[edge.parentID]
+			if ok && accessRanks[candidate] <= accessRanks[parent.Access] {
+				continue
+			}
+			ret[edge.parentID] = groupAccessEntry{
+				GroupAccess: db.GroupAccess{
+					Access:    candidate,
+					Inherited: true,
+					ExpiresAt: earlierExpiration(child.ExpiresAt, parent.ExpiresAt),
+				},
+				label: edge.parentLabel,
+			}
+			changed = true
+		}
+	}
+	return ret
+}
+
+func (pgdb *pgDB) getDirectGroupsAccesses(ctx context.Context, userID string) (map[string]groupAccessEntry, error) {
+	ret := make(map[string]groupAccessEntry)
+	rows, err := pgdb.qLog.QueryxContext(ctx, "SELECT groups.id, groups.label, default_access, expires_at FROM groups_members "+
+		"JOIN groups ON group_id = groups.id WHERE user_id = $1", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var groupID string
+		var entry groupAccessEntry
+		if err := rows.Scan(&groupID, &entry.label, &entry.Access, &entry.ExpiresAt); err != nil {
+			return nil, err
+		}
+		ret[groupID] = entry
+	}
+	return ret, rows.Err()
+}
+
+// getAncestorSubgroupEdges returns all subgroup relations above groups where user is direct member.
+func (pgdb *pgDB) getAncestorSubgroupEdges(ctx context.Context, userID string) ([]subgroupEdge, error) {
+	ret := make([]subgroupEdge, 0)
+	rows, err := pgdb.qLog.QueryxContext(ctx, "WITH RECURSIVE ancestors (parent_group_id, child_group_id, default_access) AS ("+
+		"SELECT s.parent_group_id, s.child_group_id, s.default_access FROM groups_subgroups s "+
+		"JOIN groups_members gm ON gm.group_id = s.child_group_id WHERE gm.user_id = $1 "+
+		"UNION "+
+		"SELECT s.parent_group_id, s.child_group_id, s.default_access FROM groups_subgroups s "+
+		"JOIN ancestors a ON s.child_group_id = a.parent_group_id) "+
+		"SELECT ancestors.parent_group_id, groups.label, ancestors.child_group_id, ancestors.default_access FROM ancestors "+
+		"JOIN groups ON ancestors.parent_group_id = groups.id", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var edge subgroupEdge
+		if err := rows.Scan(&edge.parentID, &edge.parentLabel, &edge.childID, &edge.access); err != nil {
+			return nil, err
+		}
+		ret = append(ret, edge)
+	}
+	return ret, rows.Err()
+}
+
+func (pgdb *pgDB) AddSubgroup(ctx context.Context, parentID, childID, access string) error {
+	pgdb.log.WithField("parent_id", parentID).Infoln("Add subgroup", childID)
+
+	// prevent concurrent insertions which may create cycle together
+	if _, err := pgdb.eLog.ExecContext(ctx, "LOCK TABLE groups_subgroups IN SHARE ROW EXCLUSIVE MODE"); err != nil {
+		return err
+	}
+
+	rows, err := pgdb.qLog.QueryxContext(ctx, "WITH RECURSIVE ancestors (group_id) AS ("+
+		"SELECT $1::UUID "+
+		"UNION "+
+		"SELECT s.parent_group_id FROM groups_subgroups s JOIN ancestors a ON s.child_group_id = a.group_id) "+
+		"SELECT count(*) FROM ancestors WHERE group_id = $2", parentID, childID)
+	if err != nil {
+		return err
+	}
+	var cycles int
+	if rows.Next() {
+		err = rows.Scan(&cycles)
+	}
+	rows.Close()
+	if err != nil {
+		return err
+	}
+	if cycles > 0 {
+		return db.ErrGroupCycle
+	}
+
+	_, err = pgdb.eLog.ExecContext(ctx, "INSERT INTO groups_subgroups (parent_group_id, child_group_id, default_access) "+
+		"VALUES ($1, $2, $3) ON CONFLICT (parent_group_id, child_group_id) DO UPDATE SET default_access = $3",
+		parentID, childID, access)
+	return err
+}
+
+func (pgdb *pgDB) GetSubgroups(ctx context.Context, parentID string) ([]db.Subgroup, error) {
+	pgdb.log.Infoln("Get subgroups", parentID)
+	ret := make([]db.Subgroup, 0)
+	rows, err := pgdb.qLog.QueryxContext(ctx, "SELECT s.parent_group_id, s.child_group_id, groups.label, s.default_access, s.added_at "+
+		"FROM groups_subgroups s JOIN groups ON s.child_group_id = groups.id WHERE s.parent_group_id = $1 ORDER BY groups.label", parentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var subgroup db.Subgroup
+		if err := rows.Scan(&subgroup.ParentID, &subgroup.ChildID, &subgroup.Label, &subgroup.Access, &subgroup.AddedAt); err != nil {
+			return nil, err
+		}
+		ret = append(ret, subgroup)
+	}
+	return ret, rows.Err()
+}
+
+func (pgdb *pgDB) DeleteSubgroup(ctx context.Context, parentID, childID string) error {
+	pgdb.log.WithField("parent_id", parentID).Infoln("Delete subgroup", childID)
+	res, err := pgdb.eLog.ExecContext(ctx, "DELETE FROM groups_subgroups WHERE parent_group_id = $1 AND child_group_id = $2", parentID, childID)
+	if err != nil {
+		return err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	} else if rows == 0 {
+		return errors.New("group is not a subgroup")
+	}
+	return nil
+}
@@ -10,7 +10,7 @@ import (
 )
 
 const tokenQueryColumnsWithUser = "tokens.token, tokens.created_at, tokens.is_active, tokens.session_id, " +
-	"users.id, users.login, users.password_hash, users.salt, users.role, users.is_active, users.is_deleted, users.is_in_blacklist"
+	"users.id, users.login, users.password_hash, users.salt, users.role, users.is_active, users.is_deleted, users.is_in_blacklist, users.org_id, users.is_org_admin, users.password_changed_at, users.must_change_password"
 
 func (pgdb *pgDB) GetTokenObject(ctx context.Context, token string) (*db.Token, error) {
 	pgdb.log.Infoln("Get token object", token)
@@ -26,7 +26,7 @@ func (pgdb *pgDB) GetTokenObject(ctx context.Context, token string) (*db.Token,
 	ret := db.Token{User: &db.User{}}
 	err = rows.Scan(&ret.Token, &ret.CreatedAt, &ret.IsActive, &ret.SessionID,
 		&ret.User.ID, &ret.User.Login, &ret.User.PasswordHash, &ret.User.Salt, &ret.User.Role,
-		&ret.User.IsActive, &ret.User.IsDeleted, &ret.User.IsInBlacklist)
+		&ret.User.IsActive, &ret.User.IsDeleted, &ret.User.IsInBlacklist, &ret.User.OrgID, &ret.User.IsOrgAdmin, &ret.User.PasswordChangedAt, &ret.User.MustChangePassword)
 	return &ret, err
 }
 
@@ -58,7 +58,7 @@ func (pgdb *pgDB) GetTokenBySessionID(ctx context.Context, sessionID string) (*d
 	ret := db.Token{User: &db.User{}}
 	err = rows.Scan(&ret.Token, &ret.CreatedAt, &ret.IsActive, &ret.SessionID,
 		&ret.User.ID, &ret.User.Login, &ret.User.PasswordHash, &ret.User.Salt, &ret.User.Role,
-		&ret.User.IsActive, &ret.User.IsDeleted, &ret.User.IsInBlacklist)
+		&ret.User.IsActive, &ret.User.IsDeleted, &ret.User.IsInBlacklist, &ret.User.OrgID, &ret.User.IsOrgAdmin, &ret.User.PasswordChangedAt, &ret.User.MustChangePassword)
 
 	return &ret, err
 }
@@ -75,3 +75,13 @@ func (pgdb *pgDB) UpdateToken(ctx context.Context, token *db.Token) error {
 		token.Token, token.IsActive, token.SessionID)
 	return err
 }
+
+func (pgdb *pgDB) DeleteInactiveTokens(ctx context.Context, before time.Time, limit int) (int64, error) {
+	pgdb.log.Infoln("Delete inactive tokens created before", before.Format(time.ANSIC))
+	res, err := pgdb.eLog.ExecContext(ctx, "DELETE FROM tokens WHERE token IN "+
+		"(SELECT token FROM tokens WHERE NOT is_active AND created_at < $1 LIMIT $2)", before, limit)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
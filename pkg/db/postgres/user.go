@@ -2,12 +2,13 @@ package postgres
 
 import (
 	"context"
+	"time"
 
 	"git.containerum.net/ch/user-manager/pkg/db"
 	"github.com/jmoiron/sqlx"
 )
 
-const userQueryColumns = "id, login, password_hash, salt, role, is_active, is_deleted, is_in_blacklist"
+const userQueryColumns = "id, login, password_hash, salt, role, is_active, is_deleted, is_in_blacklist, org_id, is_org_admin, password_changed_at, must_change_password"
 
 func (pgdb *pgDB) GetUserByLogin(ctx context.Context, login string) (*db.User, error) {
 	pgdb.log.Infoln("Get user by login", login)
@@ -88,9 +89,12 @@ func (pgdb *pgDB) GetAnyUserByID(ctx context.Context, id string) (*db.User, erro
 
 func (pgdb *pgDB) CreateUser(ctx context.Context, user *db.User) error {
 	pgdb.log.Infoln("Create user", user.Login)
-	rows, err := pgdb.qLog.QueryxContext(ctx, "INSERT INTO users (login, password_hash, salt, role, is_active) "+
-		"VALUES ($1, $2, $3, $4, $5) RETURNING id",
-		user.Login, user.PasswordHash, user.Salt, user.Role, user.IsActive)
+	if user.OrgID == "" {
+		user.OrgID = db.DefaultOrganizationID
+	}
+	rows, err := pgdb.qLog.QueryxContext(ctx, "INSERT INTO users (login, password_hash, salt, role, is_active, org_id, must_change_password) "+
+		"VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, password_changed_at",
+		user.Login, user.PasswordHash, user.Salt, user.Role, user.IsActive, user.OrgID, user.MustChangePassword)
 	if err != nil {
 		return err
 	}
@@ -98,15 +102,18 @@ func (pgdb *pgDB) CreateUser(ctx context.Context, user *db.User) error {
 	if !rows.Next() {
 		return rows.Err()
 	}
-	err = rows.Scan(&user.ID)
+	err = rows.Scan(&user.ID, &user.PasswordChangedAt)
 	return err
 }
 
 func (pgdb *pgDB) CreateUserWOContext(user *db.User) error {
 	pgdb.log.Infoln("Create user", user.Login)
-	rows, err := pgdb.conn.DB.Query("INSERT INTO users (login, password_hash, salt, role, is_active) "+
-		"VALUES ($1, $2, $3, $4, $5) RETURNING id",
-		user.Login, user.PasswordHash, user.Salt, user.Role, user.IsActive)
+	if user.OrgID == "" {
+		user.OrgID = db.DefaultOrganizationID
+	}
+	rows, err := pgdb.conn.DB.Query("INSERT INTO users (login, password_hash, salt, role, is_active, org_id) "+
+		"VALUES ($1, $2, $3, $4, $5, $6) RETURNING id",
+		user.Login, user.PasswordHash, user.Salt, user.Role, user.IsActive, user.OrgID)
 	if err != nil {
 		return err
 	}
@@ -121,24 +128,28 @@ func (pgdb *pgDB) CreateUserWOContext(user *db.User) error {
 func (pgdb *pgDB) UpdateUser(ctx context.Context, user *db.User) error {
 	pgdb.log.Infoln("Update user", user.Login)
 	_, err := pgdb.eLog.ExecContext(ctx, "UPDATE users SET "+
-		"login = $2, password_hash = $3, salt = $4, role = $5, is_active = $6, is_deleted = $7 WHERE id = $1",
-		user.ID, user.Login, user.PasswordHash, user.Salt, user.Role, user.IsActive, user.IsDeleted)
+		"login = $2, password_hash = $3, salt = $4, role = $5, is_active = $6, is_deleted = $7, must_change_password = $9, "+
+		// expiry notice is sent again after password change
+		"password_expiry_notified_at = CASE WHEN password_changed_at = $8 THEN password_expiry_notified_at END, "+
+		"password_changed_at = $8 WHERE id = $1",
+		user.ID, user.Login, user.PasswordHash, user.Salt, user.Role, user.IsActive, user.IsDeleted, user.PasswordChangedAt, user.MustChangePassword)
 	return err
 }
 
 func (pgdb *pgDB) UpdateUserWOContext(user *db.User) error {
 	pgdb.log.Infoln("Update user", user.Login)
 	_, err := pgdb.conn.DB.Exec("UPDATE users SET "+
-		"password_hash = $2 WHERE id = $1",
+		"password_hash = $2, password_changed_at = NOW(), password_expiry_notified_at = NULL WHERE id = $1",
 		user.ID, user.PasswordHash)
 	return err
 }
 
-func (pgdb *pgDB) GetBlacklistedUsers(ctx context.Context, limit, offset int) ([]db.User, error) {
-	pgdb.log.Infoln("Get blacklisted users")
+func (pgdb *pgDB) GetBlacklistedUsers(ctx context.Context, orgID string, limit, offset int) ([]db.User, error) {
+	pgdb.log.Infoln("Get blacklisted users", orgID)
 	resp := make([]db.User, 0)
-	rows, err := pgdb.qLog.QueryxContext(ctx, "SELECT "+userQueryColumns+" FROM users WHERE is_in_blacklist ORDER BY users.login LIMIT $1 OFFSET $2",
-		limit, offset)
+	rows, err := pgdb.qLog.QueryxContext(ctx, "SELECT "+userQueryColumns+" FROM users WHERE is_in_blacklist "+
+		"AND ($3 = '' OR org_id::TEXT = $3) ORDER BY users.login LIMIT $1 OFFSET $2",
+		limit, offset, orgID)
 	if err != nil {
 		return nil, err
 	}
@@ -223,3 +234,54 @@ func (pgdb *pgDB) CountAdmins(ctx context.Context) (*int, error) {
 	err = rows.Scan(&count)
 	return &count, err
 }
+
+func (pgdb *pgDB) RevokeUserLinks(ctx context.Context, userID string) error {
+	pgdb.log.Infoln("Revoke signed links for user", userID)
+	if _, err := pgdb.eLog.ExecContext(ctx, "UPDATE users SET link_nonce = link_nonce + 1 WHERE id = $1", userID); err != nil {
+		return err
+	}
+	// stored links were signed with previous nonce and must not be resent
+	_, err := pgdb.eLog.ExecContext(ctx, "UPDATE links SET is_active = false WHERE user_id = $1", userID)
+	return err
+}
+
+func (pgdb *pgDB) getLinkNonce(ctx context.Context, userID string) (int64, error) {
+	rows, err := pgdb.qLog.QueryxContext(ctx, "SELECT link_nonce FROM users WHERE id = $1", userID)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	var nonce int64
+	if !rows.Next() {
+		return 0, rows.Err()
+	}
+	err = rows.Scan(&nonce)
+	return nonce, err
+}
+
+func (pgdb *pgDB) GetUsersWithExpiringPasswords(ctx context.Context, changedBefore time.Time, limit int) ([]db.User, error) {
+	pgdb.log.Infoln("Get users with expiring passwords")
+	resp := make([]db.User, 0)
+	rows, err := pgdb.qLog.QueryxContext(ctx, "SELECT "+userQueryColumns+" FROM users "+
+		"WHERE password_changed_at < $1 AND password_expiry_notified_at IS NULL "+
+		"AND is_active AND NOT is_deleted AND NOT is_in_blacklist AND role != 'service' "+
+		"ORDER BY password_changed_at LIMIT $2", changedBefore, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var user db.User
+		if err := rows.StructScan(&user); err != nil {
+			return nil, err
+		}
+		resp = append(resp, user)
+	}
+	return resp, rows.Err()
+}
+
+func (pgdb *pgDB) MarkPasswordExpiryNotified(ctx context.Context, userID string) error {
+	pgdb.log.Infoln("Mark password expiry notified", userID)
+	_, err := pgdb.eLog.ExecContext(ctx, "UPDATE users SET password_expiry_notified_at = NOW() WHERE id = $1", userID)
+	return err
+}
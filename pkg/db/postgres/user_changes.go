@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const userChangesChannel = "user_changes"
+
+func (pgdb *pgDB) NotifyUserChanged(ctx context.Context, userID string) error {
+	pgdb.log.Infoln("Notify user changed", userID)
+	_, err := pgdb.eLog.ExecContext(ctx, "SELECT pg_notify($1, $2)", userChangesChannel, userID)
+	return err
+}
+
+func (pgdb *pgDB) ListenUserChanges(ctx context.Context, f func(userID string)) error {
+	e := pgdb.log.WithField("channel", userChangesChannel)
+	listener := pq.NewListener(pgdb.connStr, 10*time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			e.WithError(err).Warnln("Listener connection event", ev)
+		}
+	})
+	defer listener.Close()
+	if err := listener.Listen(userChangesChannel); err != nil {
+		return err
+	}
+	e.Infoln("Listening user changes")
+
+	ping := time.NewTicker(90 * time.Second)
+	defer ping.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case n := <-listener.Notify:
+			if n == nil {
+				// connection was re-established, notifications sent meanwhile are lost
+				f("")
+				continue
+			}
+			f(n.Extra)
+		case <-ping.C:
+			if err := listener.Ping(); err != nil {
+				return err
+			}
+		}
+	}
+}
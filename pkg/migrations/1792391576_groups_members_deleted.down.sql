@@ -0,0 +1 @@
+DROP TABLE IF EXISTS groups_members_deleted;
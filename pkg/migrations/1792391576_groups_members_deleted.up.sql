@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS groups_members_deleted
+(
+  group_id UUID NOT NULL,
+  user_id UUID NOT NULL,
+  default_access TEXT NOT NULL,
+  added_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+  deleted_at TIMESTAMP WITHOUT TIME ZONE DEFAULT NOW() NOT NULL,
+  CONSTRAINT group_member_deleted_group_id FOREIGN KEY (group_id) REFERENCES groups (id) ON DELETE CASCADE,
+  CONSTRAINT group_member_deleted_user_id FOREIGN KEY (user_id) REFERENCES users (id),
+  CONSTRAINT unique_deleted_user_id_group UNIQUE (group_id, user_id)
+);
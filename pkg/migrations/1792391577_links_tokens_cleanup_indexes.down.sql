@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS tokens_inactive_created_at_idx;
+DROP INDEX IF EXISTS links_expired_at_idx;
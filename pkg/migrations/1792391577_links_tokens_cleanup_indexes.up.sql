@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS links_expired_at_idx ON links (expired_at);
+CREATE INDEX IF NOT EXISTS tokens_inactive_created_at_idx ON tokens (created_at) WHERE NOT is_active;
@@ -0,0 +1,2 @@
+ALTER TABLE links
+  ADD COLUMN resend_count INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+ALTER TABLE users
+  ADD COLUMN link_nonce BIGINT NOT NULL DEFAULT 0;
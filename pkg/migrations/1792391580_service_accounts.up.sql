@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS service_accounts
+(
+    user_id UUID PRIMARY KEY NOT NULL,
+    owner_id UUID NOT NULL,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+    CONSTRAINT service_accounts_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT service_accounts_owner_id_fkey FOREIGN KEY (owner_id) REFERENCES users (id)
+);
+CREATE INDEX IF NOT EXISTS service_accounts_owner_id_idx ON service_accounts (owner_id);
+
+CREATE TABLE IF NOT EXISTS api_keys
+(
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY NOT NULL,
+    user_id UUID NOT NULL,
+    name TEXT NOT NULL,
+    prefix TEXT NOT NULL,
+    key_hash TEXT NOT NULL,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITHOUT TIME ZONE,
+    last_used_at TIMESTAMP WITHOUT TIME ZONE,
+    CONSTRAINT api_keys_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT unique_api_key_prefix UNIQUE (prefix),
+    CONSTRAINT unique_api_key_name UNIQUE (user_id, name)
+);
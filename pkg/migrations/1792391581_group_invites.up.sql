@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS group_invites
+(
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY NOT NULL,
+    group_id UUID NOT NULL,
+    email TEXT NOT NULL,
+    access TEXT NOT NULL,
+    invited_by UUID NOT NULL,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    CONSTRAINT group_invites_group_id_fkey FOREIGN KEY (group_id) REFERENCES groups (id) ON DELETE CASCADE,
+    CONSTRAINT group_invites_invited_by_fkey FOREIGN KEY (invited_by) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT unique_group_invite_email UNIQUE (group_id, email)
+);
+CREATE INDEX IF NOT EXISTS group_invites_email_idx ON group_invites (email);
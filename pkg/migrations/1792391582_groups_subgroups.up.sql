@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS groups_subgroups
+(
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY NOT NULL,
+    parent_group_id UUID NOT NULL,
+    child_group_id UUID NOT NULL,
+    default_access TEXT NOT NULL,
+    added_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+    CONSTRAINT groups_subgroups_parent_fkey FOREIGN KEY (parent_group_id) REFERENCES groups (id) ON DELETE CASCADE,
+    CONSTRAINT groups_subgroups_child_fkey FOREIGN KEY (child_group_id) REFERENCES groups (id) ON DELETE CASCADE,
+    CONSTRAINT unique_subgroup UNIQUE (parent_group_id, child_group_id),
+    CONSTRAINT subgroup_not_self CHECK (parent_group_id <> child_group_id)
+);
+CREATE INDEX IF NOT EXISTS groups_subgroups_child_idx ON groups_subgroups (child_group_id);
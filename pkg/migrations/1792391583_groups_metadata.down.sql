@@ -0,0 +1,4 @@
+ALTER TABLE groups
+  DROP COLUMN IF EXISTS description,
+  DROP COLUMN IF EXISTS labels,
+  DROP COLUMN IF EXISTS updated_at;
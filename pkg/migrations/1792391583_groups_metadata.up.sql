@@ -0,0 +1,5 @@
+ALTER TABLE groups
+  ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS labels JSONB NOT NULL DEFAULT '{}',
+  ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW();
+UPDATE groups SET updated_at = created_at;
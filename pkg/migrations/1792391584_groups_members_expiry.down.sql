@@ -0,0 +1,7 @@
+ALTER TABLE groups_members_deleted
+  DROP COLUMN IF EXISTS expires_at;
+
+DROP INDEX IF EXISTS groups_members_expires_at_idx;
+ALTER TABLE groups_members
+  DROP COLUMN IF EXISTS expires_at,
+  DROP COLUMN IF EXISTS expiry_notified_at;
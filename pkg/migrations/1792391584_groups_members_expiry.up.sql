@@ -0,0 +1,7 @@
+ALTER TABLE groups_members
+  ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP WITHOUT TIME ZONE,
+  ADD COLUMN IF NOT EXISTS expiry_notified_at TIMESTAMP WITHOUT TIME ZONE;
+CREATE INDEX IF NOT EXISTS groups_members_expires_at_idx ON groups_members (expires_at) WHERE expires_at IS NOT NULL;
+
+ALTER TABLE groups_members_deleted
+  ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP WITHOUT TIME ZONE;
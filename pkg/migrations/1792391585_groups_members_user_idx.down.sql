@@ -0,0 +1 @@
+DROP INDEX IF EXISTS groups_members_user_id_idx;
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS groups_members_user_id_idx ON groups_members (user_id);
@@ -0,0 +1,10 @@
+ALTER TABLE groups
+  DROP CONSTRAINT IF EXISTS group_org_id,
+  DROP COLUMN IF EXISTS org_id;
+
+ALTER TABLE users
+  DROP CONSTRAINT IF EXISTS user_org_id,
+  DROP COLUMN IF EXISTS is_org_admin,
+  DROP COLUMN IF EXISTS org_id;
+
+DROP TABLE IF EXISTS organizations;
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS organizations
+(
+  id UUID DEFAULT uuid_generate_v4() PRIMARY KEY NOT NULL,
+  name TEXT NOT NULL,
+  created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT NOW() NOT NULL,
+  CONSTRAINT unique_organization_name UNIQUE (name)
+);
+
+-- existing users and groups are moved to default organization
+INSERT INTO organizations (id, name) VALUES ('00000000-0000-0000-0000-000000000001', 'default')
+  ON CONFLICT DO NOTHING;
+
+ALTER TABLE users
+  ADD COLUMN IF NOT EXISTS org_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001',
+  ADD COLUMN IF NOT EXISTS is_org_admin BOOLEAN NOT NULL DEFAULT FALSE,
+  ADD CONSTRAINT user_org_id FOREIGN KEY (org_id) REFERENCES organizations (id);
+CREATE INDEX IF NOT EXISTS users_org_id_idx ON users (org_id);
+
+ALTER TABLE groups
+  ADD COLUMN IF NOT EXISTS org_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001',
+  ADD CONSTRAINT group_org_id FOREIGN KEY (org_id) REFERENCES organizations (id);
+CREATE INDEX IF NOT EXISTS groups_org_id_idx ON groups (org_id);
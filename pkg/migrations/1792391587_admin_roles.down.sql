@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS users_admin_roles;
+DROP TABLE IF EXISTS admin_roles_permissions;
+DROP TABLE IF EXISTS admin_roles;
@@ -0,0 +1,46 @@
+CREATE TABLE IF NOT EXISTS admin_roles
+(
+  name TEXT PRIMARY KEY NOT NULL,
+  description TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT NOW() NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS admin_roles_permissions
+(
+  role_name TEXT NOT NULL,
+  permission TEXT NOT NULL,
+  CONSTRAINT admin_role_permission_role FOREIGN KEY (role_name) REFERENCES admin_roles (name) ON DELETE CASCADE ON UPDATE CASCADE,
+  CONSTRAINT unique_admin_role_permission UNIQUE (role_name, permission)
+);
+
+CREATE TABLE IF NOT EXISTS users_admin_roles
+(
+  user_id UUID NOT NULL,
+  role_name TEXT NOT NULL,
+  granted_at TIMESTAMP WITHOUT TIME ZONE DEFAULT NOW() NOT NULL,
+  CONSTRAINT user_admin_role_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+  CONSTRAINT user_admin_role_role FOREIGN KEY (role_name) REFERENCES admin_roles (name) ON DELETE CASCADE ON UPDATE CASCADE,
+  CONSTRAINT unique_user_admin_role UNIQUE (user_id, role_name)
+);
+CREATE INDEX IF NOT EXISTS users_admin_roles_role_name_idx ON users_admin_roles (role_name);
+
+INSERT INTO admin_roles (name, description) VALUES
+  ('support', 'Views users and resets their passwords'),
+  ('billing-viewer', 'Views users'),
+  ('user-admin', 'Manages user accounts'),
+  ('security-admin', 'Manages users and domains blacklists')
+  ON CONFLICT DO NOTHING;
+
+INSERT INTO admin_roles_permissions (role_name, permission) VALUES
+  ('support', 'users:read'),
+  ('support', 'users:reset_password'),
+  ('billing-viewer', 'users:read'),
+  ('user-admin', 'users:read'),
+  ('user-admin', 'users:write'),
+  ('user-admin', 'users:reset_password'),
+  ('user-admin', 'users:blacklist'),
+  ('security-admin', 'users:read'),
+  ('security-admin', 'users:blacklist'),
+  ('security-admin', 'domains:read'),
+  ('security-admin', 'domains:write')
+  ON CONFLICT DO NOTHING;
@@ -0,0 +1,2 @@
+DELETE FROM admin_roles_permissions WHERE permission = 'users:impersonate';
+DROP TABLE IF EXISTS impersonations;
@@ -0,0 +1,19 @@
+-- audit records are kept after users deletion, so there are no foreign keys
+CREATE TABLE IF NOT EXISTS impersonations
+(
+  id UUID DEFAULT uuid_generate_v4() PRIMARY KEY NOT NULL,
+  impersonator_id UUID NOT NULL,
+  impersonator_login TEXT NOT NULL,
+  user_id UUID NOT NULL,
+  user_login TEXT NOT NULL,
+  reason TEXT NOT NULL,
+  ip TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT NOW() NOT NULL,
+  expires_at TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
+CREATE INDEX IF NOT EXISTS impersonations_user_id_idx ON impersonations (user_id, created_at);
+CREATE INDEX IF NOT EXISTS impersonations_impersonator_id_idx ON impersonations (impersonator_id, created_at);
+
+INSERT INTO admin_roles_permissions (role_name, permission)
+  SELECT name, 'users:impersonate' FROM admin_roles WHERE name = 'support'
+  ON CONFLICT DO NOTHING;
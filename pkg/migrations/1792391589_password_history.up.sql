@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS users_password_history
+(
+  user_id UUID NOT NULL,
+  password_hash TEXT NOT NULL,
+  created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT NOW() NOT NULL,
+  CONSTRAINT password_history_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS users_password_history_user_id_idx ON users_password_history (user_id, created_at);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS users_password_changed_at_idx;
+ALTER TABLE users
+  DROP COLUMN IF EXISTS password_changed_at,
+  DROP COLUMN IF EXISTS must_change_password,
+  DROP COLUMN IF EXISTS password_expiry_notified_at;
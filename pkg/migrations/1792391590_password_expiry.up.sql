@@ -0,0 +1,5 @@
+ALTER TABLE users
+  ADD COLUMN IF NOT EXISTS password_changed_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+  ADD COLUMN IF NOT EXISTS must_change_password BOOLEAN NOT NULL DEFAULT FALSE,
+  ADD COLUMN IF NOT EXISTS password_expiry_notified_at TIMESTAMP WITHOUT TIME ZONE;
+CREATE INDEX IF NOT EXISTS users_password_changed_at_idx ON users (password_changed_at) WHERE password_expiry_notified_at IS NULL;
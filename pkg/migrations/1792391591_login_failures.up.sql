@@ -0,0 +1,8 @@
+-- failures are counted per login and per client IP, key is "login:<login>" or "ip:<ip>"
+CREATE TABLE IF NOT EXISTS login_failures
+(
+  key TEXT PRIMARY KEY NOT NULL,
+  failures INTEGER NOT NULL DEFAULT 0,
+  last_failure_at TIMESTAMP WITHOUT TIME ZONE DEFAULT NOW() NOT NULL
+);
+CREATE INDEX IF NOT EXISTS login_failures_last_failure_at_idx ON login_failures (last_failure_at);
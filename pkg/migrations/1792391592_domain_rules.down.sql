@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS domains_expires_at_idx;
+DROP INDEX IF EXISTS domains_list_kind_idx;
+DELETE FROM domains WHERE kind <> 'exact' OR list <> 'block';
+ALTER TABLE domains
+  DROP COLUMN IF EXISTS expires_at,
+  DROP COLUMN IF EXISTS reason,
+  DROP COLUMN IF EXISTS list,
+  DROP COLUMN IF EXISTS kind;
@@ -0,0 +1,9 @@
+-- kind is one of "exact", "suffix" (domain and all its subdomains) or "regex" (domain column contains pattern)
+-- list is one of "block" or "allow", allow rules are checked only when allowlist mode is enabled
+ALTER TABLE domains
+  ADD COLUMN IF NOT EXISTS kind TEXT NOT NULL DEFAULT 'exact',
+  ADD COLUMN IF NOT EXISTS list TEXT NOT NULL DEFAULT 'block',
+  ADD COLUMN IF NOT EXISTS reason TEXT NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP WITHOUT TIME ZONE;
+CREATE INDEX IF NOT EXISTS domains_list_kind_idx ON domains (list, kind);
+CREATE INDEX IF NOT EXISTS domains_expires_at_idx ON domains (expires_at) WHERE expires_at IS NOT NULL;
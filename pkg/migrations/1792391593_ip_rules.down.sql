@@ -0,0 +1,2 @@
+DELETE FROM admin_roles_permissions WHERE permission IN ('ips:read', 'ips:write');
+DROP TABLE IF EXISTS ip_rules;
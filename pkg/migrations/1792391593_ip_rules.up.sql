@@ -0,0 +1,16 @@
+-- list is one of "block" or "allow", allow rules exempt networks from block rules
+CREATE TABLE IF NOT EXISTS ip_rules
+(
+  cidr CIDR PRIMARY KEY NOT NULL,
+  list TEXT NOT NULL DEFAULT 'block',
+  reason TEXT NOT NULL DEFAULT '',
+  expires_at TIMESTAMP WITHOUT TIME ZONE,
+  created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT NOW() NOT NULL,
+  added_by UUID
+);
+CREATE INDEX IF NOT EXISTS ip_rules_cidr_idx ON ip_rules USING gist (cidr inet_ops);
+CREATE INDEX IF NOT EXISTS ip_rules_expires_at_idx ON ip_rules (expires_at) WHERE expires_at IS NOT NULL;
+
+INSERT INTO admin_roles_permissions (role_name, permission)
+  SELECT name, perm FROM admin_roles, unnest(ARRAY['ips:read', 'ips:write']) AS perm WHERE name = 'security-admin'
+  ON CONFLICT DO NOTHING;
@@ -0,0 +1,11 @@
+-- token buckets for rate limiting, allowed is result of last token take
+-- full_at is a time when bucket refills, rows after it are equivalent to missing ones
+CREATE TABLE IF NOT EXISTS rate_limits
+(
+  key TEXT PRIMARY KEY NOT NULL,
+  tokens DOUBLE PRECISION NOT NULL,
+  allowed BOOLEAN NOT NULL,
+  updated_at TIMESTAMP WITHOUT TIME ZONE DEFAULT NOW() NOT NULL,
+  full_at TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
+CREATE INDEX IF NOT EXISTS rate_limits_full_at_idx ON rate_limits (full_at);
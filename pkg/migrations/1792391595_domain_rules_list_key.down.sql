@@ -0,0 +1,3 @@
+DELETE FROM domains WHERE list <> 'block' AND domain IN (SELECT domain FROM domains WHERE list = 'block');
+ALTER TABLE domains DROP CONSTRAINT IF EXISTS domains_pkey;
+ALTER TABLE domains ADD PRIMARY KEY (domain);
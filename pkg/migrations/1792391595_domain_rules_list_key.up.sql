@@ -0,0 +1,3 @@
+-- same domain may be present in both block and allow lists
+ALTER TABLE domains DROP CONSTRAINT IF EXISTS domains_pkey;
+ALTER TABLE domains ADD PRIMARY KEY (domain, list);
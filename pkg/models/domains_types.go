@@ -2,6 +2,19 @@ package models
 
 import "time"
 
+// Domain rule kinds.
+const (
+	DomainRuleExact  = "exact"
+	DomainRuleSuffix = "suffix"
+	DomainRuleRegex  = "regex"
+)
+
+// Domain rule lists.
+const (
+	DomainListBlock = "block"
+	DomainListAllow = "allow"
+)
+
 // DomainListResponse -- domains list
 //
 // swagger:model
@@ -9,12 +22,36 @@ type DomainListResponse struct {
 	DomainList []Domain `json:"domain_list,omitempty"`
 }
 
-// DomainListResponse -- domains list
+// Domain -- email domain rule
 //
 // swagger:model
 type Domain struct {
+	// domain, suffix ("*.example.com" is a shorthand) or regular expression depending on kind
 	// required: true
-	Domain    string    `json:"domain"`
-	AddedBy   string    `json:"added_by,omitempty"`
-	CreatedAt time.Time `json:"created_at,omitempty"`
+	Domain string `json:"domain"`
+	// one of "exact", "suffix" or "regex", "exact" by default
+	Kind string `json:"kind,omitempty"`
+	// one of "block" or "allow", "block" by default
+	List      string     `json:"list,omitempty"`
+	Reason    string     `json:"reason,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	AddedBy   string     `json:"added_by,omitempty"`
+	CreatedAt time.Time  `json:"created_at,omitempty"`
+}
+
+// DomainImportRequest -- request to import list of domain rules
+type DomainImportRequest struct {
+	Domains   []string
+	Kind      string
+	List      string
+	Reason    string
+	ExpiresAt *time.Time
+}
+
+// DomainImportResponse -- domain rules import result
+//
+// swagger:model
+type DomainImportResponse struct {
+	Imported int `json:"imported"`
+	Skipped  int `json:"skipped"`
 }
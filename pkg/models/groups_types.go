@@ -0,0 +1,203 @@
+package models
+
+import (
+	"time"
+
+	kube_types "github.com/containerum/kube-client/pkg/model"
+)
+
+// GroupOwnerTransferRequest -- request to transfer group ownership
+//
+// swagger:model
+type GroupOwnerTransferRequest struct {
+	// new owner login
+	// required: true
+	Login string `json:"login"`
+}
+
+// GroupInviteRequest -- request to invite user by email to group
+//
+// swagger:model
+type GroupInviteRequest struct {
+	// required: true
+	Email string `json:"email"`
+	// required: true
+	Access string `json:"access"`
+}
+
+// GroupInvite -- pending group invitation
+//
+// swagger:model
+type GroupInvite struct {
+	ID         string    `json:"id"`
+	GroupID    string    `json:"group_id"`
+	GroupLabel string    `json:"group_label"`
+	Email      string    `json:"email"`
+	Access     string    `json:"access"`
+	InvitedBy  string    `json:"invited_by"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// GroupInvites -- group invitations list
+//
+// swagger:model
+type GroupInvites struct {
+	Invites []GroupInvite `json:"invites"`
+}
+
+// GroupInviteToken -- request to accept or decline group invitation
+//
+// swagger:model
+type GroupInviteToken struct {
+	// required: true
+	Token string `json:"token"`
+}
+
+// UserGroup -- user group with effective user access
+//
+// swagger:model
+type UserGroup struct {
+	kube_types.UserGroup
+	Description string            `json:"description,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	// organization group belongs to
+	OrgID string `json:"org_id,omitempty"`
+	//last update date in RFC3339 format
+	UpdatedAt string `json:"updated_at,omitempty"`
+	// user is not a direct member of group and got access through subgroups
+	Inherited bool `json:"inherited,omitempty"`
+	// user membership expiration, present only in groups list
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// seconds left until user membership expiration
+	ExpiresIn *int64 `json:"expires_in,omitempty"`
+	// group members with expiration info, shadows members from kube-client model
+	Members []GroupMember `json:"members,omitempty"`
+}
+
+// GroupMember -- group member with optional access expiration
+//
+// swagger:model
+type GroupMember struct {
+	kube_types.UserGroupMember
+	// membership is removed after this time
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// removes membership expiration on update, current expiration is kept if neither this nor expires_at is set
+	RemoveExpiration bool `json:"remove_expiration,omitempty"`
+	// seconds left until membership expiration, read only
+	ExpiresIn *int64 `json:"expires_in,omitempty"`
+}
+
+// GroupMembers -- list of group members
+//
+// swagger:model
+type GroupMembers struct {
+	Members []GroupMember `json:"members"`
+}
+
+// UserGroups -- list of user groups
+//
+// swagger:model
+type UserGroups struct {
+	Groups []UserGroup `json:"groups"`
+}
+
+// SubgroupAddRequest -- request to add group as member of other group
+//
+// swagger:model
+type SubgroupAddRequest struct {
+	// required: true
+	Label string `json:"label"`
+	// access which subgroup members get in parent group
+	// required: true
+	Access string `json:"access"`
+}
+
+// Subgroup -- group which members are members of parent group
+//
+// swagger:model
+type Subgroup struct {
+	ID      string    `json:"id"`
+	Label   string    `json:"label"`
+	Access  string    `json:"access"`
+	AddedAt time.Time `json:"added_at"`
+}
+
+// Subgroups -- subgroups list
+//
+// swagger:model
+type Subgroups struct {
+	Subgroups []Subgroup `json:"subgroups"`
+}
+
+// GroupUpdateRequest -- request to update group. Omitted fields are not changed
+//
+// swagger:model
+type GroupUpdateRequest struct {
+	// new group label
+	Label       *string           `json:"label,omitempty"`
+	Description *string           `json:"description,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+}
+
+// GroupMembersSyncResult -- changes made (or which would be made in dry run mode) by group members sync
+//
+// swagger:model
+type GroupMembersSyncResult struct {
+	Added     []GroupMember `json:"added"`
+	Updated   []GroupMember `json:"updated"`
+	Removed   []string      `json:"removed"`
+	Unchanged int           `json:"unchanged"`
+	DryRun    bool          `json:"dry_run"`
+}
+
+// GroupMemberInfo -- group member with user account details
+//
+// swagger:model
+type GroupMemberInfo struct {
+	GroupMember
+	AddedAt       *time.Time `json:"added_at,omitempty"`
+	IsActive      bool       `json:"is_active"`
+	IsInBlacklist bool       `json:"is_in_blacklist"`
+	LastLogin     *time.Time `json:"last_login,omitempty"`
+}
+
+// GroupMembersPage -- page of group members
+//
+// swagger:model
+type GroupMembersPage struct {
+	Members []GroupMemberInfo `json:"members"`
+	// total number of members satisfying filter
+	Total uint `json:"total"`
+	Pages uint `json:"pages"`
+}
+
+// UserGroupMembership -- user direct membership in group
+//
+// swagger:model
+type UserGroupMembership struct {
+	GroupID    string                 `json:"group_id"`
+	GroupLabel string                 `json:"group_label"`
+	OwnerID    string                 `json:"owner_user_id"`
+	OwnerLogin string                 `json:"owner_login"`
+	Access     kube_types.AccessLevel `json:"access"`
+	AddedAt    *time.Time             `json:"added_at,omitempty"`
+	ExpiresAt  *time.Time             `json:"expires_at,omitempty"`
+	ExpiresIn  *int64                 `json:"expires_in,omitempty"`
+}
+
+// UserGroupMemberships -- page of user group memberships
+//
+// swagger:model
+type UserGroupMemberships struct {
+	Groups []UserGroupMembership `json:"groups"`
+	Total  uint                  `json:"total"`
+	Pages  uint                  `json:"pages"`
+}
+
+// GroupMembersFilter -- group members list filter
+type GroupMembersFilter struct {
+	Access string
+	// case-insensitive substring of member login
+	Login string
+}
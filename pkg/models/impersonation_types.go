@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// ImpersonationRequest -- request to act as user
+//
+// swagger:model
+type ImpersonationRequest struct {
+	// required: true
+	Login string `json:"login"`
+	// Reason is recorded to audit log and sent to user
+	// required: true
+	Reason string `json:"reason"`
+}
+
+// Impersonation -- audit record of impersonation
+//
+// swagger:model
+type Impersonation struct {
+	ID                string    `json:"id"`
+	ImpersonatorID    string    `json:"impersonator_id"`
+	ImpersonatorLogin string    `json:"impersonator_login"`
+	UserID            string    `json:"user_id"`
+	UserLogin         string    `json:"user_login"`
+	Reason            string    `json:"reason"`
+	IP                string    `json:"ip,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+// Impersonations -- impersonations list
+//
+// swagger:model
+type Impersonations struct {
+	Impersonations []Impersonation `json:"impersonations"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// IP rule lists.
+const (
+	IPListBlock = "block"
+	IPListAllow = "allow"
+)
+
+// IPRule -- blocked or allowed client network
+//
+// swagger:model
+type IPRule struct {
+	// network in CIDR notation, single address is converted to /32 (/128 for IPv6) network
+	// required: true
+	CIDR string `json:"cidr"`
+	// one of "block" or "allow", "block" by default
+	List      string     `json:"list,omitempty"`
+	Reason    string     `json:"reason,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	AddedBy   string     `json:"added_by,omitempty"`
+	CreatedAt time.Time  `json:"created_at,omitempty"`
+}
+
+// IPRules -- IP rules list
+//
+// swagger:model
+type IPRules struct {
+	IPRules []IPRule `json:"ip_rules"`
+}
@@ -18,12 +18,13 @@ const (
 // swagger:model
 type Link struct {
 	// required: true
-	Link      string    `json:"link,omitempty"`
-	Type      LinkType  `json:"type,omitempty"`
-	CreatedAt time.Time `json:"created_at,omitempty"`
-	ExpiredAt time.Time `json:"expired_at,omitempty"`
-	IsActive  bool      `json:"is_active,omitempty"`
-	SentAt    time.Time `json:"sent_at,omitempty"`
+	Link        string    `json:"link,omitempty"`
+	Type        LinkType  `json:"type,omitempty"`
+	CreatedAt   time.Time `json:"created_at,omitempty"`
+	ExpiredAt   time.Time `json:"expired_at,omitempty"`
+	IsActive    bool      `json:"is_active,omitempty"`
+	SentAt      time.Time `json:"sent_at,omitempty"`
+	ResendCount int       `json:"resend_count,omitempty"`
 }
 
 // Links -- links list
@@ -32,3 +33,23 @@ type Link struct {
 type Links struct {
 	Links []Link `json:"links,omitempty"`
 }
+
+// LinkPolicy -- link lifetime and resend restrictions
+//
+// swagger:model
+type LinkPolicy struct {
+	Type LinkType `json:"type"`
+	// link lifetime in seconds
+	Lifetime int64 `json:"lifetime"`
+	// minimal interval between link sends in seconds
+	ResendCooldown int64 `json:"resend_cooldown"`
+	// maximum number of link resends, 0 means unlimited
+	MaxResends int `json:"max_resends"`
+}
+
+// LinkPolicies -- link policies list
+//
+// swagger:model
+type LinkPolicies struct {
+	Policies []LinkPolicy `json:"policies"`
+}
@@ -8,6 +8,8 @@ type LoginRequest struct {
 	Login string `json:"login"`
 	// required: true
 	Password string `json:"password"`
+	// required after too many failed login attempts
+	ReCaptcha string `json:"recaptcha,omitempty"`
 }
 
 // LoginRequest -- login request (for token login)
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Organization -- customer organization, users and groups belong to it
+//
+// swagger:model
+type Organization struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Organizations -- organizations list
+//
+// swagger:model
+type Organizations struct {
+	Organizations []Organization `json:"organizations"`
+}
+
+// OrganizationCreateRequest -- request to create organization
+//
+// swagger:model
+type OrganizationCreateRequest struct {
+	// required: true
+	Name string `json:"name"`
+}
+
+// UserOrganizationRequest -- request to move user to organization
+//
+// swagger:model
+type UserOrganizationRequest struct {
+	// required: true
+	OrgID string `json:"org_id"`
+	// user can manage users and groups of organization
+	OrgAdmin bool `json:"org_admin"`
+}
@@ -19,3 +19,37 @@ type PasswordRestoreRequest struct {
 	// required: true
 	NewPassword string `json:"new_password"`
 }
+
+// ExpiredPasswordChangeRequest -- request to change password which must be changed before login
+//
+// swagger:model
+type ExpiredPasswordChangeRequest struct {
+	// required: true
+	Login string `json:"login"`
+	// required: true
+	CurrentPassword string `json:"current_password"`
+	// required: true
+	NewPassword string `json:"new_password"`
+	// required after too many failed login attempts
+	ReCaptcha string `json:"recaptcha,omitempty"`
+}
+
+// PasswordChangeRequirement -- request to set or unset user "must change password" flag
+//
+// swagger:model
+type PasswordChangeRequirement struct {
+	// required: true
+	Login string `json:"login"`
+	// user must change password on next login
+	Required bool `json:"required"`
+}
+
+// PasswordResetRequest -- request to send password reset link
+//
+// swagger:model
+type PasswordResetRequest struct {
+	// required: true
+	Login string `json:"login"`
+	// required after too many failed login attempts
+	ReCaptcha string `json:"recaptcha,omitempty"`
+}
@@ -0,0 +1,61 @@
+package models
+
+import "time"
+
+// Permission -- action on admin API allowed by admin role
+type Permission string
+
+const (
+	PermissionUsersRead          Permission = "users:read"
+	PermissionUsersWrite         Permission = "users:write"
+	PermissionUsersResetPassword Permission = "users:reset_password"
+	PermissionUsersBlacklist     Permission = "users:blacklist"
+	PermissionDomainsRead        Permission = "domains:read"
+	PermissionDomainsWrite       Permission = "domains:write"
+	PermissionRolesManage        Permission = "roles:manage"
+	PermissionUsersImpersonate   Permission = "users:impersonate"
+	PermissionIPsRead            Permission = "ips:read"
+	PermissionIPsWrite           Permission = "ips:write"
+)
+
+// Permissions contains all known permissions
+var Permissions = []Permission{
+	PermissionUsersRead,
+	PermissionUsersWrite,
+	PermissionUsersResetPassword,
+	PermissionUsersBlacklist,
+	PermissionDomainsRead,
+	PermissionDomainsWrite,
+	PermissionRolesManage,
+	PermissionUsersImpersonate,
+	PermissionIPsRead,
+	PermissionIPsWrite,
+}
+
+// AdminRole -- named set of permissions which can be granted to user
+//
+// swagger:model
+type AdminRole struct {
+	Name        string       `json:"name"`
+	Description string       `json:"description,omitempty"`
+	Permissions []Permission `json:"permissions"`
+	CreatedAt   time.Time    `json:"created_at,omitempty"`
+}
+
+// AdminRoles -- admin roles list
+//
+// swagger:model
+type AdminRoles struct {
+	Roles []AdminRole `json:"roles"`
+	// all permissions which can be used in roles
+	KnownPermissions []Permission `json:"known_permissions"`
+}
+
+// AdminRoleRequest -- request to create or replace admin role
+//
+// swagger:model
+type AdminRoleRequest struct {
+	Description string `json:"description"`
+	// required: true
+	Permissions []Permission `json:"permissions"`
+}
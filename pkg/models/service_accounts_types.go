@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+// ServiceAccountCreateRequest -- request to create service account
+//
+// swagger:model
+type ServiceAccountCreateRequest struct {
+	// required: true
+	Name string `json:"name"`
+}
+
+// ServiceAccount -- non-human account used for automation
+//
+// swagger:model
+type ServiceAccount struct {
+	ID        string    `json:"id"`
+	Login     string    `json:"login"`
+	OwnerID   string    `json:"owner_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ServiceAccounts -- service accounts list
+//
+// swagger:model
+type ServiceAccounts struct {
+	ServiceAccounts []ServiceAccount `json:"service_accounts"`
+}
+
+// APIKeyCreateRequest -- request to create service account API key
+//
+// swagger:model
+type APIKeyCreateRequest struct {
+	// required: true
+	Name      string     `json:"name"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// APIKey -- service account API key
+//
+// swagger:model
+type APIKey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	// full key value, returned only on creation
+	Key string `json:"key,omitempty"`
+}
+
+// APIKeys -- API keys list
+//
+// swagger:model
+type APIKeys struct {
+	Keys []APIKey `json:"keys"`
+}
+
+// APIKeyLoginRequest -- login request (for API key login)
+//
+// swagger:model
+type APIKeyLoginRequest struct {
+	// required: true
+	APIKey string `json:"api_key"`
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // RegisterRequest -- request to create new user
 //
 // swagger:model
@@ -35,6 +37,13 @@ type User struct {
 	IsActive      bool   `json:"is_active,omitempty"`
 	IsInBlacklist bool   `json:"is_in_blacklist,omitempty"`
 	IsDeleted     bool   `json:"is_deleted,omitempty"`
+	OrgID         string `json:"org_id,omitempty"`
+	IsOrgAdmin    bool   `json:"is_org_admin,omitempty"`
+	// names of admin roles granted to user
+	AdminRoles []string `json:"admin_roles,omitempty"`
+	// password expiration time if password expiration is enabled
+	PasswordExpiresAt  *time.Time `json:"password_expires_at,omitempty"`
+	MustChangePassword bool       `json:"must_change_password,omitempty"`
 }
 
 // UserList -- model for user login, password and id
@@ -266,3 +266,92 @@ func AdminResetPasswordHandler(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusAccepted, resp)
 }
+
+// swagger:operation POST /admin/user/password/require_change Admin AdminPasswordChangeRequirementHandler
+// Set or unset requirement to change password on next login.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserIDHeader'
+//  - name: body
+//    in: body
+//    schema:
+//      $ref: '#/definitions/PasswordChangeRequirement'
+// responses:
+//  '202':
+//    description: password change requirement updated
+//  default:
+//    $ref: '#/responses/error'
+func AdminPasswordChangeRequirementHandler(ctx *gin.Context) {
+	um := ctx.MustGet(m.UMServices).(server.UserManager)
+
+	var request models.PasswordChangeRequirement
+	if err := ctx.ShouldBindWith(&request, binding.JSON); err != nil {
+		gonic.Gonic(umerrors.ErrRequestValidationFailed().AddDetailsErr(err), ctx)
+		return
+	}
+
+	if errs := validation.ValidateUserLogin(models.UserLogin{Login: request.Login}); errs != nil {
+		gonic.Gonic(umerrors.ErrRequestValidationFailed().AddDetailsErr(errs...), ctx)
+		return
+	}
+
+	if err := um.AdminSetPasswordChangeRequirement(ctx.Request.Context(), request); err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrUnableChangePassword(), ctx)
+		}
+		return
+	}
+
+	ctx.Status(http.StatusAccepted)
+}
+
+// swagger:operation POST /admin/user/restore Admin AdminUserRestoreHandler
+// Restore partially deleted user.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserIDHeader'
+//  - name: body
+//    in: body
+//    schema:
+//      $ref: '#/definitions/UserLogin'
+// responses:
+//  '202':
+//    description: user restored
+//  default:
+//    $ref: '#/responses/error'
+func AdminUserRestoreHandler(ctx *gin.Context) {
+	um := ctx.MustGet(m.UMServices).(server.UserManager)
+
+	var request models.UserLogin
+	if err := ctx.ShouldBindWith(&request, binding.JSON); err != nil {
+		gonic.Gonic(umerrors.ErrRequestValidationFailed().AddDetailsErr(err), ctx)
+		return
+	}
+
+	errs := validation.ValidateUserLogin(request)
+	if errs != nil {
+		gonic.Gonic(umerrors.ErrRequestValidationFailed().AddDetailsErr(errs...), ctx)
+		return
+	}
+
+	if err := um.AdminRestoreUser(ctx.Request.Context(), request); err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrUnableRestoreUser(), ctx)
+		}
+		return
+	}
+
+	ctx.Status(http.StatusAccepted)
+}
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"net/http"
+
+	"git.containerum.net/ch/user-manager/pkg/models"
+	m "git.containerum.net/ch/user-manager/pkg/router/middleware"
+	"git.containerum.net/ch/user-manager/pkg/server"
+	"git.containerum.net/ch/user-manager/pkg/umerrors"
+	"git.containerum.net/ch/user-manager/pkg/validation"
+	"github.com/containerum/cherry"
+	"github.com/containerum/cherry/adaptors/gonic"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// swagger:operation GET /roles AdminRoles AdminRolesGetHandler
+// Get admin roles with their permissions.
+//
+// ---
+// x-method-visibility: private
+// parameters:
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserIDHeader'
+// responses:
+//  '200':
+//    description: admin roles list
+//    schema:
+//      $ref: '#/definitions/AdminRoles'
+//  default:
+//    $ref: '#/responses/error'
+func AdminRolesGetHandler(ctx *gin.Context) {
+	um := ctx.MustGet(m.UMServices).(server.UserManager)
+
+	resp, err := um.GetAdminRoles(ctx.Request.Context())
+	if err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrUnableGetAdminRoles(), ctx)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// swagger:operation PUT /roles/{role} AdminRoles AdminRoleSetHandler
+// Create admin role or replace its permissions.
+// Roles granted to the caller and permissions the caller doesn't have can be changed only by admin.
+//
+// ---
+// x-method-visibility: private
+// parameters:
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserIDHeader'
+//  - name: role
+//    in: path
+//    type: string
+//    required: true
+//  - name: body
+//    in: body
+//    schema:
+//      $ref: '#/definitions/AdminRoleRequest'
+// responses:
+//  '200':
+//    description: admin role saved
+//    schema:
+//      $ref: '#/definitions/AdminRole'
+//  default:
+//    $ref: '#/responses/error'
+func AdminRoleSetHandler(ctx *gin.Context) {
+	um := ctx.MustGet(m.UMServices).(server.UserManager)
+
+	var request models.AdminRoleRequest
+	if err := ctx.ShouldBindWith(&request, binding.JSON); err != nil {
+		gonic.Gonic(umerrors.ErrRequestValidationFailed().AddDetailsErr(err), ctx)
+		return
+	}
+
+	if errs := validation.ValidateAdminRoleRequest(ctx.Param("role"), request); errs != nil {
+		gonic.Gonic(umerrors.ErrRequestValidationFailed().AddDetailsErr(errs...), ctx)
+		return
+	}
+
+	resp, err := um.SetAdminRole(ctx.Request.Context(), ctx.Param("role"), request)
+	if err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrUnableSetAdminRole(), ctx)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// swagger:operation DELETE /roles/{role} AdminRoles AdminRoleDeleteHandler
+// Delete admin role. Role is revoked from all users.
+//
+// ---
+// x-method-visibility: private
+// parameters:
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserIDHeader'
+//  - name: role
+//    in: path
+//    type: string
+//    required: true
+// responses:
+//  '202':
+//    description: admin role deleted
+//  default:
+//    $ref: '#/responses/error'
+func AdminRoleDeleteHandler(ctx *gin.Context) {
+	um := ctx.MustGet(m.UMServices).(server.UserManager)
+
+	if err := um.DeleteAdminRole(ctx.Request.Context(), ctx.Param("role")); err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrUnableDeleteAdminRole(), ctx)
+		}
+		return
+	}
+
+	ctx.Status(http.StatusAccepted)
+}
+
+// swagger:operation PUT /roles/{role}/users/{user_id} AdminRoles AdminRoleGrantHandler
+// Grant admin role to user.
+//
+// ---
+// x-method-visibility: private
+// parameters:
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserIDHeader'
+//  - name: role
+//    in: path
+//    type: string
+//    required: true
+//  - name: user_id
+//    in: path
+//    type: string
+//    required: true
+// responses:
+//  '202':
+//    description: admin role granted
+//  default:
+//    $ref: '#/responses/error'
+func AdminRoleGrantHandler(ctx *gin.Context) {
+	um := ctx.MustGet(m.UMServices).(server.UserManager)
+
+	if err := um.GrantAdminRole(ctx.Request.Context(), ctx.Param("role"), ctx.Param("user_id")); err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrUnableGrantAdminRole(), ctx)
+		}
+		return
+	}
+
+	ctx.Status(http.StatusAccepted)
+}
+
+// swagger:operation DELETE /roles/{role}/users/{user_id} AdminRoles AdminRoleRevokeHandler
+// Revoke admin role from user.
+//
+// ---
+// x-method-visibility: private
+// parameters:
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserIDHeader'
+//  - name: role
+//    in: path
+//    type: string
+//    required: true
+//  - name: user_id
+//    in: path
+//    type: string
+//    required: true
+// responses:
+//  '202':
+//    description: admin role revoked
+//  default:
+//    $ref: '#/responses/error'
+func AdminRoleRevokeHandler(ctx *gin.Context) {
+	um := ctx.MustGet(m.UMServices).(server.UserManager)
+
+	if err := um.RevokeAdminRole(ctx.Request.Context(), ctx.Param("role"), ctx.Param("user_id")); err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrUnableGrantAdminRole(), ctx)
+		}
+		return
+	}
+
+	ctx.Status(http.StatusAccepted)
+}
@@ -7,6 +7,7 @@ import (
 	m "git.containerum.net/ch/user-manager/pkg/router/middleware"
 	"git.containerum.net/ch/user-manager/pkg/server"
 	"git.containerum.net/ch/user-manager/pkg/umerrors"
+	"git.containerum.net/ch/user-manager/pkg/utils"
 	"git.containerum.net/ch/user-manager/pkg/validation"
 	"github.com/containerum/cherry"
 	"github.com/containerum/cherry/adaptors/gonic"
@@ -22,6 +23,11 @@ import (
 // parameters:
 //  - $ref: '#/parameters/UserRoleHeader'
 //  - $ref: '#/parameters/UserIDHeader'
+//  - name: list
+//    in: query
+//    type: string
+//    enum: [block, allow]
+//    default: block
 // responses:
 //  '200':
 //    description: blacklisted domains
@@ -32,7 +38,7 @@ import (
 func BlacklistDomainsListGetHandler(ctx *gin.Context) {
 	um := ctx.MustGet(m.UMServices).(server.UserManager)
 
-	resp, err := um.GetBlacklistedDomainsList(ctx.Request.Context())
+	resp, err := um.GetBlacklistedDomainsList(ctx.Request.Context(), ctx.Query("list"))
 	if err != nil {
 		if cherr, ok := err.(*cherry.Err); ok {
 			gonic.Gonic(cherr, ctx)
@@ -47,7 +53,7 @@ func BlacklistDomainsListGetHandler(ctx *gin.Context) {
 }
 
 // swagger:operation GET /domain/{domain} DomainBlacklist BlacklistDomainGetHandler
-// Check if domain is in blacklist.
+// Check if domain is in blacklist. Returns rule with this domain or rule from the same list matching it.
 //
 // ---
 // x-method-visibility: public
@@ -58,6 +64,11 @@ func BlacklistDomainsListGetHandler(ctx *gin.Context) {
 //    in: path
 //    type: string
 //    required: true
+//  - name: list
+//    in: query
+//    type: string
+//    enum: [block, allow]
+//    default: block
 // responses:
 //  '200':
 //    description: blacklisted domain
@@ -68,7 +79,7 @@ func BlacklistDomainsListGetHandler(ctx *gin.Context) {
 func BlacklistDomainGetHandler(ctx *gin.Context) {
 	um := ctx.MustGet(m.UMServices).(server.UserManager)
 
-	resp, err := um.GetBlacklistedDomain(ctx.Request.Context(), ctx.Param("domain"))
+	resp, err := um.GetBlacklistedDomain(ctx.Request.Context(), ctx.Query("list"), ctx.Param("domain"))
 	if err != nil {
 		if cherr, ok := err.(*cherry.Err); ok {
 			gonic.Gonic(cherr, ctx)
@@ -128,6 +139,74 @@ func BlacklistDomainAddHandler(ctx *gin.Context) {
 	ctx.Status(http.StatusAccepted)
 }
 
+// swagger:operation POST /domain/import DomainBlacklist DomainsImportHandler
+// Import domain rules from list with one domain per line (public disposable domains lists format).
+//
+// ---
+// x-method-visibility: public
+// consumes:
+//  - text/plain
+// parameters:
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserIDHeader'
+//  - name: kind
+//    in: query
+//    type: string
+//    enum: [exact, suffix]
+//    default: suffix
+//  - name: list
+//    in: query
+//    type: string
+//    enum: [block, allow]
+//    default: block
+//  - name: reason
+//    in: query
+//    type: string
+//  - name: body
+//    in: body
+//    schema:
+//      type: string
+// responses:
+//  '200':
+//    description: domains imported
+//    schema:
+//      $ref: '#/definitions/DomainImportResponse'
+//  default:
+//    $ref: '#/responses/error'
+func DomainsImportHandler(ctx *gin.Context) {
+	um := ctx.MustGet(m.UMServices).(server.UserManager)
+
+	domains, err := utils.ParseDomainList(ctx.Request.Body)
+	if err != nil {
+		gonic.Gonic(umerrors.ErrRequestValidationFailed().AddDetailsErr(err), ctx)
+		return
+	}
+
+	request := models.DomainImportRequest{
+		Domains: domains,
+		Kind:    ctx.DefaultQuery("kind", models.DomainRuleSuffix),
+		List:    ctx.Query("list"),
+		Reason:  ctx.Query("reason"),
+	}
+	if errs := validation.ValidateDomainImport(request); errs != nil {
+		gonic.Gonic(umerrors.ErrRequestValidationFailed().AddDetailsErr(errs...), ctx)
+		return
+	}
+
+	resp, err := um.ImportDomains(ctx.Request.Context(), request)
+	if err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrUnableImportDomains(), ctx)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
 // swagger:operation DELETE /domain/{domain} DomainBlacklist BlacklistDomainDeleteHandler
 // Remove domain from blacklist.
 //
@@ -140,6 +219,11 @@ func BlacklistDomainAddHandler(ctx *gin.Context) {
 //    in: path
 //    type: string
 //    required: true
+//  - name: list
+//    in: query
+//    type: string
+//    enum: [block, allow]
+//    default: block
 // responses:
 //  '202':
 //    description: domain removed from blacklist
@@ -148,7 +232,7 @@ func BlacklistDomainAddHandler(ctx *gin.Context) {
 func BlacklistDomainDeleteHandler(ctx *gin.Context) {
 	um := ctx.MustGet(m.UMServices).(server.UserManager)
 
-	err := um.RemoveDomainFromBlacklist(ctx.Request.Context(), ctx.Param("domain"))
+	err := um.RemoveDomainFromBlacklist(ctx.Request.Context(), ctx.Query("list"), ctx.Param("domain"))
 	if err != nil {
 		if cherr, ok := err.(*cherry.Err); ok {
 			gonic.Gonic(cherr, ctx)
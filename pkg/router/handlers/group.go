@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"net/http"
+	"strconv"
 
 	kube_types "github.com/containerum/kube-client/pkg/model"
 
@@ -71,7 +72,7 @@ func GetGroupsListHandler(ctx *gin.Context) {
 func GetGroupHandler(ctx *gin.Context) {
 	um := ctx.MustGet(m.UMServices).(server.UserManager)
 
-	resp, err := um.GetGroupByLabel(ctx.Request.Context(), ctx.Param("group"))
+	group, err := um.GetGroupByLabel(ctx.Request.Context(), ctx.Param("group"))
 	if err != nil {
 		if cherr, ok := err.(*cherry.Err); ok {
 			gonic.Gonic(cherr, ctx)
@@ -82,7 +83,17 @@ func GetGroupHandler(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, resp)
+	if err := um.CheckGroupManager(ctx.Request.Context(), group.UserGroup); err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrNotGroupOwner(), ctx)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, group)
 }
 
 // swagger:operation POST /groups UserGroups CreateGroupHandler
@@ -160,8 +171,93 @@ func CreateGroupHandler(ctx *gin.Context) {
 	ctx.JSON(http.StatusCreated, resp)
 }
 
+// swagger:operation PUT /groups/{group}/members UserGroups SyncGroupMembersHandler
+// Replace group members list. Members missing in request are removed (except owner), new ones are added and changed ones are updated.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserIDHeader'
+//  - name: group
+//    in: path
+//    type: string
+//    required: true
+//  - name: dry_run
+//    in: query
+//    type: boolean
+//    required: false
+//  - name: body
+//    in: body
+//    schema:
+//      $ref: '#/definitions/GroupMembers'
+// responses:
+//  '200':
+//    description: group members synced
+//    schema:
+//      $ref: '#/definitions/GroupMembersSyncResult'
+//  default:
+//    $ref: '#/responses/error'
+func SyncGroupMembersHandler(ctx *gin.Context) {
+	um := ctx.MustGet(m.UMServices).(server.UserManager)
+
+	var request models.GroupMembers
+	if err := ctx.ShouldBindWith(&request, binding.JSON); err != nil {
+		gonic.Gonic(umerrors.ErrRequestValidationFailed().AddDetailsErr(err), ctx)
+		return
+	}
+
+	if errs := validation.ValidateSyncGroupMembers(request); errs != nil {
+		gonic.Gonic(umerrors.ErrRequestValidationFailed().AddDetailsErr(errs...), ctx)
+		return
+	}
+
+	dryRun := false
+	if dryRunStr, ok := ctx.GetQuery("dry_run"); ok {
+		var err error
+		if dryRun, err = strconv.ParseBool(dryRunStr); err != nil {
+			gonic.Gonic(umerrors.ErrRequestValidationFailed().AddDetailsErr(err), ctx)
+			return
+		}
+	}
+
+	group, err := um.GetGroupByLabel(ctx.Request.Context(), ctx.Param("group"))
+	if err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrUnableGetGroup(), ctx)
+		}
+		return
+	}
+
+	if err := um.CheckGroupManager(ctx.Request.Context(), group.UserGroup); err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrNotGroupOwner(), ctx)
+		}
+		return
+	}
+
+	resp, err := um.SyncGroupMembers(ctx.Request.Context(), group.UserGroup, request, dryRun)
+	if err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrUnableSyncGroupMembers(), ctx)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
 // swagger:operation POST /groups/{group}/members/{member} UserGroups UpdateGroupMemberHandler
-// Change group member access.
+// Change group member access. Access expiration is kept if expires_at is omitted and removed if remove_expiration is set.
 //
 // ---
 // x-method-visibility: public
@@ -179,7 +275,7 @@ func CreateGroupHandler(ctx *gin.Context) {
 //  - name: body
 //    in: body
 //    schema:
-//      $ref: '#/definitions/UserGroupMember'
+//      $ref: '#/definitions/GroupMember'
 // responses:
 //  '202':
 //    description: user access changed
@@ -188,13 +284,13 @@ func CreateGroupHandler(ctx *gin.Context) {
 func UpdateGroupMemberHandler(ctx *gin.Context) {
 	um := ctx.MustGet(m.UMServices).(server.UserManager)
 
-	var request kube_types.UserGroupMember
+	var request models.GroupMember
 	if err := ctx.ShouldBindWith(&request, binding.JSON); err != nil {
 		gonic.Gonic(umerrors.ErrRequestValidationFailed().AddDetailsErr(err), ctx)
 		return
 	}
 
-	if errs := validation.ValidateUpdateMember(request); errs != nil {
+	if errs := validation.ValidateUpdateGroupMember(request); errs != nil {
 		gonic.Gonic(umerrors.ErrRequestValidationFailed().AddDetailsErr(errs...), ctx)
 		return
 	}
@@ -210,8 +306,13 @@ func UpdateGroupMemberHandler(ctx *gin.Context) {
 		return
 	}
 
-	if group.OwnerID != httputil.MustGetUserID(ctx.Request.Context()) && httputil.MustGetUserRole(ctx.Request.Context()) != "admin" {
-		gonic.Gonic(umerrors.ErrNotGroupOwner(), ctx)
+	if err := um.CheckGroupManager(ctx.Request.Context(), group.UserGroup); err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrNotGroupOwner(), ctx)
+		}
 		return
 	}
 
@@ -231,7 +332,7 @@ func UpdateGroupMemberHandler(ctx *gin.Context) {
 		return
 	}
 
-	if err := um.UpdateGroupMemberAccess(ctx.Request.Context(), *group, ctx.Param("login"), string(request.Access)); err != nil {
+	if err := um.UpdateGroupMemberAccess(ctx.Request.Context(), group.UserGroup, ctx.Param("login"), string(request.Access), request.ExpiresAt, request.RemoveExpiration); err != nil {
 		if cherr, ok := err.(*cherry.Err); ok {
 			gonic.Gonic(cherr, ctx)
 		} else {
@@ -259,7 +360,7 @@ func UpdateGroupMemberHandler(ctx *gin.Context) {
 //  - name: body
 //    in: body
 //    schema:
-//      $ref: '#/definitions/UserGroupMembers'
+//      $ref: '#/definitions/GroupMembers'
 // responses:
 //  '202':
 //    description: user added
@@ -268,13 +369,13 @@ func UpdateGroupMemberHandler(ctx *gin.Context) {
 func AddGroupMembersHandler(ctx *gin.Context) {
 	um := ctx.MustGet(m.UMServices).(server.UserManager)
 
-	var request kube_types.UserGroupMembers
+	var request models.GroupMembers
 	if err := ctx.ShouldBindWith(&request, binding.JSON); err != nil {
 		gonic.Gonic(umerrors.ErrRequestValidationFailed().AddDetailsErr(err), ctx)
 		return
 	}
 
-	if errs := validation.ValidateAddMembers(request); errs != nil {
+	if errs := validation.ValidateGroupMembers(request); errs != nil {
 		gonic.Gonic(umerrors.ErrRequestValidationFailed().AddDetailsErr(errs...), ctx)
 		return
 	}
@@ -290,8 +391,13 @@ func AddGroupMembersHandler(ctx *gin.Context) {
 		return
 	}
 
-	if group.OwnerID != httputil.MustGetUserID(ctx.Request.Context()) {
-		gonic.Gonic(umerrors.ErrNotGroupOwner(), ctx)
+	if err := um.CheckGroupManager(ctx.Request.Context(), group.UserGroup); err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrNotGroupOwner(), ctx)
+		}
 		return
 	}
 
@@ -344,12 +450,17 @@ func DeleteGroupMemberHandler(ctx *gin.Context) {
 		return
 	}
 
-	if group.OwnerID != httputil.MustGetUserID(ctx.Request.Context()) {
-		gonic.Gonic(umerrors.ErrNotGroupOwner(), ctx)
+	if err := um.CheckGroupManager(ctx.Request.Context(), group.UserGroup); err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrNotGroupOwner(), ctx)
+		}
 		return
 	}
 
-	if err := um.DeleteGroupMember(ctx.Request.Context(), *group, ctx.Param("login")); err != nil {
+	if err := um.DeleteGroupMember(ctx.Request.Context(), group.UserGroup, ctx.Param("login")); err != nil {
 		if cherr, ok := err.(*cherry.Err); ok {
 			gonic.Gonic(cherr, ctx)
 		} else {
@@ -398,7 +509,7 @@ func DeleteGroupHandler(ctx *gin.Context) {
 		return
 	}
 
-	if err := um.DeleteGroup(ctx.Request.Context(), *group); err != nil {
+	if err := um.DeleteGroup(ctx.Request.Context(), group.UserGroup); err != nil {
 		if cherr, ok := err.(*cherry.Err); ok {
 			gonic.Gonic(cherr, ctx)
 		} else {
@@ -411,6 +522,142 @@ func DeleteGroupHandler(ctx *gin.Context) {
 	ctx.Status(http.StatusAccepted)
 }
 
+// swagger:operation PUT /groups/{group}/owner UserGroups TransferGroupOwnerHandler
+// Transfer group ownership to another user. Previous owner stays in group with admin access.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserIDHeader'
+//  - name: group
+//    in: path
+//    type: string
+//    required: true
+//  - name: body
+//    in: body
+//    schema:
+//      $ref: '#/definitions/GroupOwnerTransferRequest'
+// responses:
+//  '202':
+//    description: group ownership transferred
+//  default:
+//    $ref: '#/responses/error'
+func TransferGroupOwnerHandler(ctx *gin.Context) {
+	um := ctx.MustGet(m.UMServices).(server.UserManager)
+
+	var request models.GroupOwnerTransferRequest
+	if err := ctx.ShouldBindWith(&request, binding.JSON); err != nil {
+		gonic.Gonic(umerrors.ErrRequestValidationFailed().AddDetailsErr(err), ctx)
+		return
+	}
+
+	if errs := validation.ValidateGroupOwnerTransfer(request); errs != nil {
+		gonic.Gonic(umerrors.ErrRequestValidationFailed().AddDetailsErr(errs...), ctx)
+		return
+	}
+
+	group, err := um.GetGroupByLabel(ctx.Request.Context(), ctx.Param("group"))
+	if err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrUnableGetGroup(), ctx)
+		}
+		return
+	}
+
+	if group.OwnerID != httputil.MustGetUserID(ctx.Request.Context()) && httputil.MustGetUserRole(ctx.Request.Context()) != m.RoleAdmin {
+		gonic.Gonic(umerrors.ErrNotGroupOwner(), ctx)
+		return
+	}
+
+	if err := um.TransferGroupOwnership(ctx.Request.Context(), group.UserGroup, request.Login); err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrUnableTransferGroupOwnership(), ctx)
+		}
+		return
+	}
+
+	ctx.Status(http.StatusAccepted)
+}
+
+// swagger:operation PUT /groups/{group} UserGroups UpdateGroupHandler
+// Update group label, description or labels.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserIDHeader'
+//  - name: group
+//    in: path
+//    type: string
+//    required: true
+//  - name: body
+//    in: body
+//    schema:
+//      $ref: '#/definitions/GroupUpdateRequest'
+// responses:
+//  '202':
+//    description: group updated
+//    schema:
+//      $ref: '#/definitions/UserGroup'
+//  default:
+//    $ref: '#/responses/error'
+func UpdateGroupHandler(ctx *gin.Context) {
+	um := ctx.MustGet(m.UMServices).(server.UserManager)
+
+	var request models.GroupUpdateRequest
+	if err := ctx.ShouldBindWith(&request, binding.JSON); err != nil {
+		gonic.Gonic(umerrors.ErrRequestValidationFailed().AddDetailsErr(err), ctx)
+		return
+	}
+
+	if errs := validation.ValidateUpdateGroup(request); errs != nil {
+		gonic.Gonic(umerrors.ErrRequestValidationFailed().AddDetailsErr(errs...), ctx)
+		return
+	}
+
+	group, err := um.GetGroupByLabel(ctx.Request.Context(), ctx.Param("group"))
+	if err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrUnableGetGroup(), ctx)
+		}
+		return
+	}
+
+	if err := um.CheckGroupManager(ctx.Request.Context(), group.UserGroup); err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrNotGroupOwner(), ctx)
+		}
+		return
+	}
+
+	resp, err := um.UpdateGroup(ctx.Request.Context(), group.UserGroup, request)
+	if err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrUpdateGroup(), ctx)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, resp)
+}
+
 func GroupListLabelID(ctx *gin.Context) {
 	switch ctx.Param("group") {
 	case "labelid":
@@ -0,0 +1,293 @@
+package handlers
+
+import (
+	"net/http"
+
+	"git.containerum.net/ch/user-manager/pkg/models"
+	m "git.containerum.net/ch/user-manager/pkg/router/middleware"
+	"git.containerum.net/ch/user-manager/pkg/server"
+	"git.containerum.net/ch/user-manager/pkg/umerrors"
+	"git.containerum.net/ch/user-manager/pkg/validation"
+	"github.com/containerum/cherry"
+	"github.com/containerum/cherry/adaptors/gonic"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// swagger:operation POST /groups/{group}/invites UserGroups GroupInviteCreateHandler
+// Invite not registered user to group by email. Invite is applied when user activates account.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserIDHeader'
+//  - name: group
+//    in: path
+//    type: string
+//    required: true
+//  - name: body
+//    in: body
+//    schema:
+//      $ref: '#/definitions/GroupInviteRequest'
+// responses:
+//  '201':
+//    description: invite created and sent
+//    schema:
+//      $ref: '#/definitions/GroupInvite'
+//  default:
+//    $ref: '#/responses/error'
+func GroupInviteCreateHandler(ctx *gin.Context) {
+	um := ctx.MustGet(m.UMServices).(server.UserManager)
+
+	var request models.GroupInviteRequest
+	if err := ctx.ShouldBindWith(&request, binding.JSON); err != nil {
+		gonic.Gonic(umerrors.ErrRequestValidationFailed().AddDetailsErr(err), ctx)
+		return
+	}
+
+	if errs := validation.ValidateGroupInvite(request); errs != nil {
+		gonic.Gonic(umerrors.ErrRequestValidationFailed().AddDetailsErr(errs...), ctx)
+		return
+	}
+
+	group, err := um.GetGroupByLabel(ctx.Request.Context(), ctx.Param("group"))
+	if err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrUnableGetGroup(), ctx)
+		}
+		return
+	}
+
+	if err := um.CheckGroupManager(ctx.Request.Context(), group.UserGroup); err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrNotGroupOwner(), ctx)
+		}
+		return
+	}
+
+	resp, err := um.CreateGroupInvite(ctx.Request.Context(), group.UserGroup, request)
+	if err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrUnableCreateGroupInvite(), ctx)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, resp)
+}
+
+// swagger:operation GET /groups/{group}/invites UserGroups GroupInvitesGetHandler
+// Get group invites list.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserIDHeader'
+//  - name: group
+//    in: path
+//    type: string
+//    required: true
+// responses:
+//  '200':
+//    description: group invites list
+//    schema:
+//      $ref: '#/definitions/GroupInvites'
+//  default:
+//    $ref: '#/responses/error'
+func GroupInvitesGetHandler(ctx *gin.Context) {
+	um := ctx.MustGet(m.UMServices).(server.UserManager)
+
+	group, err := um.GetGroupByLabel(ctx.Request.Context(), ctx.Param("group"))
+	if err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrUnableGetGroup(), ctx)
+		}
+		return
+	}
+
+	if err := um.CheckGroupManager(ctx.Request.Context(), group.UserGroup); err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrNotGroupOwner(), ctx)
+		}
+		return
+	}
+
+	resp, err := um.GetGroupInvites(ctx.Request.Context(), group.UserGroup)
+	if err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrUnableGetGroupInvites(), ctx)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// swagger:operation DELETE /groups/{group}/invites/{invite_id} UserGroups GroupInviteDeleteHandler
+// Revoke group invite.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserIDHeader'
+//  - name: group
+//    in: path
+//    type: string
+//    required: true
+//  - name: invite_id
+//    in: path
+//    type: string
+//    required: true
+// responses:
+//  '202':
+//    description: invite revoked
+//  default:
+//    $ref: '#/responses/error'
+func GroupInviteDeleteHandler(ctx *gin.Context) {
+	um := ctx.MustGet(m.UMServices).(server.UserManager)
+
+	group, err := um.GetGroupByLabel(ctx.Request.Context(), ctx.Param("group"))
+	if err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrUnableGetGroup(), ctx)
+		}
+		return
+	}
+
+	if err := um.CheckGroupManager(ctx.Request.Context(), group.UserGroup); err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrNotGroupOwner(), ctx)
+		}
+		return
+	}
+
+	if !validation.IsValidUUID(ctx.Param("invite_id")) {
+		gonic.Gonic(umerrors.ErrGroupInviteNotExist(), ctx)
+		return
+	}
+
+	if err := um.DeleteGroupInvite(ctx.Request.Context(), group.UserGroup, ctx.Param("invite_id")); err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrUnableDeleteGroupInvite(), ctx)
+		}
+		return
+	}
+
+	ctx.Status(http.StatusAccepted)
+}
+
+// swagger:operation POST /invites/accept UserGroups GroupInviteAcceptHandler
+// Accept group invite. Invite can be accepted only by user with invited email.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserIDHeader'
+//  - name: body
+//    in: body
+//    schema:
+//      $ref: '#/definitions/GroupInviteToken'
+// responses:
+//  '202':
+//    description: invite accepted
+//  default:
+//    $ref: '#/responses/error'
+func GroupInviteAcceptHandler(ctx *gin.Context) {
+	um := ctx.MustGet(m.UMServices).(server.UserManager)
+
+	var request models.GroupInviteToken
+	if err := ctx.ShouldBindWith(&request, binding.JSON); err != nil {
+		gonic.Gonic(umerrors.ErrRequestValidationFailed().AddDetailsErr(err), ctx)
+		return
+	}
+
+	if errs := validation.ValidateGroupInviteToken(request); errs != nil {
+		gonic.Gonic(umerrors.ErrRequestValidationFailed().AddDetailsErr(errs...), ctx)
+		return
+	}
+
+	if err := um.AcceptGroupInvite(ctx.Request.Context(), request); err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrUnableAcceptGroupInvite(), ctx)
+		}
+		return
+	}
+
+	ctx.Status(http.StatusAccepted)
+}
+
+// swagger:operation POST /invites/decline UserGroups GroupInviteDeclineHandler
+// Decline group invite.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - name: body
+//    in: body
+//    schema:
+//      $ref: '#/definitions/GroupInviteToken'
+// responses:
+//  '202':
+//    description: invite declined
+//  default:
+//    $ref: '#/responses/error'
+func GroupInviteDeclineHandler(ctx *gin.Context) {
+	um := ctx.MustGet(m.UMServices).(server.UserManager)
+
+	var request models.GroupInviteToken
+	if err := ctx.ShouldBindWith(&request, binding.JSON); err != nil {
+		gonic.Gonic(umerrors.ErrRequestValidationFailed().AddDetailsErr(err), ctx)
+		return
+	}
+
+	if errs := validation.ValidateGroupInviteToken(request); errs != nil {
+		gonic.Gonic(umerrors.ErrRequestValidationFailed().AddDetailsErr(errs...), ctx)
+		return
+	}
+
+	if err := um.DeclineGroupInvite(ctx.Request.Context(), request); err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrUnableDeleteGroupInvite(), ctx)
+		}
+		return
+	}
+
+	ctx.Status(http.StatusAccepted)
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"git.containerum.net/ch/user-manager/pkg/models"
+	m "git.containerum.net/ch/user-manager/pkg/router/middleware"
+	"git.containerum.net/ch/user-manager/pkg/server"
+	"git.containerum.net/ch/user-manager/pkg/umerrors"
+	"git.containerum.net/ch/user-manager/pkg/validation"
+	"github.com/containerum/cherry"
+	"github.com/containerum/cherry/adaptors/gonic"
+	"github.com/gin-gonic/gin"
+)
+
+// getPagination parses "page" and "per_page" query parameters.
+func getPagination(ctx *gin.Context) (page, perPage uint, err error) {
+	page, perPage = 1, 10
+	if pageStr, ok := ctx.GetQuery("page"); ok {
+		parsed, parseErr := strconv.ParseUint(pageStr, 10, 32)
+		if parseErr != nil || parsed == 0 {
+			return 0, 0, errors.New("page must be positive integer")
+		}
+		page = uint(parsed)
+	}
+	if perPageStr, ok := ctx.GetQuery("per_page"); ok {
+		parsed, parseErr := strconv.ParseUint(perPageStr, 10, 32)
+		if parseErr != nil || parsed == 0 || parsed > 100 {
+			return 0, 0, errors.New("per_page must be integer between 1 and 100")
+		}
+		perPage = uint(parsed)
+	}
+	return page, perPage, nil
+}
+
+// swagger:operation GET /groups/{group}/members UserGroups GetGroupMembersHandler
+// Get group members page with user details.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserIDHeader'
+//  - name: group
+//    in: path
+//    type: string
+//    required: true
+//  - name: page
+//    in: query
+//    type: integer
+//    required: false
+//  - name: per_page
+//    in: query
+//    type: integer
+//    required: false
+//  - name: access
+//    in: query
+//    type: string
+//    required: false
+//  - name: login
+//    in: query
+//    type: string
+//    required: false
+// responses:
+//  '200':
+//    description: group members page
+//    schema:
+//      $ref: '#/definitions/GroupMembersPage'
+//  default:
+//    $ref: '#/responses/error'
+func GetGroupMembersHandler(ctx *gin.Context) {
+	um := ctx.MustGet(m.UMServices).(server.UserManager)
+
+	page, perPage, err := getPagination(ctx)
+	if err != nil {
+		gonic.Gonic(umerrors.ErrRequestValidationFailed().AddDetailsErr(err), ctx)
+		return
+	}
+
+	filter := models.GroupMembersFilter{
+		Access: ctx.Query("access"),
+		Login:  ctx.Query("login"),
+	}
+	if errs := validation.ValidateGroupMembersFilter(filter.Access); errs != nil {
+		gonic.Gonic(umerrors.ErrRequestValidationFailed().AddDetailsErr(errs...), ctx)
+		return
+	}
+
+	resp, err := um.GetGroupMembersPage(ctx.Request.Context(), ctx.Param("group"), page, perPage, filter)
+	if err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrUnableGetGroupMembers(), ctx)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// swagger:operation GET /user/{user_id}/groups UserGroups GetUserGroupMembershipsHandler
+// Get groups user is direct member of.
+//
+// ---
+// x-method-visibility: private
+// parameters:
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserIDHeader'
+//  - name: user_id
+//    in: path
+//    type: string
+//    required: true
+//  - name: page
+//    in: query
+//    type: integer
+//    required: false
+//  - name: per_page
+//    in: query
+//    type: integer
+//    required: false
+// responses:
+//  '200':
+//    description: user groups page
+//    schema:
+//      $ref: '#/definitions/UserGroupMemberships'
+//  default:
+//    $ref: '#/responses/error'
+func GetUserGroupMembershipsHandler(ctx *gin.Context) {
+	um := ctx.MustGet(m.UMServices).(server.UserManager)
+
+	page, perPage, err := getPagination(ctx)
+	if err != nil {
+		gonic.Gonic(umerrors.ErrRequestValidationFailed().AddDetailsErr(err), ctx)
+		return
+	}
+
+	resp, err := um.GetUserGroupMemberships(ctx.Request.Context(), ctx.Param("user_id"), page, perPage)
+	if err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrUnableGetUserGroups(), ctx)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
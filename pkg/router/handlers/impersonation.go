@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"net/http"
+
+	"git.containerum.net/ch/user-manager/pkg/models"
+	m "git.containerum.net/ch/user-manager/pkg/router/middleware"
+	"git.containerum.net/ch/user-manager/pkg/server"
+	"git.containerum.net/ch/user-manager/pkg/umerrors"
+	"github.com/containerum/cherry"
+	"github.com/containerum/cherry/adaptors/gonic"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// swagger:operation POST /admin/user/impersonate Admin AdminImpersonateHandler
+// Get short-living tokens of user. Impersonation is recorded and user is notified by email.
+// Password change, account deletion and bound accounts changes are not allowed with these tokens.
+//
+// ---
+// x-method-visibility: private
+// parameters:
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserIDHeader'
+//  - $ref: '#/parameters/UserAgentHeader'
+//  - $ref: '#/parameters/FingerprintHeader'
+//  - $ref: '#/parameters/ClientIPHeader'
+//  - name: body
+//    in: body
+//    schema:
+//      $ref: '#/definitions/ImpersonationRequest'
+// responses:
+//  '200':
+//    description: user tokens
+//    schema:
+//      $ref: '#/definitions/CreateTokenResponse'
+//  default:
+//    $ref: '#/responses/error'
+func AdminImpersonateHandler(ctx *gin.Context) {
+	um := ctx.MustGet(m.UMServices).(server.UserManager)
+
+	var request models.ImpersonationRequest
+	if err := ctx.ShouldBindWith(&request, binding.JSON); err != nil {
+		gonic.Gonic(umerrors.ErrRequestValidationFailed().AddDetailsErr(err), ctx)
+		return
+	}
+
+	tokens, err := um.Impersonate(ctx.Request.Context(), request)
+	if err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrUnableImpersonate(), ctx)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, tokens)
+}
+
+// swagger:operation GET /admin/user/impersonations/{user_id} Admin AdminImpersonationsGetHandler
+// Get impersonations of user, newest first.
+//
+// ---
+// x-method-visibility: private
+// parameters:
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserIDHeader'
+//  - name: user_id
+//    in: path
+//    type: string
+//    required: true
+//  - name: page
+//    in: query
+//    type: integer
+//    required: false
+//  - name: per_page
+//    in: query
+//    type: integer
+//    required: false
+// responses:
+//  '200':
+//    description: user impersonations
+//    schema:
+//      $ref: '#/definitions/Impersonations'
+//  default:
+//    $ref: '#/responses/error'
+func AdminImpersonationsGetHandler(ctx *gin.Context) {
+	um := ctx.MustGet(m.UMServices).(server.UserManager)
+
+	page, perPage, err := getPagination(ctx)
+	if err != nil {
+		gonic.Gonic(umerrors.ErrRequestValidationFailed().AddDetailsErr(err), ctx)
+		return
+	}
+
+	resp, err := um.GetUserImpersonations(ctx.Request.Context(), ctx.Param("user_id"), page, perPage)
+	if err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrUnableGetImpersonations(), ctx)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
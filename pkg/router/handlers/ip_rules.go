@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"git.containerum.net/ch/user-manager/pkg/models"
+	m "git.containerum.net/ch/user-manager/pkg/router/middleware"
+	"git.containerum.net/ch/user-manager/pkg/server"
+	"git.containerum.net/ch/user-manager/pkg/umerrors"
+	"git.containerum.net/ch/user-manager/pkg/validation"
+	"github.com/containerum/cherry"
+	"github.com/containerum/cherry/adaptors/gonic"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// swagger:operation GET /ip IPRules IPRulesGetHandler
+// Get IP rules list.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserIDHeader'
+//  - name: list
+//    in: query
+//    type: string
+//    enum: [block, allow]
+//    default: block
+// responses:
+//  '200':
+//    description: IP rules
+//    schema:
+//      $ref: '#/definitions/IPRules'
+//  default:
+//    $ref: '#/responses/error'
+func IPRulesGetHandler(ctx *gin.Context) {
+	um := ctx.MustGet(m.UMServices).(server.UserManager)
+
+	resp, err := um.GetIPRules(ctx.Request.Context(), ctx.Query("list"))
+	if err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrUnableGetIPRules(), ctx)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// swagger:operation GET /ip/{cidr} IPRules IPRuleGetHandler
+// Get IP rule. For single address returns rule for this address or for network containing it.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserIDHeader'
+//  - name: cidr
+//    in: path
+//    type: string
+//    required: true
+// responses:
+//  '200':
+//    description: IP rule
+//    schema:
+//      $ref: '#/definitions/IPRule'
+//  default:
+//    $ref: '#/responses/error'
+func IPRuleGetHandler(ctx *gin.Context) {
+	um := ctx.MustGet(m.UMServices).(server.UserManager)
+
+	resp, err := um.GetIPRule(ctx.Request.Context(), strings.TrimPrefix(ctx.Param("cidr"), "/"))
+	if err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrUnableGetIPRules(), ctx)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// swagger:operation POST /ip IPRules IPRuleAddHandler
+// Add or update IP rule.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserIDHeader'
+//  - name: body
+//    in: body
+//    schema:
+//      $ref: '#/definitions/IPRule'
+// responses:
+//  '201':
+//    description: IP rule added
+//    schema:
+//      $ref: '#/definitions/IPRule'
+//  default:
+//    $ref: '#/responses/error'
+func IPRuleAddHandler(ctx *gin.Context) {
+	um := ctx.MustGet(m.UMServices).(server.UserManager)
+
+	var request models.IPRule
+	if err := ctx.ShouldBindWith(&request, binding.JSON); err != nil {
+		gonic.Gonic(umerrors.ErrRequestValidationFailed().AddDetailsErr(err), ctx)
+		return
+	}
+
+	if errs := validation.ValidateIPRule(request); errs != nil {
+		gonic.Gonic(umerrors.ErrRequestValidationFailed().AddDetailsErr(errs...), ctx)
+		return
+	}
+
+	resp, err := um.AddIPRule(ctx.Request.Context(), request)
+	if err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrUnableAddIPRule(), ctx)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, resp)
+}
+
+// swagger:operation DELETE /ip/{cidr} IPRules IPRuleDeleteHandler
+// Delete IP rule.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserIDHeader'
+//  - name: cidr
+//    in: path
+//    type: string
+//    required: true
+// responses:
+//  '202':
+//    description: IP rule deleted
+//  default:
+//    $ref: '#/responses/error'
+func IPRuleDeleteHandler(ctx *gin.Context) {
+	um := ctx.MustGet(m.UMServices).(server.UserManager)
+
+	err := um.DeleteIPRule(ctx.Request.Context(), strings.TrimPrefix(ctx.Param("cidr"), "/"))
+	if err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrUnableDeleteIPRule(), ctx)
+		}
+		return
+	}
+
+	ctx.Status(http.StatusAccepted)
+}
@@ -94,3 +94,32 @@ func LinksGetHandler(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, resp)
 }
+
+// swagger:operation GET /links/policies Links LinkPoliciesGetHandler
+// Get lifetime and resend restrictions for each link type.
+//
+// ---
+// x-method-visibility: public
+// responses:
+//  '200':
+//    description: link policies
+//    schema:
+//      $ref: '#/definitions/LinkPolicies'
+//  default:
+//    $ref: '#/responses/error'
+func LinkPoliciesGetHandler(ctx *gin.Context) {
+	um := ctx.MustGet(m.UMServices).(server.UserManager)
+
+	resp, err := um.GetLinkPolicies(ctx.Request.Context())
+	if err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrInternalError(), ctx)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
@@ -182,3 +182,51 @@ func LogoutHandler(ctx *gin.Context) {
 
 	ctx.Status(http.StatusOK)
 }
+
+// swagger:operation POST /login/apikey Login APIKeyLoginHandler
+// Login with service account API key.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserAgentHeader'
+//  - $ref: '#/parameters/FingerprintHeader'
+//  - $ref: '#/parameters/ClientIPHeader'
+//  - name: body
+//    in: body
+//    schema:
+//      $ref: '#/definitions/APIKeyLoginRequest'
+// responses:
+//  '200':
+//    description: service account logged in
+//    schema:
+//      $ref: '#/definitions/CreateTokenResponse'
+//  default:
+//    $ref: '#/responses/error'
+func APIKeyLoginHandler(ctx *gin.Context) {
+	um := ctx.MustGet(m.UMServices).(server.UserManager)
+
+	var request models.APIKeyLoginRequest
+	if err := ctx.ShouldBindWith(&request, binding.JSON); err != nil {
+		gonic.Gonic(umerrors.ErrRequestValidationFailed().AddDetailsErr(err), ctx)
+		return
+	}
+
+	if errs := validation.ValidateAPIKeyLoginRequest(request); errs != nil {
+		gonic.Gonic(umerrors.ErrRequestValidationFailed().AddDetailsErr(errs...), ctx)
+		return
+	}
+
+	tokens, err := um.APIKeyLogin(ctx.Request.Context(), request)
+	if err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrLoginFailed(), ctx)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, tokens)
+}
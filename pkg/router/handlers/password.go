@@ -62,6 +62,54 @@ func PasswordChangeHandler(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, tokens)
 }
 
+// swagger:operation PUT /password/change/expired Password ExpiredPasswordChangeHandler
+// Change password of user which must change password before login.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserAgentHeader'
+//  - $ref: '#/parameters/FingerprintHeader'
+//  - $ref: '#/parameters/ClientIPHeader'
+//  - name: body
+//    in: body
+//    schema:
+//      $ref: '#/definitions/ExpiredPasswordChangeRequest'
+// responses:
+//  '200':
+//    description: password changed
+//    schema:
+//      $ref: '#/definitions/CreateTokenResponse'
+//  default:
+//    $ref: '#/responses/error'
+func ExpiredPasswordChangeHandler(ctx *gin.Context) {
+	um := ctx.MustGet(m.UMServices).(server.UserManager)
+
+	var request models.ExpiredPasswordChangeRequest
+	if err := ctx.ShouldBindWith(&request, binding.JSON); err != nil {
+		gonic.Gonic(umerrors.ErrRequestValidationFailed().AddDetailsErr(err), ctx)
+		return
+	}
+
+	if errs := validation.ValidateExpiredPasswordChangeRequest(request); errs != nil {
+		gonic.Gonic(umerrors.ErrRequestValidationFailed().AddDetailsErr(errs...), ctx)
+		return
+	}
+
+	tokens, err := um.ChangeExpiredPassword(ctx.Request.Context(), request)
+	if err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrUnableChangePassword(), ctx)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, tokens)
+}
+
 // swagger:operation POST /password/reset Password PasswordResetHandler
 // Reset password.
 //
@@ -71,7 +119,7 @@ func PasswordChangeHandler(ctx *gin.Context) {
 //  - name: body
 //    in: body
 //    schema:
-//      $ref: '#/definitions/UserLogin'
+//      $ref: '#/definitions/PasswordResetRequest'
 // responses:
 //  '202':
 //    description: password reset link sent
@@ -80,13 +128,13 @@ func PasswordChangeHandler(ctx *gin.Context) {
 func PasswordResetHandler(ctx *gin.Context) {
 	um := ctx.MustGet(m.UMServices).(server.UserManager)
 
-	var request models.UserLogin
+	var request models.PasswordResetRequest
 	if err := ctx.ShouldBindWith(&request, binding.JSON); err != nil {
 		gonic.Gonic(umerrors.ErrRequestValidationFailed().AddDetailsErr(err), ctx)
 		return
 	}
 
-	errs := validation.ValidateUserLogin(request)
+	errs := validation.ValidateUserLogin(models.UserLogin{Login: request.Login})
 	if errs != nil {
 		gonic.Gonic(umerrors.ErrRequestValidationFailed().AddDetailsErr(errs...), ctx)
 		return
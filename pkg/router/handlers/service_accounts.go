@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"net/http"
+
+	"git.containerum.net/ch/user-manager/pkg/models"
+	m "git.containerum.net/ch/user-manager/pkg/router/middleware"
+	"git.containerum.net/ch/user-manager/pkg/server"
+	"git.containerum.net/ch/user-manager/pkg/umerrors"
+	"git.containerum.net/ch/user-manager/pkg/validation"
+	"github.com/containerum/cherry"
+	"github.com/containerum/cherry/adaptors/gonic"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// swagger:operation GET /service_accounts ServiceAccounts ServiceAccountsGetHandler
+// Get service accounts owned by current user.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserIDHeader'
+// responses:
+//  '200':
+//    description: service accounts
+//    schema:
+//      $ref: '#/definitions/ServiceAccounts'
+//  default:
+//    $ref: '#/responses/error'
+func ServiceAccountsGetHandler(ctx *gin.Context) {
+	um := ctx.MustGet(m.UMServices).(server.UserManager)
+
+	resp, err := um.GetServiceAccounts(ctx.Request.Context())
+	if err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrUnableGetServiceAccounts(), ctx)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// swagger:operation POST /service_accounts ServiceAccounts ServiceAccountCreateHandler
+// Create service account owned by current user.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserIDHeader'
+//  - name: body
+//    in: body
+//    schema:
+//      $ref: '#/definitions/ServiceAccountCreateRequest'
+// responses:
+//  '201':
+//    description: service account created
+//    schema:
+//      $ref: '#/definitions/ServiceAccount'
+//  default:
+//    $ref: '#/responses/error'
+func ServiceAccountCreateHandler(ctx *gin.Context) {
+	um := ctx.MustGet(m.UMServices).(server.UserManager)
+
+	var request models.ServiceAccountCreateRequest
+	if err := ctx.ShouldBindWith(&request, binding.JSON); err != nil {
+		gonic.Gonic(umerrors.ErrRequestValidationFailed().AddDetailsErr(err), ctx)
+		return
+	}
+
+	if errs := validation.ValidateServiceAccountCreateRequest(request); errs != nil {
+		gonic.Gonic(umerrors.ErrRequestValidationFailed().AddDetailsErr(errs...), ctx)
+		return
+	}
+
+	resp, err := um.CreateServiceAccount(ctx.Request.Context(), request)
+	if err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrUnableCreateServiceAccount(), ctx)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, resp)
+}
+
+// swagger:operation GET /service_accounts/{account_id}/keys ServiceAccounts APIKeysGetHandler
+// Get service account API keys. Key values are not returned.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserIDHeader'
+//  - name: account_id
+//    in: path
+//    type: string
+//    required: true
+// responses:
+//  '200':
+//    description: API keys
+//    schema:
+//      $ref: '#/definitions/APIKeys'
+//  default:
+//    $ref: '#/responses/error'
+func APIKeysGetHandler(ctx *gin.Context) {
+	um := ctx.MustGet(m.UMServices).(server.UserManager)
+
+	resp, err := um.GetAPIKeys(ctx.Request.Context(), ctx.Param("account_id"))
+	if err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrUnableGetAPIKeys(), ctx)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// swagger:operation POST /service_accounts/{account_id}/keys ServiceAccounts APIKeyCreateHandler
+// Create service account API key. Key value is returned only in this response.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserIDHeader'
+//  - name: account_id
+//    in: path
+//    type: string
+//    required: true
+//  - name: body
+//    in: body
+//    schema:
+//      $ref: '#/definitions/APIKeyCreateRequest'
+// responses:
+//  '201':
+//    description: API key created
+//    schema:
+//      $ref: '#/definitions/APIKey'
+//  default:
+//    $ref: '#/responses/error'
+func APIKeyCreateHandler(ctx *gin.Context) {
+	um := ctx.MustGet(m.UMServices).(server.UserManager)
+
+	var request models.APIKeyCreateRequest
+	if err := ctx.ShouldBindWith(&request, binding.JSON); err != nil {
+		gonic.Gonic(umerrors.ErrRequestValidationFailed().AddDetailsErr(err), ctx)
+		return
+	}
+
+	if errs := validation.ValidateAPIKeyCreateRequest(request); errs != nil {
+		gonic.Gonic(umerrors.ErrRequestValidationFailed().AddDetailsErr(errs...), ctx)
+		return
+	}
+
+	resp, err := um.CreateAPIKey(ctx.Request.Context(), ctx.Param("account_id"), request)
+	if err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrUnableCreateAPIKey(), ctx)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, resp)
+}
+
+// swagger:operation DELETE /service_accounts/{account_id}/keys/{key_id} ServiceAccounts APIKeyRevokeHandler
+// Revoke service account API key.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserIDHeader'
+//  - name: account_id
+//    in: path
+//    type: string
+//    required: true
+//  - name: key_id
+//    in: path
+//    type: string
+//    required: true
+// responses:
+//  '202':
+//    description: API key revoked
+//  default:
+//    $ref: '#/responses/error'
+func APIKeyRevokeHandler(ctx *gin.Context) {
+	um := ctx.MustGet(m.UMServices).(server.UserManager)
+
+	err := um.RevokeAPIKey(ctx.Request.Context(), ctx.Param("account_id"), ctx.Param("key_id"))
+	if err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrUnableRevokeAPIKey(), ctx)
+		}
+		return
+	}
+
+	ctx.Status(http.StatusAccepted)
+}
@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"net/http"
+
+	"git.containerum.net/ch/user-manager/pkg/models"
+	m "git.containerum.net/ch/user-manager/pkg/router/middleware"
+	"git.containerum.net/ch/user-manager/pkg/server"
+	"git.containerum.net/ch/user-manager/pkg/umerrors"
+	"git.containerum.net/ch/user-manager/pkg/validation"
+	"github.com/containerum/cherry"
+	"github.com/containerum/cherry/adaptors/gonic"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// swagger:operation POST /groups/{group}/subgroups UserGroups AddSubgroupHandler
+// Add group as member of other group. Subgroup members get specified access in parent group.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserIDHeader'
+//  - name: group
+//    in: path
+//    type: string
+//    required: true
+//  - name: body
+//    in: body
+//    schema:
+//      $ref: '#/definitions/SubgroupAddRequest'
+// responses:
+//  '202':
+//    description: subgroup added
+//  default:
+//    $ref: '#/responses/error'
+func AddSubgroupHandler(ctx *gin.Context) {
+	um := ctx.MustGet(m.UMServices).(server.UserManager)
+
+	var request models.SubgroupAddRequest
+	if err := ctx.ShouldBindWith(&request, binding.JSON); err != nil {
+		gonic.Gonic(umerrors.ErrRequestValidationFailed().AddDetailsErr(err), ctx)
+		return
+	}
+
+	if errs := validation.ValidateAddSubgroup(request); errs != nil {
+		gonic.Gonic(umerrors.ErrRequestValidationFailed().AddDetailsErr(errs...), ctx)
+		return
+	}
+
+	group, err := um.GetGroupByLabel(ctx.Request.Context(), ctx.Param("group"))
+	if err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrUnableGetGroup(), ctx)
+		}
+		return
+	}
+
+	if err := um.CheckGroupManager(ctx.Request.Context(), group.UserGroup); err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrNotGroupOwner(), ctx)
+		}
+		return
+	}
+
+	if err := um.AddSubgroup(ctx.Request.Context(), group.UserGroup, request); err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrUnableAddSubgroup(), ctx)
+		}
+		return
+	}
+
+	ctx.Status(http.StatusAccepted)
+}
+
+// swagger:operation GET /groups/{group}/subgroups UserGroups GetSubgroupsHandler
+// Get groups which are members of group.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserIDHeader'
+//  - name: group
+//    in: path
+//    type: string
+//    required: true
+// responses:
+//  '200':
+//    description: subgroups list
+//    schema:
+//      $ref: '#/definitions/Subgroups'
+//  default:
+//    $ref: '#/responses/error'
+func GetSubgroupsHandler(ctx *gin.Context) {
+	um := ctx.MustGet(m.UMServices).(server.UserManager)
+
+	group, err := um.GetGroupByLabel(ctx.Request.Context(), ctx.Param("group"))
+	if err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrUnableGetGroup(), ctx)
+		}
+		return
+	}
+
+	if err := um.CheckGroupManager(ctx.Request.Context(), group.UserGroup); err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrNotGroupOwner(), ctx)
+		}
+		return
+	}
+
+	resp, err := um.GetSubgroups(ctx.Request.Context(), group.UserGroup)
+	if err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrUnableGetSubgroups(), ctx)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// swagger:operation DELETE /groups/{group}/subgroups/{subgroup} UserGroups DeleteSubgroupHandler
+// Remove group from parent group.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserIDHeader'
+//  - name: group
+//    in: path
+//    type: string
+//    required: true
+//  - name: subgroup
+//    in: path
+//    type: string
+//    required: true
+// responses:
+//  '202':
+//    description: subgroup removed
+//  default:
+//    $ref: '#/responses/error'
+func DeleteSubgroupHandler(ctx *gin.Context) {
+	um := ctx.MustGet(m.UMServices).(server.UserManager)
+
+	group, err := um.GetGroupByLabel(ctx.Request.Context(), ctx.Param("group"))
+	if err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrUnableGetGroup(), ctx)
+		}
+		return
+	}
+
+	if err := um.CheckGroupManager(ctx.Request.Context(), group.UserGroup); err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrNotGroupOwner(), ctx)
+		}
+		return
+	}
+
+	if err := um.DeleteSubgroup(ctx.Request.Context(), group.UserGroup, ctx.Param("subgroup")); err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			ctx.Error(err)
+			gonic.Gonic(umerrors.ErrUnableDeleteSubgroup(), ctx)
+		}
+		return
+	}
+
+	ctx.Status(http.StatusAccepted)
+}
@@ -0,0 +1,245 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"git.containerum.net/ch/user-manager/pkg/clients"
+	"git.containerum.net/ch/user-manager/pkg/umerrors"
+	"github.com/containerum/cherry/adaptors/gonic"
+	headers "github.com/containerum/utils/httputil"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// Headers of signed internal request.
+const (
+	InternalTimestampXHeader = "X-Internal-Timestamp"
+	InternalNonceXHeader     = "X-Internal-Nonce"
+	InternalSignatureXHeader = "X-Internal-Signature"
+)
+
+// maxSignedBodySize limits body read for signature verification
+const maxSignedBodySize = 1 << 20
+
+// identityHeaders are trusted only if request is authenticated. They are covered by request signature.
+var identityHeaders = []string{
+	headers.UserIDXHeader,
+	headers.UserRoleXHeader,
+	headers.TokenIDXHeader,
+	clients.ImpersonatorXHeader,
+}
+
+// InternalAuthenticated is a context key set for requests authenticated as internal
+const InternalAuthenticated = "internal-authenticated"
+
+// InternalAuth verifies that requests to internal routes are made by trusted services.
+// Service proves it either by HMAC request signature with shared key or by client certificate verified by TLS server.
+type InternalAuth struct {
+	// HMACKeys are accepted signing keys, several keys allow rotation
+	HMACKeys [][]byte
+	// MaxClockSkew limits signed request age. Requests are also accepted only once inside this window,
+	// so captured requests can't be replayed.
+	MaxClockSkew time.Duration
+	// ClientCertNames are accepted client certificate common or DNS names, any verified certificate is accepted if empty
+	ClientCertNames []string
+	// Routes are patterns like "POST /groups/:group" of routes which require authentication.
+	// Method may be omitted, "*" segment at the end matches any path suffix.
+	Routes []string
+
+	mu sync.Mutex
+	// used contains accepted signatures with their expiration time
+	used      map[string]time.Time
+	nextSweep time.Time
+}
+
+// requestURL returns URL received by server, so signature and routes are checked against it
+// even if request path was rewritten before routing. Client requests have no RequestURI and their URL is used.
+func requestURL(req *http.Request) *url.URL {
+	if req.RequestURI != "" {
+		if u, err := url.ParseRequestURI(req.RequestURI); err == nil {
+			return u
+		}
+	}
+	return req.URL
+}
+
+// requestSignature returns HMAC of request method, URI, timestamp, nonce, identity headers and body hash.
+func requestSignature(key []byte, req *http.Request, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+	parts := []string{
+		req.Method,
+		requestURL(req).RequestURI(),
+		req.Header.Get(InternalTimestampXHeader),
+		req.Header.Get(InternalNonceXHeader),
+	}
+	for _, name := range identityHeaders {
+		parts = append(parts, req.Header.Get(name))
+	}
+	parts = append(parts, hex.EncodeToString(bodyHash[:]))
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(strings.Join(parts, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignInternalRequest adds signature headers to request. Request body is read and replaced.
+// Identity headers must be set before signing. Signed request is accepted only once, so it must be signed again on retry.
+func SignInternalRequest(req *http.Request, key []byte) error {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	req.Header.Set(InternalTimestampXHeader, strconv.FormatInt(time.Now().Unix(), 10))
+	req.Header.Set(InternalNonceXHeader, hex.EncodeToString(nonce))
+	req.Header.Set(InternalSignatureXHeader, requestSignature(key, req, body))
+	return nil
+}
+
+func routeMatches(pattern, method, path string) bool {
+	if sp := strings.IndexByte(pattern, ' '); sp >= 0 {
+		if !strings.EqualFold(pattern[:sp], method) {
+			return false
+		}
+		pattern = strings.TrimSpace(pattern[sp+1:])
+	}
+	patternParts := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathParts := strings.Split(strings.Trim(path, "/"), "/")
+	for i, part := range patternParts {
+		if part == "*" && i == len(patternParts)-1 {
+			return true
+		}
+		if i >= len(pathParts) || (!strings.HasPrefix(part, ":") && part != pathParts[i]) {
+			return false
+		}
+	}
+	return len(patternParts) == len(pathParts)
+}
+
+func (ia *InternalAuth) required(ctx *gin.Context) bool {
+	for _, pattern := range ia.Routes {
+		if routeMatches(pattern, ctx.Request.Method, requestURL(ctx.Request).Path) {
+			return true
+		}
+	}
+	return false
+}
+
+func (ia *InternalAuth) verifyCertificate(ctx *gin.Context) bool {
+	if ctx.Request.TLS == nil || len(ctx.Request.TLS.VerifiedChains) == 0 {
+		return false
+	}
+	if len(ia.ClientCertNames) == 0 {
+		return true
+	}
+	cert := ctx.Request.TLS.VerifiedChains[0][0]
+	for _, name := range ia.ClientCertNames {
+		if cert.Subject.CommonName == name {
+			return true
+		}
+		for _, dnsName := range cert.DNSNames {
+			if dnsName == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// markUsed remembers accepted signature until it expires. Returns false if signature was already used.
+func (ia *InternalAuth) markUsed(signature string, expiresAt time.Time) bool {
+	ia.mu.Lock()
+	defer ia.mu.Unlock()
+	now := time.Now()
+	if ia.used == nil {
+		ia.used = make(map[string]time.Time)
+	}
+	if now.After(ia.nextSweep) {
+		for sig, exp := range ia.used {
+			if now.After(exp) {
+				delete(ia.used, sig)
+			}
+		}
+		ia.nextSweep = now.Add(ia.MaxClockSkew)
+	}
+	if _, ok := ia.used[signature]; ok {
+		return false
+	}
+	ia.used[signature] = expiresAt
+	return true
+}
+
+func (ia *InternalAuth) verifySignature(ctx *gin.Context) bool {
+	signature := ctx.GetHeader(textproto.CanonicalMIMEHeaderKey(InternalSignatureXHeader))
+	timestamp := ctx.GetHeader(textproto.CanonicalMIMEHeaderKey(InternalTimestampXHeader))
+	if len(ia.HMACKeys) == 0 || signature == "" || timestamp == "" {
+		return false
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	signedAt := time.Unix(unix, 0)
+	if age := time.Since(signedAt); age > ia.MaxClockSkew || age < -ia.MaxClockSkew {
+		return false
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(ctx.Request.Body, maxSignedBodySize+1))
+	// body must be available for handler
+	ctx.Request.Body = ioutil.NopCloser(io.MultiReader(bytes.NewReader(body), ctx.Request.Body))
+	if err != nil || len(body) > maxSignedBodySize {
+		return false
+	}
+
+	for _, key := range ia.HMACKeys {
+		expected := requestSignature(key, ctx.Request, body)
+		if hmac.Equal([]byte(expected), []byte(signature)) {
+			if !ia.markUsed(signature, signedAt.Add(ia.MaxClockSkew)) {
+				logrus.WithField("path", requestURL(ctx.Request).Path).Warnln("Replayed internal request")
+				return false
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware returns middleware which rejects not authenticated requests to internal routes.
+// Other requests are marked as internal if they are authenticated, otherwise identity headers are removed from them.
+func (ia *InternalAuth) Middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if ia == nil {
+			return
+		}
+		if ia.verifyCertificate(ctx) || ia.verifySignature(ctx) {
+			ctx.Set(InternalAuthenticated, true)
+			return
+		}
+		for _, name := range identityHeaders {
+			ctx.Request.Header.Del(textproto.CanonicalMIMEHeaderKey(name))
+		}
+		if ia.required(ctx) {
+			logrus.WithField("path", requestURL(ctx.Request).Path).Warnln("Internal request authentication failed")
+			gonic.Gonic(umerrors.ErrInternalAuthRequired(), ctx)
+		}
+	}
+}
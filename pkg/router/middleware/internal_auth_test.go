@@ -0,0 +1,282 @@
+package middleware
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	headers "github.com/containerum/utils/httputil"
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	testKey    = []byte("current-key")
+	testOldKey = []byte("previous-key")
+)
+
+func TestRouteMatches(t *testing.T) {
+	tests := []struct {
+		pattern string
+		method  string
+		path    string
+		match   bool
+	}{
+		{"/user/info", "GET", "/user/info", true},
+		{"/user/info", "POST", "/user/info", true},
+		{"GET /user/info", "GET", "/user/info", true},
+		{"get /user/info", "GET", "/user/info", true},
+		{"GET /user/info", "POST", "/user/info", false},
+		{"/user/info", "GET", "/user/info/", true},
+		{"/user/info", "GET", "/user", false},
+		{"/user/info", "GET", "/user/info/id", false},
+		{"/user/info/id/:user_id", "GET", "/user/info/id/42", true},
+		{"/user/info/id/:user_id", "GET", "/user/info/id", false},
+		{"/user/info/id/:user_id", "GET", "/user/info/id/42/extra", false},
+		{"/groups/:group/members/:login", "PUT", "/groups/dev/members/john", true},
+		{"/groups/:group/members/:login", "PUT", "/groups/dev/owners/john", false},
+		{"/admin/*", "POST", "/admin/user/sign_up", true},
+		{"/admin/*", "POST", "/admin", true},
+		{"/admin/*", "POST", "/administrator/user", false},
+		{"POST /admin/*", "GET", "/admin/user", false},
+	}
+	for _, tt := range tests {
+		if got := routeMatches(tt.pattern, tt.method, tt.path); got != tt.match {
+			t.Errorf("routeMatches(%q, %q, %q) = %v, expected %v", tt.pattern, tt.method, tt.path, got, tt.match)
+		}
+	}
+}
+
+type internalAuthResult struct {
+	status        int
+	authenticated bool
+	userID        string
+	body          string
+}
+
+// serve passes request through internal auth middleware and records what handler received.
+func serve(ia *InternalAuth, req *http.Request) internalAuthResult {
+	gin.SetMode(gin.TestMode)
+	var result internalAuthResult
+	e := gin.New()
+	e.Use(ia.Middleware())
+	e.Any("/*path", func(ctx *gin.Context) {
+		result.authenticated = ctx.GetBool(InternalAuthenticated)
+		result.userID = ctx.GetHeader(headers.UserIDXHeader)
+		body, _ := ioutil.ReadAll(ctx.Request.Body)
+		result.body = string(body)
+		ctx.Status(http.StatusOK)
+	})
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+	result.status = w.Code
+	return result
+}
+
+func newSignedRequest(t *testing.T, key []byte, method, target, body string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set(headers.UserIDXHeader, "user-id")
+	req.Header.Set(headers.UserRoleXHeader, "user")
+	if err := SignInternalRequest(req, key); err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+func newInternalAuth() *InternalAuth {
+	return &InternalAuth{
+		HMACKeys:     [][]byte{testKey, testOldKey},
+		MaxClockSkew: time.Minute,
+		Routes:       []string{"/internal/*"},
+	}
+}
+
+func TestInternalAuthSignature(t *testing.T) {
+	tests := []struct {
+		name    string
+		request func(t *testing.T) *http.Request
+		valid   bool
+	}{
+		{
+			name: "valid",
+			request: func(t *testing.T) *http.Request {
+				return newSignedRequest(t, testKey, "POST", "/internal/users?limit=1", `{"login":"user"}`)
+			},
+			valid: true,
+		},
+		{
+			name: "rotated key",
+			request: func(t *testing.T) *http.Request {
+				return newSignedRequest(t, testOldKey, "POST", "/internal/users", `{}`)
+			},
+			valid: true,
+		},
+		{
+			name: "unknown key",
+			request: func(t *testing.T) *http.Request {
+				return newSignedRequest(t, []byte("unknown-key"), "POST", "/internal/users", `{}`)
+			},
+		},
+		{
+			name: "tampered body",
+			request: func(t *testing.T) *http.Request {
+				req := newSignedRequest(t, testKey, "POST", "/internal/users", `{"login":"user"}`)
+				req.Body = ioutil.NopCloser(strings.NewReader(`{"login":"admin"}`))
+				return req
+			},
+		},
+		{
+			name: "tampered URI",
+			request: func(t *testing.T) *http.Request {
+				req := newSignedRequest(t, testKey, "GET", "/internal/users?limit=1", "")
+				req.RequestURI = "/internal/users?limit=1000"
+				req.URL.RawQuery = "limit=1000"
+				return req
+			},
+		},
+		{
+			name: "tampered method",
+			request: func(t *testing.T) *http.Request {
+				req := newSignedRequest(t, testKey, "GET", "/internal/users", "")
+				req.Method = "DELETE"
+				return req
+			},
+		},
+		{
+			name: "tampered identity header",
+			request: func(t *testing.T) *http.Request {
+				req := newSignedRequest(t, testKey, "GET", "/internal/users", "")
+				req.Header.Set(headers.UserIDXHeader, "other-user-id")
+				return req
+			},
+		},
+		{
+			name: "tampered nonce",
+			request: func(t *testing.T) *http.Request {
+				req := newSignedRequest(t, testKey, "GET", "/internal/users", "")
+				req.Header.Set(InternalNonceXHeader, "00")
+				return req
+			},
+		},
+		{
+			name: "body too large",
+			request: func(t *testing.T) *http.Request {
+				return newSignedRequest(t, testKey, "POST", "/internal/users", strings.Repeat("a", maxSignedBodySize+1))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.request(t)
+			result := serve(newInternalAuth(), req)
+			if result.authenticated != tt.valid {
+				t.Errorf("expected authenticated = %v, got %v", tt.valid, result.authenticated)
+			}
+			if tt.valid && result.status != http.StatusOK {
+				t.Errorf("expected status 200, got %v", result.status)
+			}
+			if !tt.valid && result.status == http.StatusOK {
+				t.Error("expected request to internal route to be rejected")
+			}
+		})
+	}
+}
+
+func TestInternalAuthKeepsBody(t *testing.T) {
+	body := `{"login":"user"}`
+	result := serve(newInternalAuth(), newSignedRequest(t, testKey, "POST", "/internal/users", body))
+	if result.body != body {
+		t.Errorf("expected handler to receive body %q, got %q", body, result.body)
+	}
+	if result.userID != "user-id" {
+		t.Errorf("expected identity header to be kept, got %q", result.userID)
+	}
+}
+
+// signedAt returns request signed with given timestamp.
+func signedAt(t *testing.T, at time.Time) *http.Request {
+	req := newSignedRequest(t, testKey, "GET", "/internal/users", "")
+	req.Header.Set(InternalTimestampXHeader, strconv.FormatInt(at.Unix(), 10))
+	req.Header.Set(InternalSignatureXHeader, requestSignature(testKey, req, nil))
+	return req
+}
+
+func TestInternalAuthClockSkew(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name  string
+		at    time.Time
+		valid bool
+	}{
+		{"now", now, true},
+		{"recent past", now.Add(-30 * time.Second), true},
+		{"near future", now.Add(30 * time.Second), true},
+		{"too old", now.Add(-2 * time.Minute), false},
+		{"too far in future", now.Add(2 * time.Minute), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := serve(newInternalAuth(), signedAt(t, tt.at))
+			if result.authenticated != tt.valid {
+				t.Errorf("expected authenticated = %v, got %v", tt.valid, result.authenticated)
+			}
+		})
+	}
+}
+
+func TestInternalAuthReplay(t *testing.T) {
+	ia := newInternalAuth()
+	req := newSignedRequest(t, testKey, "POST", "/internal/users", `{"login":"user"}`)
+	replay := httptest.NewRequest(req.Method, req.RequestURI, strings.NewReader(`{"login":"user"}`))
+	for name, values := range req.Header {
+		replay.Header[name] = values
+	}
+
+	if result := serve(ia, req); !result.authenticated {
+		t.Fatal("expected first request to be accepted")
+	}
+	if result := serve(ia, replay); result.authenticated || result.status == http.StatusOK {
+		t.Error("expected replayed request to be rejected")
+	}
+	// same request signed again has new nonce
+	if result := serve(ia, newSignedRequest(t, testKey, "POST", "/internal/users", `{"login":"user"}`)); !result.authenticated {
+		t.Error("expected request signed again to be accepted")
+	}
+}
+
+func TestInternalAuthStripsIdentityHeaders(t *testing.T) {
+	req := httptest.NewRequest("GET", "/user/info", nil)
+	req.Header.Set(headers.UserIDXHeader, "forged-user-id")
+	result := serve(newInternalAuth(), req)
+	if result.status != http.StatusOK {
+		t.Fatalf("expected not internal route to be served, got status %v", result.status)
+	}
+	if result.authenticated {
+		t.Error("expected request without signature to be not authenticated")
+	}
+	if result.userID != "" {
+		t.Errorf("expected identity header to be removed, got %q", result.userID)
+	}
+}
+
+func TestInternalAuthRewrittenPath(t *testing.T) {
+	ia := newInternalAuth()
+	ia.Routes = []string{"GET /user/:user_id/groups"}
+	rewrite := func(req *http.Request) *http.Request {
+		req.URL.Path = "/user/groups/42"
+		return req
+	}
+
+	result := serve(ia, rewrite(newSignedRequest(t, testKey, "GET", "/user/42/groups", "")))
+	if !result.authenticated || result.status != http.StatusOK {
+		t.Errorf("expected request signed for original path to be accepted, got status %v", result.status)
+	}
+
+	req := rewrite(httptest.NewRequest("GET", "/user/42/groups", nil))
+	if result := serve(ia, req); result.status == http.StatusOK {
+		t.Error("expected not signed request to be rejected by original path route")
+	}
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/textproto"
+	"strings"
+
+	"git.containerum.net/ch/user-manager/pkg/clients"
+	"git.containerum.net/ch/user-manager/pkg/umerrors"
+	"git.containerum.net/ch/user-manager/pkg/utils"
+	"github.com/containerum/cherry/adaptors/gonic"
+	headers "github.com/containerum/utils/httputil"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// JWTIdentity derives identity headers from access token issued by auth instead of trusting headers set by gateway.
+type JWTIdentity struct {
+	Verifier *utils.JWTVerifier
+	// UserIDClaim and RoleClaim are names of claims containing user ID and role
+	UserIDClaim string
+	RoleClaim   string
+}
+
+// ImpersonatorClaim contains impersonator ID in tokens created by impersonation
+const ImpersonatorClaim = "impersonator_id"
+
+// Middleware returns middleware which replaces identity headers with token claims.
+// Identity headers are removed from requests without token, so such requests are anonymous.
+// Requests authenticated as internal keep their headers.
+func (j *JWTIdentity) Middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if j == nil || ctx.GetBool(InternalAuthenticated) {
+			return
+		}
+		h := ctx.Request.Header
+		for _, name := range identityHeaders {
+			h.Del(textproto.CanonicalMIMEHeaderKey(name))
+		}
+
+		auth := ctx.GetHeader("Authorization")
+		if !strings.HasPrefix(auth, "Bearer ") {
+			return
+		}
+		claims, err := j.Verifier.Verify(strings.TrimPrefix(auth, "Bearer "))
+		if err != nil {
+			logrus.WithError(err).Infoln("Access token rejected")
+			gonic.Gonic(umerrors.ErrInvalidToken().AddDetailsErr(err), ctx)
+			return
+		}
+		userID, _ := claims[j.UserIDClaim].(string)
+		role, _ := claims[j.RoleClaim].(string)
+		if userID == "" || role == "" {
+			gonic.Gonic(umerrors.ErrInvalidToken().AddDetails("token has no user ID or role"), ctx)
+			return
+		}
+
+		h.Set(textproto.CanonicalMIMEHeaderKey(headers.UserIDXHeader), userID)
+		h.Set(textproto.CanonicalMIMEHeaderKey(headers.UserRoleXHeader), role)
+		if jti, ok := claims["jti"].(string); ok && jti != "" {
+			h.Set(textproto.CanonicalMIMEHeaderKey(headers.TokenIDXHeader), jti)
+		}
+		if impersonator, ok := claims[ImpersonatorClaim].(string); ok && impersonator != "" {
+			h.Set(textproto.CanonicalMIMEHeaderKey(clients.ImpersonatorXHeader), impersonator)
+		}
+	}
+}
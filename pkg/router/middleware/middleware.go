@@ -3,16 +3,20 @@ package middleware
 import (
 	"net/textproto"
 
+	"git.containerum.net/ch/user-manager/pkg/clients"
+	"git.containerum.net/ch/user-manager/pkg/models"
 	"git.containerum.net/ch/user-manager/pkg/server"
 	"git.containerum.net/ch/user-manager/pkg/umerrors"
+	"github.com/containerum/cherry"
 	"github.com/containerum/cherry/adaptors/gonic"
 	headers "github.com/containerum/utils/httputil"
 	"github.com/gin-gonic/gin"
 )
 
 const (
-	RoleUser  = "user"
-	RoleAdmin = "admin"
+	RoleUser    = "user"
+	RoleAdmin   = "admin"
+	RoleService = "service"
 )
 
 // RequireAdminRole
@@ -29,6 +33,68 @@ func RequireAdminRole(ctx *gin.Context) {
 	}
 }
 
+// RequireOrgAdmin allows admins and organization admins. Handlers must restrict organization admins to their organization.
+func RequireOrgAdmin(ctx *gin.Context) {
+	um := ctx.MustGet(UMServices).(server.UserManager)
+	if ctx.GetHeader(textproto.CanonicalMIMEHeaderKey(headers.UserRoleXHeader)) == RoleAdmin {
+		if err := um.CheckAdmin(ctx.Request.Context()); err != nil {
+			gonic.Gonic(umerrors.ErrAdminRequired(), ctx)
+		}
+		return
+	}
+
+	if err := um.CheckOrgAdmin(ctx.Request.Context()); err != nil {
+		gonic.Gonic(umerrors.ErrOrgAdminRequired(), ctx)
+	}
+}
+
+// RequirePermission allows admins and users which have permission through admin roles.
+func RequirePermission(permission models.Permission) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		um := ctx.MustGet(UMServices).(server.UserManager)
+		if ctx.GetHeader(textproto.CanonicalMIMEHeaderKey(headers.UserRoleXHeader)) == RoleAdmin {
+			if err := um.CheckAdmin(ctx.Request.Context()); err != nil {
+				gonic.Gonic(umerrors.ErrAdminRequired(), ctx)
+			}
+			return
+		}
+
+		if err := um.CheckPermission(ctx.Request.Context(), permission); err != nil {
+			if cherr, ok := err.(*cherry.Err); ok {
+				gonic.Gonic(cherr, ctx)
+			} else {
+				gonic.Gonic(umerrors.ErrPermissionRequired().AddDetails(string(permission)), ctx)
+			}
+		}
+	}
+}
+
+// RequireService allows service accounts and admins. It protects internal endpoints.
+func RequireService(ctx *gin.Context) {
+	switch ctx.GetHeader(textproto.CanonicalMIMEHeaderKey(headers.UserRoleXHeader)) {
+	case RoleService, RoleAdmin:
+	default:
+		gonic.Gonic(umerrors.ErrServiceRequired(), ctx)
+		return
+	}
+
+	um := ctx.MustGet(UMServices).(server.UserManager)
+	if err := um.CheckService(ctx.Request.Context()); err != nil {
+		if cherr, ok := err.(*cherry.Err); ok {
+			gonic.Gonic(cherr, ctx)
+		} else {
+			gonic.Gonic(umerrors.ErrServiceRequired(), ctx)
+		}
+	}
+}
+
+// DenyImpersonated rejects requests made in session created by admin impersonation.
+func DenyImpersonated(ctx *gin.Context) {
+	if ctx.GetHeader(textproto.CanonicalMIMEHeaderKey(clients.ImpersonatorXHeader)) != "" {
+		gonic.Gonic(umerrors.ErrImpersonatedSession(), ctx)
+	}
+}
+
 func RequireUserExist(ctx *gin.Context) {
 	um := ctx.MustGet(UMServices).(server.UserManager)
 	err := um.CheckUserExist(ctx.Request.Context())
@@ -0,0 +1,226 @@
+package middleware
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"git.containerum.net/ch/user-manager/pkg/db"
+	"git.containerum.net/ch/user-manager/pkg/umerrors"
+	"github.com/containerum/cherry/adaptors/gonic"
+	headers "github.com/containerum/utils/httputil"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// Rate limit keys.
+const (
+	// RateLimitByIP uses client IP from X-Client-IP header. The header is set by gateway and is not verified,
+	// so service must not be reachable by clients directly if limits by IP are used.
+	RateLimitByIP    = "ip"
+	RateLimitByLogin = "login"
+)
+
+// maxLoginBodySize limits body read to get login, larger bodies are truncated and rejected by handler.
+const maxLoginBodySize = 64 << 10
+
+// RateLimit allows Burst requests with same key per Period.
+type RateLimit struct {
+	Key    string
+	Burst  int
+	Period time.Duration
+}
+
+func (l RateLimit) refillPerSecond() float64 {
+	return float64(l.Burst) / l.Period.Seconds()
+}
+
+// ParseRateLimits parses limits in format "ip:10/1h,login:3/1h". Empty string means no limits.
+func ParseRateLimits(s string) ([]RateLimit, error) {
+	var limits []RateLimit
+	for _, spec := range strings.Split(s, ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		keyAndLimit := strings.SplitN(spec, ":", 2)
+		burstAndPeriod := strings.SplitN(keyAndLimit[len(keyAndLimit)-1], "/", 2)
+		if len(keyAndLimit) != 2 || len(burstAndPeriod) != 2 {
+			return nil, fmt.Errorf("invalid rate limit %q, expected format is key:burst/period", spec)
+		}
+		limit := RateLimit{Key: keyAndLimit[0]}
+		if _, ok := rateLimitKeys[limit.Key]; !ok {
+			return nil, fmt.Errorf("invalid rate limit %q: unknown key %v", spec, limit.Key)
+		}
+		var err error
+		if limit.Burst, err = strconv.Atoi(burstAndPeriod[0]); err != nil || limit.Burst <= 0 {
+			return nil, fmt.Errorf("invalid rate limit %q: burst must be positive number", spec)
+		}
+		if limit.Period, err = time.ParseDuration(burstAndPeriod[1]); err != nil || limit.Period <= 0 {
+			return nil, fmt.Errorf("invalid rate limit %q: period must be positive duration", spec)
+		}
+		limits = append(limits, limit)
+	}
+	return limits, nil
+}
+
+// RateLimitStore keeps token buckets.
+type RateLimitStore interface {
+	// Take takes token from bucket for key. If bucket is empty returns time after which token will be available.
+	Take(ctx context.Context, key string, limit RateLimit) (retryAfter time.Duration, err error)
+}
+
+// RateLimiter limits requests to route groups using token buckets.
+type RateLimiter struct {
+	Store RateLimitStore
+	// Limits by route group name
+	Limits map[string][]RateLimit
+}
+
+var rateLimitKeys = map[string]func(ctx *gin.Context) string{
+	RateLimitByIP: func(ctx *gin.Context) string {
+		// client IP header is not required by some routes
+		if ip := ctx.GetHeader(textproto.CanonicalMIMEHeaderKey(headers.UserIPXHeader)); ip != "" {
+			return ip
+		}
+		return ctx.ClientIP()
+	},
+	RateLimitByLogin: func(ctx *gin.Context) string {
+		body, err := ioutil.ReadAll(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxLoginBodySize))
+		// body must be available for handler
+		ctx.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+		if err != nil {
+			return ""
+		}
+		var request struct {
+			Login string `json:"login"`
+		}
+		if json.Unmarshal(body, &request) != nil {
+			return ""
+		}
+		return strings.ToLower(request.Login)
+	},
+}
+
+// Limit returns middleware which applies limits of route group. Requests pass if store is unavailable.
+func (rl *RateLimiter) Limit(group string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if rl == nil {
+			return
+		}
+		for _, limit := range rl.Limits[group] {
+			value := rateLimitKeys[limit.Key](ctx)
+			if value == "" {
+				continue
+			}
+			retryAfter, err := rl.Store.Take(ctx.Request.Context(), group+":"+limit.Key+":"+value, limit)
+			if err != nil {
+				logrus.WithError(err).WithField("group", group).Warnln("Unable to check rate limit")
+				continue
+			}
+			if retryAfter > 0 {
+				ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+				gonic.Gonic(umerrors.ErrTooManyRequests(), ctx)
+				return
+			}
+		}
+	}
+}
+
+type bucket struct {
+	key       string
+	tokens    float64
+	updatedAt time.Time
+	fullAt    time.Time
+}
+
+type memoryRateLimitStore struct {
+	mu      sync.Mutex
+	size    int
+	buckets map[string]*list.Element
+	// recently used buckets are in front
+	lru       *list.List
+	lastSweep time.Time
+}
+
+// NewMemoryRateLimitStore creates store which keeps up to size buckets in memory. Limits are applied per replica.
+// Least recently used buckets are evicted when store is full.
+func NewMemoryRateLimitStore(size int) RateLimitStore {
+	return &memoryRateLimitStore{
+		size:      size,
+		buckets:   make(map[string]*list.Element),
+		lru:       list.New(),
+		lastSweep: time.Now(),
+	}
+}
+
+func (s *memoryRateLimitStore) Take(_ context.Context, key string, limit RateLimit) (time.Duration, error) {
+	now := time.Now()
+	rate := limit.refillPerSecond()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) > time.Minute {
+		// full buckets are equivalent to missing ones
+		for k, e := range s.buckets {
+			if now.After(e.Value.(*bucket).fullAt) {
+				s.lru.Remove(e)
+				delete(s.buckets, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	var b *bucket
+	if e, ok := s.buckets[key]; ok {
+		s.lru.MoveToFront(e)
+		b = e.Value.(*bucket)
+	} else {
+		if s.lru.Len() >= s.size {
+			oldest := s.lru.Back()
+			s.lru.Remove(oldest)
+			delete(s.buckets, oldest.Value.(*bucket).key)
+		}
+		b = &bucket{key: key, tokens: float64(limit.Burst), updatedAt: now}
+		s.buckets[key] = s.lru.PushFront(b)
+	}
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+now.Sub(b.updatedAt).Seconds()*rate)
+	b.updatedAt = now
+	var retryAfter time.Duration
+	if b.tokens >= 1 {
+		b.tokens--
+	} else {
+		retryAfter = time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	}
+	b.fullAt = now.Add(time.Duration((float64(limit.Burst) - b.tokens) / rate * float64(time.Second)))
+	return retryAfter, nil
+}
+
+type dbRateLimitStore struct {
+	db db.DB
+}
+
+// NewDBRateLimitStore creates store which keeps buckets in database, so limits are shared between replicas.
+func NewDBRateLimitStore(db db.DB) RateLimitStore {
+	return &dbRateLimitStore{db: db}
+}
+
+func (s *dbRateLimitStore) Take(ctx context.Context, key string, limit RateLimit) (time.Duration, error) {
+	rate := limit.refillPerSecond()
+	tokens, allowed, err := s.db.TakeRateLimitToken(ctx, key, float64(limit.Burst), rate)
+	if err != nil || allowed {
+		return 0, err
+	}
+	return time.Duration((1 - tokens) / rate * float64(time.Second)), nil
+}
@@ -2,8 +2,10 @@ package router
 
 import (
 	"net/http"
+	"regexp"
 	"time"
 
+	"git.containerum.net/ch/user-manager/pkg/models"
 	h "git.containerum.net/ch/user-manager/pkg/router/handlers"
 	m "git.containerum.net/ch/user-manager/pkg/router/middleware"
 	"git.containerum.net/ch/user-manager/pkg/server"
@@ -21,32 +23,83 @@ import (
 	"gopkg.in/gin-contrib/cors.v1"
 )
 
+// Route groups with configurable rate limits.
+const (
+	RateLimitGroupSignUp        = "sign_up"
+	RateLimitGroupResend        = "resend"
+	RateLimitGroupPasswordReset = "password_reset"
+	RateLimitGroupLogin         = "login"
+)
+
+// Options are optional router features. Nil middlewares are disabled.
+type Options struct {
+	EnableCORS  bool
+	RateLimiter *m.RateLimiter
+	// PrivacyMode restricts internal user lookups to service accounts and internal requests
+	PrivacyMode  bool
+	InternalAuth *m.InternalAuth
+	JWTIdentity  *m.JWTIdentity
+}
+
 //CreateRouter initialises router and middlewares
-func CreateRouter(um *server.UserManager, status *model.ServiceStatus, enableCORS bool) http.Handler {
+func CreateRouter(um *server.UserManager, status *model.ServiceStatus, opts Options) http.Handler {
 	e := gin.New()
-	initMiddlewares(e, um)
-	initRoutes(e, status, enableCORS)
-	return e
+	initMiddlewares(e, um, opts)
+	initRoutes(e, status, opts)
+	return rewriteUserGroupsPath(e)
+}
+
+// userGroupsPath matches /user/{user_id}/groups. Such route can't be registered in gin
+// because user id wildcard conflicts with static /user routes.
+var userGroupsPath = regexp.MustCompile(`^/user/([^/]+)/groups/?$`)
+
+// rewriteUserGroupsPath serves /user/{user_id}/groups by route /user/groups/{user_id}.
+// Request URI is kept, so internal authentication checks original path.
+func rewriteUserGroupsPath(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if match := userGroupsPath.FindStringSubmatch(r.URL.Path); match != nil {
+			r.URL.Path = "/user/groups/" + match[1]
+			r.URL.RawPath = ""
+		}
+		next.ServeHTTP(w, r)
+	})
 }
 
-func initMiddlewares(e *gin.Engine, um *server.UserManager) {
+func initMiddlewares(e *gin.Engine, um *server.UserManager, opts Options) {
 	/* System */
 	e.Use(ginrus.Ginrus(logrus.WithField("component", "gin"), time.RFC3339, true))
 	e.Use(gonic.Recovery(umerrors.ErrInternalError, cherrylog.NewLogrusAdapter(logrus.WithField("component", "gin"))))
 	/* Custom */
 	e.Use(m.RegisterServices(um))
+	// identity headers must be verified before they are copied to context
+	e.Use(opts.InternalAuth.Middleware())
+	e.Use(opts.JWTIdentity.Middleware())
 	e.Use(httputil.PrepareContext)
 	e.Use(httputil.SaveHeaders)
 }
 
 // SetupRoutes sets up http router needed to handle requests from clients.
-func initRoutes(app *gin.Engine, status *model.ServiceStatus, enableCORS bool) {
+func initRoutes(app *gin.Engine, status *model.ServiceStatus, opts Options) {
+	rl := opts.RateLimiter
 	requireIdentityHeaders := httputil.RequireHeaders(umerrors.ErrRequiredHeadersNotProvided, httputil.UserIDXHeader, httputil.UserRoleXHeader)
 	requireLoginHeaders := httputil.RequireHeaders(umerrors.ErrRequiredHeadersNotProvided, httputil.UserAgentXHeader, httputil.UserClientXHeader, httputil.UserIPXHeader)
 	//TODO
 	requireLogoutHeaders := httputil.RequireHeaders(umerrors.ErrRequiredHeadersNotProvided, httputil.TokenIDXHeader, "X-Session-ID")
 
-	if enableCORS {
+	// internal lookups allow to enumerate users
+	requireInternal := func(*gin.Context) {}
+	if opts.PrivacyMode {
+		requireInternal = func(ctx *gin.Context) {
+			if ctx.GetBool(m.InternalAuthenticated) {
+				return
+			}
+			if requireIdentityHeaders(ctx); !ctx.IsAborted() {
+				m.RequireService(ctx)
+			}
+		}
+	}
+
+	if opts.EnableCORS {
 		cfg := cors.DefaultConfig()
 		cfg.AllowAllOrigins = true
 		cfg.AddAllowMethods(http.MethodDelete)
@@ -61,29 +114,34 @@ func initRoutes(app *gin.Engine, status *model.ServiceStatus, enableCORS bool) {
 	root := app.Group("")
 	{
 		root.POST("/logout", requireLogoutHeaders, m.RequireUserExist, h.LogoutHandler)
+		root.GET("/links/policies", h.LinkPoliciesGetHandler)
 	}
 
 	user := app.Group("/user")
 	{
-		user.POST("/sign_up", requireLoginHeaders, h.UserCreateHandler)
-		user.POST("/sign_up/resend", h.LinkResendHandler)
+		user.POST("/sign_up", requireLoginHeaders, rl.Limit(RateLimitGroupSignUp), h.UserCreateHandler)
+		user.POST("/sign_up/resend", rl.Limit(RateLimitGroupResend), h.LinkResendHandler)
 		user.POST("/activation", requireLoginHeaders, h.ActivateHandler)
+		user.POST("/restore", requireLoginHeaders, h.RestoreHandler)
 
-		user.GET("/list", requireIdentityHeaders, m.RequireAdminRole, h.UserListGetHandler)
-		user.GET("/links/:user_id", requireIdentityHeaders, m.RequireAdminRole, h.LinksGetHandler)
+		user.GET("/list", requireIdentityHeaders, m.RequirePermission(models.PermissionUsersRead), h.UserListGetHandler)
+		// links may be used to login
+		user.GET("/links/:user_id", requireIdentityHeaders, m.RequirePermission(models.PermissionUsersWrite), h.LinksGetHandler)
+		// served as /user/:user_id/groups, see rewriteUserGroupsPath
+		user.GET("/groups/:user_id", requireIdentityHeaders, m.RequirePermission(models.PermissionUsersRead), h.GetUserGroupMembershipsHandler)
 
-		user.POST("/loginid", h.UserListLoginID)
+		user.POST("/loginid", requireInternal, h.UserListLoginID)
 
 		deleteuser := user.Group("/delete", requireIdentityHeaders)
 		{
-			deleteuser.POST("/partial", m.RequireUserExist, h.PartialDeleteHandler)
-			deleteuser.POST("/complete", m.RequireAdminRole, h.CompleteDeleteHandler)
+			deleteuser.POST("/partial", m.DenyImpersonated, m.RequireUserExist, h.PartialDeleteHandler)
+			deleteuser.POST("/complete", m.RequirePermission(models.PermissionUsersWrite), h.CompleteDeleteHandler)
 		}
 
 		info := user.Group("/info")
 		{
-			info.GET("/id/:user_id", h.UserGetByIDHandler)
-			info.GET("/login/:login", h.UserGetByLoginHandler)
+			info.GET("/id/:user_id", requireInternal, h.UserGetByIDHandler)
+			info.GET("/login/:login", requireInternal, h.UserGetByLoginHandler)
 			info.GET("", requireIdentityHeaders, m.RequireUserExist, h.UserInfoGetHandler)
 			info.PUT("", requireIdentityHeaders, m.RequireUserExist, h.UserInfoUpdateHandler)
 		}
@@ -91,67 +149,133 @@ func initRoutes(app *gin.Engine, status *model.ServiceStatus, enableCORS bool) {
 		accounts := user.Group("/bound_accounts", requireIdentityHeaders, m.RequireUserExist)
 		{
 			accounts.GET("", h.GetBoundAccountsHandler)
-			accounts.POST("", h.AddBoundAccountHandler)
-			accounts.DELETE("", h.DeleteBoundAccountHandler)
+			accounts.POST("", m.DenyImpersonated, h.AddBoundAccountHandler)
+			accounts.DELETE("", m.DenyImpersonated, h.DeleteBoundAccountHandler)
 		}
 
-		blacklist := user.Group("/blacklist", requireIdentityHeaders, m.RequireAdminRole)
+		blacklist := user.Group("/blacklist", requireIdentityHeaders)
 		{
-			blacklist.GET("", h.BlacklistGetHandler)
-			blacklist.POST("", h.UserToBlacklistHandler)
-			blacklist.DELETE("", h.UserDeleteFromBlacklistHandler)
+			blacklist.GET("", m.RequirePermission(models.PermissionUsersRead), h.BlacklistGetHandler)
+			blacklist.POST("", m.RequirePermission(models.PermissionUsersBlacklist), h.UserToBlacklistHandler)
+			blacklist.DELETE("", m.RequirePermission(models.PermissionUsersBlacklist), h.UserDeleteFromBlacklistHandler)
 		}
 	}
 
-	login := app.Group("/login", requireLoginHeaders)
+	login := app.Group("/login", requireLoginHeaders, rl.Limit(RateLimitGroupLogin))
 	{
 		login.POST("/basic", h.BasicLoginHandler)
 		login.POST("/token", h.OneTimeTokenLoginHandler)
 		login.POST("/oauth", h.OAuthLoginHandler)
+		login.POST("/apikey", h.APIKeyLoginHandler)
 	}
 
 	password := app.Group("/password")
 	{
-		password.POST("/reset", h.PasswordResetHandler)
-		password.POST("/restore", h.PasswordRestoreHandler)
+		password.POST("/reset", rl.Limit(RateLimitGroupPasswordReset), h.PasswordResetHandler)
+		password.POST("/restore", rl.Limit(RateLimitGroupPasswordReset), h.PasswordRestoreHandler)
+
+		password.PUT("/change", requireIdentityHeaders, m.DenyImpersonated, m.RequireUserExist, h.PasswordChangeHandler)
+		// issues tokens like login
+		password.PUT("/change/expired", requireLoginHeaders, rl.Limit(RateLimitGroupLogin), h.ExpiredPasswordChangeHandler)
+	}
+
+	serviceAccounts := app.Group("/service_accounts", requireIdentityHeaders, m.RequireUserExist)
+	{
+		serviceAccounts.GET("", h.ServiceAccountsGetHandler)
+		// long-living credentials would outlive impersonated session
+		serviceAccounts.POST("", m.DenyImpersonated, h.ServiceAccountCreateHandler)
 
-		password.PUT("/change", requireIdentityHeaders, m.RequireUserExist, h.PasswordChangeHandler)
+		serviceAccounts.GET("/:account_id/keys", h.APIKeysGetHandler)
+		serviceAccounts.POST("/:account_id/keys", m.DenyImpersonated, h.APIKeyCreateHandler)
+		serviceAccounts.DELETE("/:account_id/keys/:key_id", h.APIKeyRevokeHandler)
 	}
 
-	domainBlacklist := app.Group("/domain", requireIdentityHeaders, m.RequireAdminRole)
+	domainBlacklist := app.Group("/domain", requireIdentityHeaders)
 	{
-		domainBlacklist.GET("", h.BlacklistDomainsListGetHandler)
-		domainBlacklist.GET("/:domain", h.BlacklistDomainGetHandler)
+		domainBlacklist.GET("", m.RequirePermission(models.PermissionDomainsRead), h.BlacklistDomainsListGetHandler)
+		domainBlacklist.GET("/:domain", m.RequirePermission(models.PermissionDomainsRead), h.BlacklistDomainGetHandler)
 
-		domainBlacklist.POST("", h.BlacklistDomainAddHandler)
+		domainBlacklist.POST("", m.RequirePermission(models.PermissionDomainsWrite), h.BlacklistDomainAddHandler)
+		domainBlacklist.POST("/import", m.RequirePermission(models.PermissionDomainsWrite), h.DomainsImportHandler)
 
-		domainBlacklist.DELETE("/:domain", h.BlacklistDomainDeleteHandler)
+		domainBlacklist.DELETE("/:domain", m.RequirePermission(models.PermissionDomainsWrite), h.BlacklistDomainDeleteHandler)
 	}
 
-	admin := app.Group("/admin/user", requireIdentityHeaders, m.RequireAdminRole)
+	// networks are matched with catch-all parameter because CIDR notation contains "/"
+	ipRules := app.Group("/ip", requireIdentityHeaders)
 	{
-		admin.POST("/sign_up", h.AdminUserCreateHandler)
-		admin.POST("/activation", h.AdminUserActivateHandler)
-		admin.POST("/deactivation", h.AdminUserDeactivateHandler)
-		admin.POST("/password/reset", h.AdminResetPasswordHandler)
-		admin.POST("", h.AdminSetAdminHandler)
+		ipRules.GET("", m.RequirePermission(models.PermissionIPsRead), h.IPRulesGetHandler)
+		ipRules.GET("/*cidr", m.RequirePermission(models.PermissionIPsRead), h.IPRuleGetHandler)
+
+		ipRules.POST("", m.RequirePermission(models.PermissionIPsWrite), h.IPRuleAddHandler)
 
-		admin.DELETE("", h.AdminUnsetAdminHandler)
+		ipRules.DELETE("/*cidr", m.RequirePermission(models.PermissionIPsWrite), h.IPRuleDeleteHandler)
+	}
+
+	admin := app.Group("/admin/user", requireIdentityHeaders)
+	{
+		admin.GET("/impersonations/:user_id", m.RequirePermission(models.PermissionUsersRead), h.AdminImpersonationsGetHandler)
+
+		admin.POST("/sign_up", m.RequirePermission(models.PermissionUsersWrite), h.AdminUserCreateHandler)
+		admin.POST("/activation", m.RequirePermission(models.PermissionUsersWrite), h.AdminUserActivateHandler)
+		admin.POST("/deactivation", m.RequirePermission(models.PermissionUsersWrite), h.AdminUserDeactivateHandler)
+		admin.POST("/password/reset", m.RequirePermission(models.PermissionUsersResetPassword), h.AdminResetPasswordHandler)
+		admin.POST("/password/require_change", m.RequirePermission(models.PermissionUsersResetPassword), h.AdminPasswordChangeRequirementHandler)
+		admin.POST("/restore", m.RequirePermission(models.PermissionUsersWrite), h.AdminUserRestoreHandler)
+		admin.POST("/impersonate", requireLoginHeaders, m.DenyImpersonated, m.RequirePermission(models.PermissionUsersImpersonate), h.AdminImpersonateHandler)
+		// only admins can manage superuser role
+		admin.POST("", m.RequireAdminRole, h.AdminSetAdminHandler)
+
+		admin.DELETE("", m.RequireAdminRole, h.AdminUnsetAdminHandler)
+	}
+
+	roles := app.Group("/roles", requireIdentityHeaders, m.RequirePermission(models.PermissionRolesManage))
+	{
+		roles.GET("", h.AdminRolesGetHandler)
+		roles.PUT("/:role", h.AdminRoleSetHandler)
+		roles.PUT("/:role/users/:user_id", h.AdminRoleGrantHandler)
+
+		roles.DELETE("/:role", h.AdminRoleDeleteHandler)
+		roles.DELETE("/:role/users/:user_id", h.AdminRoleRevokeHandler)
+	}
+
+	organizations := app.Group("/organizations", requireIdentityHeaders, m.RequireAdminRole)
+	{
+		organizations.GET("", h.OrganizationsGetHandler)
+		organizations.POST("", h.OrganizationCreateHandler)
+		organizations.PUT("/users/:user_id", h.UserOrganizationSetHandler)
 	}
 
 	userGroups := app.Group("/groups", requireIdentityHeaders, m.RequireUserExist)
 	{
 		userGroups.GET("", h.GetGroupsListHandler)
-		userGroups.GET("/:group", m.RequireAdminRole, h.GetGroupHandler)
+		// group owner and members with owner access are checked in handlers
+		userGroups.GET("/:group", h.GetGroupHandler)
+		userGroups.GET("/:group/members", h.GetGroupMembersHandler)
+		userGroups.GET("/:group/invites", h.GroupInvitesGetHandler)
+		userGroups.GET("/:group/subgroups", h.GetSubgroupsHandler)
 
-		userGroups.POST("", m.RequireAdminRole, h.CreateGroupHandler)
-		userGroups.POST("/:group/members", m.RequireAdminRole, h.AddGroupMembersHandler)
+		userGroups.POST("", m.RequireOrgAdmin, h.CreateGroupHandler)
+		userGroups.POST("/:group/members", h.AddGroupMembersHandler)
+		userGroups.POST("/:group/invites", h.GroupInviteCreateHandler)
+		userGroups.POST("/:group/subgroups", h.AddSubgroupHandler)
 		//TODO Some kind of workaround. Real route is "/labelid" or "/labelidfull"
 		userGroups.POST("/:group", h.GroupListLabelID)
 
-		userGroups.PUT("/:group/members/:login", m.RequireAdminRole, h.UpdateGroupMemberHandler)
+		userGroups.PUT("/:group/members", h.SyncGroupMembersHandler)
+		userGroups.PUT("/:group/members/:login", h.UpdateGroupMemberHandler)
+		userGroups.PUT("/:group/owner", h.TransferGroupOwnerHandler)
+		userGroups.PUT("/:group", h.UpdateGroupHandler)
 
-		userGroups.DELETE("/:group/members/:login", m.RequireAdminRole, h.DeleteGroupMemberHandler)
-		userGroups.DELETE("/:group", m.RequireAdminRole, h.DeleteGroupHandler)
+		userGroups.DELETE("/:group/members/:login", h.DeleteGroupMemberHandler)
+		userGroups.DELETE("/:group/invites/:invite_id", h.GroupInviteDeleteHandler)
+		userGroups.DELETE("/:group/subgroups/:subgroup", h.DeleteSubgroupHandler)
+		userGroups.DELETE("/:group", h.DeleteGroupHandler)
+	}
+
+	invites := app.Group("/invites")
+	{
+		invites.POST("/accept", requireIdentityHeaders, m.RequireUserExist, h.GroupInviteAcceptHandler)
+		invites.POST("/decline", h.GroupInviteDeclineHandler)
 	}
 }
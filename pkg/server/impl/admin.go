@@ -19,7 +19,7 @@ import (
 func (u *serverImpl) AdminCreateUser(ctx context.Context, request models.UserLogin) (*models.UserLogin, error) {
 	u.log.WithField("login", request.Login).Info("creating user (admin)")
 
-	password, err := utils.SecureRandomString(10)
+	password, err := u.generatePassword(request.Login)
 	if err != nil {
 		u.log.WithError(err)
 		return nil, cherry.ErrUnableCreateUser()
@@ -40,6 +40,12 @@ func (u *serverImpl) AdminCreateUser(ctx context.Context, request models.UserLog
 		return nil, cherry.ErrUserAlreadyExists()
 	}
 
+	// users created by admin roles holders belong to their organization
+	orgID, err := u.orgScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	salt := utils.GenSalt(request.Login, request.Login, request.Login) // compatibility with old client db
 	passwordHash := utils.GetKey(request.Login, password, salt)
 	newUser := &db.User{
@@ -49,6 +55,7 @@ func (u *serverImpl) AdminCreateUser(ctx context.Context, request models.UserLog
 		Role:         m.RoleUser,
 		IsActive:     true,
 		IsDeleted:    false,
+		OrgID:        orgID,
 	}
 
 	err = u.svc.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) error {
@@ -63,7 +70,7 @@ func (u *serverImpl) AdminCreateUser(ctx context.Context, request models.UserLog
 		}); createErr != nil {
 			return createErr
 		}
-		return nil
+		return u.savePasswordHistory(ctx, tx, newUser, password)
 	})
 	if err := u.handleDBError(err); err != nil {
 		return nil, cherry.ErrUnableCreateUser()
@@ -88,9 +95,15 @@ func (u *serverImpl) AdminActivateUser(ctx context.Context, request models.UserL
 		u.log.WithError(err)
 		return cherry.ErrUnableActivate()
 	}
+	if user == nil {
+		return cherry.ErrUserNotExist()
+	}
 	if user.IsDeleted {
 		return cherry.ErrInvalidLogin()
 	}
+	if err := u.checkAdminTarget(ctx, user); err != nil {
+		return err
+	}
 
 	if user.IsActive {
 		return cherry.ErrUserAlreadyActivated()
@@ -122,6 +135,9 @@ func (u *serverImpl) AdminDeactivateUser(ctx context.Context, request models.Use
 	if err := u.loginUserChecks(user); err != nil {
 		return err
 	}
+	if err := u.checkAdminTarget(ctx, user); err != nil {
+		return err
+	}
 
 	if user.ID == httputil.MustGetUserID(ctx) {
 		return cherry.ErrChangeOwnPermissions()
@@ -158,16 +174,28 @@ func (u *serverImpl) AdminResetPassword(ctx context.Context, request models.User
 	if err := u.loginUserChecks(user); err != nil {
 		return nil, err
 	}
+	if err := u.checkAdminTarget(ctx, user); err != nil {
+		return nil, err
+	}
 
-	password, err := utils.SecureRandomString(10)
+	password, err := u.generatePassword(user.Login)
 	if err != nil {
 		u.log.WithError(err)
 		return nil, cherry.ErrUnableChangePassword()
 	}
+	// generated password satisfies policy rules, but it still may be breached or used before
+	if err := u.checkPasswordPolicy(ctx, user.Login, user, password); err != nil {
+		u.log.WithError(err)
+		return nil, cherry.ErrUnableChangePassword()
+	}
 
-	user.PasswordHash = utils.GetKey(user.Login, password, user.Salt)
+	// user must replace password known to admin
+	setUserPassword(user, password, true)
 	err = u.svc.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) error {
-		return tx.UpdateUser(ctx, user)
+		if err := tx.UpdateUser(ctx, user); err != nil {
+			return err
+		}
+		return u.savePasswordHistory(ctx, tx, user, password)
 	})
 	if err = u.handleDBError(err); err != nil {
 		return nil, cherry.ErrUnableChangePassword()
@@ -237,6 +265,66 @@ func (u *serverImpl) AdminUnsetAdmin(ctx context.Context, request models.UserLog
 	return nil
 }
 
+func (u *serverImpl) AdminRestoreUser(ctx context.Context, request models.UserLogin) error {
+	u.log.WithField("login", request.Login).Info("restoring user (admin)")
+
+	user, err := u.svc.DB.GetAnyUserByLogin(ctx, request.Login)
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return cherry.ErrUnableRestoreUser()
+	}
+	if user == nil {
+		u.log.WithError(cherry.ErrUserNotExist())
+		return cherry.ErrUserNotExist()
+	}
+	if err := u.checkAdminTarget(ctx, user); err != nil {
+		return err
+	}
+
+	link, err := u.svc.DB.GetLinkForUser(ctx, models.LinkTypeDelete, user)
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return cherry.ErrUnableRestoreUser()
+	}
+
+	return u.restoreUser(ctx, user, link)
+}
+
+func (u *serverImpl) AdminSetPasswordChangeRequirement(ctx context.Context, request models.PasswordChangeRequirement) error {
+	u.log.WithField("login", request.Login).WithField("required", request.Required).Info("setting password change requirement (admin)")
+
+	user, err := u.svc.DB.GetUserByLogin(ctx, request.Login)
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return cherry.ErrUnableChangePassword()
+	}
+	if err := u.loginUserChecks(user); err != nil {
+		return err
+	}
+	if err := u.checkAdminTarget(ctx, user); err != nil {
+		return err
+	}
+
+	user.MustChangePassword = request.Required
+	err = u.svc.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) error {
+		return tx.UpdateUser(ctx, user)
+	})
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return cherry.ErrUnableChangePassword()
+	}
+
+	if request.Required {
+		// user has to login again to get password change requirement
+		if _, authErr := u.svc.AuthClient.DeleteUserTokens(ctx, &authProto.DeleteUserTokensRequest{
+			UserId: user.ID,
+		}); authErr != nil {
+			return authErr
+		}
+	}
+	return nil
+}
+
 func (u *serverImpl) CreateFirstAdmin(password string) error {
 	u.log.Info("creating first admin user")
 
@@ -0,0 +1,249 @@
+package impl
+
+import (
+	"context"
+
+	"git.containerum.net/ch/user-manager/pkg/db"
+	"git.containerum.net/ch/user-manager/pkg/models"
+	m "git.containerum.net/ch/user-manager/pkg/router/middleware"
+	cherry "git.containerum.net/ch/user-manager/pkg/umerrors"
+	"github.com/containerum/utils/httputil"
+	"github.com/sirupsen/logrus"
+)
+
+// orgAdminPermissions are granted to organization admins without explicit roles. They are restricted to their organization.
+var orgAdminPermissions = map[models.Permission]bool{
+	models.PermissionUsersRead:      true,
+	models.PermissionUsersBlacklist: true,
+}
+
+func adminRoleToModel(role db.AdminRole) models.AdminRole {
+	ret := models.AdminRole{
+		Name:        role.Name,
+		Description: role.Description,
+		Permissions: make([]models.Permission, 0, len(role.Permissions)),
+		CreatedAt:   role.CreatedAt,
+	}
+	for _, v := range role.Permissions {
+		ret.Permissions = append(ret.Permissions, models.Permission(v))
+	}
+	return ret
+}
+
+// CheckPermission checks if current user has permission. Admins have all permissions.
+func (u *serverImpl) CheckPermission(ctx context.Context, permission models.Permission) error {
+	userID := httputil.MustGetUserID(ctx)
+	u.log.WithField("user_id", userID).WithField("permission", permission).Info("checking user permission")
+	user, err := u.getCachedUser(ctx, userID)
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return cherry.ErrPermissionsError()
+	}
+	if err := u.loginUserChecks(user); err != nil {
+		return err
+	}
+
+	if user.Role == m.RoleAdmin || (user.IsOrgAdmin && orgAdminPermissions[permission]) {
+		return nil
+	}
+
+	permissions, err := u.svc.DB.GetUserPermissions(ctx, user.ID)
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return cherry.ErrPermissionsError()
+	}
+	for _, v := range permissions {
+		if v == string(permission) {
+			return nil
+		}
+	}
+	return cherry.ErrPermissionRequired().AddDetails(string(permission))
+}
+
+// checkAdminTarget checks if current user can manage user account.
+// Only admins can manage admins, organization admins and admin roles holders,
+// users with admin roles are restricted to their organization.
+func (u *serverImpl) checkAdminTarget(ctx context.Context, user *db.User) error {
+	if httputil.MustGetUserRole(ctx) == m.RoleAdmin {
+		return nil
+	}
+	if user.Role == m.RoleAdmin || user.IsOrgAdmin {
+		return cherry.ErrAdminRequired()
+	}
+	roles, err := u.svc.DB.GetUserAdminRoles(ctx, user.ID)
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return cherry.ErrPermissionsError()
+	}
+	if len(roles) > 0 {
+		return cherry.ErrAdminRequired()
+	}
+	return u.checkUserInScope(ctx, user)
+}
+
+// checkAdminRoleEdit checks if current user can set role permissions.
+// Only admins can change roles granted to themselves or add permissions they don't have.
+func (u *serverImpl) checkAdminRoleEdit(ctx context.Context, name string, permissions []models.Permission) error {
+	if httputil.MustGetUserRole(ctx) == m.RoleAdmin {
+		return nil
+	}
+	userID := httputil.MustGetUserID(ctx)
+	roles, err := u.svc.DB.GetUserAdminRoles(ctx, userID)
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return cherry.ErrPermissionsError()
+	}
+	for _, v := range roles {
+		if v == name {
+			return cherry.ErrAdminRoleSelfEdit().AddDetails(name)
+		}
+	}
+	owned, err := u.svc.DB.GetUserPermissions(ctx, userID)
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return cherry.ErrPermissionsError()
+	}
+	ownedSet := make(map[string]bool, len(owned))
+	for _, v := range owned {
+		ownedSet[v] = true
+	}
+	for _, v := range permissions {
+		if !ownedSet[string(v)] {
+			return cherry.ErrPermissionRequired().AddDetails(string(v))
+		}
+	}
+	return nil
+}
+
+func (u *serverImpl) GetAdminRoles(ctx context.Context) (*models.AdminRoles, error) {
+	u.log.Info("get admin roles")
+
+	roles, err := u.svc.DB.GetAdminRoles(ctx)
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return nil, cherry.ErrUnableGetAdminRoles()
+	}
+
+	resp := models.AdminRoles{
+		Roles:            []models.AdminRole{},
+		KnownPermissions: models.Permissions,
+	}
+	for _, v := range roles {
+		resp.Roles = append(resp.Roles, adminRoleToModel(v))
+	}
+	return &resp, nil
+}
+
+func (u *serverImpl) SetAdminRole(ctx context.Context, name string, request models.AdminRoleRequest) (*models.AdminRole, error) {
+	u.log.WithField("role", name).WithField("permissions", request.Permissions).Info("setting admin role")
+
+	if err := u.checkAdminRoleEdit(ctx, name, request.Permissions); err != nil {
+		return nil, err
+	}
+
+	role := &db.AdminRole{
+		Name:        name,
+		Description: request.Description,
+	}
+	for _, v := range request.Permissions {
+		role.Permissions = append(role.Permissions, string(v))
+	}
+
+	err := u.svc.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) error {
+		return tx.SetAdminRole(ctx, role)
+	})
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return nil, cherry.ErrUnableSetAdminRole()
+	}
+
+	ret := adminRoleToModel(*role)
+	return &ret, nil
+}
+
+func (u *serverImpl) DeleteAdminRole(ctx context.Context, name string) error {
+	u.log.WithField("role", name).Info("deleting admin role")
+
+	err := u.svc.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) error {
+		return tx.DeleteAdminRole(ctx, name)
+	})
+	if err == db.ErrAdminRoleNotExist {
+		return cherry.ErrAdminRoleNotExist()
+	}
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return cherry.ErrUnableDeleteAdminRole()
+	}
+	return nil
+}
+
+func (u *serverImpl) GrantAdminRole(ctx context.Context, name, userID string) error {
+	u.log.WithFields(logrus.Fields{
+		"role":    name,
+		"user_id": userID,
+	}).Info("granting admin role")
+
+	role, err := u.svc.DB.GetAdminRole(ctx, name)
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return cherry.ErrUnableGrantAdminRole()
+	}
+	if role == nil {
+		return cherry.ErrAdminRoleNotExist()
+	}
+
+	user, err := u.svc.DB.GetUserByID(ctx, userID)
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return cherry.ErrUnableGrantAdminRole()
+	}
+	if err := u.loginUserChecks(user); err != nil {
+		return err
+	}
+	if user.ID == httputil.MustGetUserID(ctx) {
+		return cherry.ErrAdminRoleSelfGrant()
+	}
+	if err := u.checkAdminTarget(ctx, user); err != nil {
+		return err
+	}
+
+	err = u.svc.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) error {
+		return tx.GrantAdminRole(ctx, user.ID, role.Name)
+	})
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return cherry.ErrUnableGrantAdminRole()
+	}
+	return nil
+}
+
+func (u *serverImpl) RevokeAdminRole(ctx context.Context, name, userID string) error {
+	u.log.WithFields(logrus.Fields{
+		"role":    name,
+		"user_id": userID,
+	}).Info("revoking admin role")
+
+	user, err := u.svc.DB.GetAnyUserByID(ctx, userID)
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return cherry.ErrUnableGrantAdminRole()
+	}
+	if user == nil {
+		return cherry.ErrUserNotExist()
+	}
+	if err := u.checkAdminTarget(ctx, user); err != nil {
+		return err
+	}
+
+	err = u.svc.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) error {
+		return tx.RevokeAdminRole(ctx, user.ID, name)
+	})
+	if err == db.ErrAdminRoleNotGranted {
+		return cherry.ErrAdminRoleNotGranted()
+	}
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return cherry.ErrUnableGrantAdminRole()
+	}
+	return nil
+}
@@ -2,8 +2,10 @@ package impl
 
 import (
 	"context"
+	"time"
 
 	m "git.containerum.net/ch/user-manager/pkg/router/middleware"
+	kube_types "github.com/containerum/kube-client/pkg/model"
 
 	cherry "git.containerum.net/ch/user-manager/pkg/umerrors"
 	"github.com/containerum/utils/httputil"
@@ -12,7 +14,7 @@ import (
 func (u *serverImpl) CheckUserExist(ctx context.Context) error {
 	userID := httputil.MustGetUserID(ctx)
 	u.log.WithField("user_id", userID).Info("checking if user exists")
-	user, err := u.svc.DB.GetUserByID(ctx, userID)
+	user, err := u.getCachedUser(ctx, userID)
 	if err := u.handleDBError(err); err != nil {
 		u.log.WithError(err)
 		return cherry.ErrPermissionsError()
@@ -27,7 +29,7 @@ func (u *serverImpl) CheckUserExist(ctx context.Context) error {
 func (u *serverImpl) CheckAdmin(ctx context.Context) error {
 	userID := httputil.MustGetUserID(ctx)
 	u.log.WithField("user_id", userID).Info("checking if user is admin")
-	user, err := u.svc.DB.GetUserByID(ctx, userID)
+	user, err := u.getCachedUser(ctx, userID)
 	if err := u.handleDBError(err); err != nil {
 		u.log.WithError(err)
 		return cherry.ErrPermissionsError()
@@ -43,3 +45,56 @@ func (u *serverImpl) CheckAdmin(ctx context.Context) error {
 
 	return nil
 }
+
+func (u *serverImpl) CheckService(ctx context.Context) error {
+	userID := httputil.MustGetUserID(ctx)
+	u.log.WithField("user_id", userID).Info("checking if user is service account")
+	user, err := u.getCachedUser(ctx, userID)
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return cherry.ErrPermissionsError()
+	}
+	if err := u.loginUserChecks(user); err != nil {
+		return err
+	}
+
+	if user.Role != m.RoleService && user.Role != m.RoleAdmin {
+		u.log.WithError(cherry.ErrServiceRequired())
+		return cherry.ErrServiceRequired()
+	}
+
+	return nil
+}
+
+// CheckGroupManager checks if current user can manage group: admins, admins of group organization, group owner and members with owner access are allowed.
+func (u *serverImpl) CheckGroupManager(ctx context.Context, group kube_types.UserGroup) error {
+	userID := httputil.MustGetUserID(ctx)
+	u.log.WithField("user_id", userID).WithField("group", group.Label).Info("checking if user can manage group")
+	if httputil.MustGetUserRole(ctx) == m.RoleAdmin || group.OwnerID == userID {
+		return nil
+	}
+
+	user, err := u.getCachedUser(ctx, userID)
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return cherry.ErrPermissionsError()
+	}
+	if user != nil && user.IsOrgAdmin && u.checkUserInGroupOrg(ctx, group.ID, user) == nil {
+		return nil
+	}
+
+	member, err := u.svc.DB.GetGroupMember(ctx, group.ID, userID)
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return cherry.ErrPermissionsError()
+	}
+	// expired membership may be not removed yet
+	if member != nil && member.ExpiresAt.Valid && !member.ExpiresAt.Time.After(time.Now().UTC()) {
+		member = nil
+	}
+	if member == nil || member.Access != string(kube_types.OwnerAccess) {
+		return cherry.ErrNotGroupOwner()
+	}
+
+	return nil
+}
@@ -2,6 +2,8 @@ package impl
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
 	"git.containerum.net/ch/user-manager/pkg/db"
 	"git.containerum.net/ch/user-manager/pkg/models"
@@ -10,29 +12,85 @@ import (
 	"github.com/pkg/errors"
 )
 
-func (u *serverImpl) GetBlacklistedDomain(ctx context.Context, domain string) (*models.Domain, error) {
-	u.log.WithField("domain", domain).Info("get domain info")
-	blacklistedDomain, err := u.svc.DB.GetBlacklistedDomain(ctx, domain)
+func domainRuleFromDB(entry db.DomainBlacklistEntry) models.Domain {
+	ret := models.Domain{
+		Domain:    entry.Domain,
+		Kind:      entry.Kind,
+		List:      entry.List,
+		Reason:    entry.Reason,
+		AddedBy:   entry.AddedBy.String,
+		CreatedAt: entry.CreatedAt,
+	}
+	if entry.ExpiresAt.Valid {
+		ret.ExpiresAt = &entry.ExpiresAt.Time
+	}
+	return ret
+}
+
+// checkEmailDomain rejects registration if email domain matches block rule or, in allowlist mode, doesn't match any allow rule.
+func (u *serverImpl) checkEmailDomain(ctx context.Context, login string) error {
+	domain := strings.ToLower(login[strings.LastIndex(login, "@")+1:])
+
+	blocked, err := u.svc.DB.MatchDomain(ctx, models.DomainListBlock, domain)
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return cherry.ErrUnableCreateUser()
+	}
+	if blocked != nil {
+		u.log.WithError(fmt.Errorf(domainInBlacklist, domain)).WithField("rule", blocked.Domain).Info("registration rejected")
+		return cherry.ErrUnableCreateUser().AddDetailsErr(fmt.Errorf(domainInBlacklist, domain))
+	}
+
+	if !u.cfg.DomainAllowlist {
+		return nil
+	}
+	allowed, err := u.svc.DB.MatchDomain(ctx, models.DomainListAllow, domain)
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return cherry.ErrUnableCreateUser()
+	}
+	if allowed == nil {
+		u.log.WithError(fmt.Errorf(domainNotInAllowlist, domain))
+		return cherry.ErrDomainNotAllowed().AddDetailsErr(fmt.Errorf(domainNotInAllowlist, domain))
+	}
+	return nil
+}
+
+func (u *serverImpl) GetBlacklistedDomain(ctx context.Context, list, domain string) (*models.Domain, error) {
+	u.log.WithField("domain", domain).WithField("list", list).Info("get domain info")
+	if list == "" {
+		list = models.DomainListBlock
+	}
+	blacklistedDomain, err := u.svc.DB.GetBlacklistedDomain(ctx, list, domain)
 	if err := u.handleDBError(err); err != nil {
 		u.log.WithError(err)
 		return nil, cherry.ErrUnableGetDomainBlacklist()
 	}
 
+	if blacklistedDomain == nil {
+		// domain may be covered by suffix or regex rule
+		blacklistedDomain, err = u.svc.DB.MatchDomain(ctx, list, domain)
+		if err := u.handleDBError(err); err != nil {
+			u.log.WithError(err)
+			return nil, cherry.ErrUnableGetDomainBlacklist()
+		}
+	}
+
 	if blacklistedDomain == nil {
 		u.log.WithError(errors.New("dom not blacklist"))
 		return nil, cherry.ErrDomainNotBlacklisted()
 	}
 
-	return &models.Domain{
-		Domain:    blacklistedDomain.Domain,
-		AddedBy:   blacklistedDomain.AddedBy.String,
-		CreatedAt: blacklistedDomain.CreatedAt,
-	}, nil
+	resp := domainRuleFromDB(*blacklistedDomain)
+	return &resp, nil
 }
 
-func (u *serverImpl) GetBlacklistedDomainsList(ctx context.Context) (*models.DomainListResponse, error) {
-	u.log.Info("get domains list")
-	blacklistedDomains, err := u.svc.DB.GetBlacklistedDomainsList(ctx)
+func (u *serverImpl) GetBlacklistedDomainsList(ctx context.Context, list string) (*models.DomainListResponse, error) {
+	u.log.WithField("list", list).Info("get domains list")
+	if list == "" {
+		list = models.DomainListBlock
+	}
+	blacklistedDomains, err := u.svc.DB.GetBlacklistedDomainsList(ctx, list)
 	if err := u.handleDBError(err); err != nil {
 		u.log.WithError(err)
 		return nil, cherry.ErrUnableGetDomainBlacklist()
@@ -42,11 +100,7 @@ func (u *serverImpl) GetBlacklistedDomainsList(ctx context.Context) (*models.Dom
 		DomainList: []models.Domain{},
 	}
 	for _, v := range blacklistedDomains {
-		resp.DomainList = append(resp.DomainList, models.Domain{
-			Domain:    v.Domain,
-			AddedBy:   v.AddedBy.String,
-			CreatedAt: v.CreatedAt,
-		})
+		resp.DomainList = append(resp.DomainList, domainRuleFromDB(v))
 	}
 
 	return &resp, nil
@@ -57,8 +111,9 @@ func (u *serverImpl) AddDomainToBlacklist(ctx context.Context, request models.Do
 
 	userID := httputil.MustGetUserID(ctx)
 
+	entry := db.NewDomainRule(request.Domain, request.Kind, request.List, request.Reason, userID, request.ExpiresAt)
 	err := u.svc.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) error {
-		return tx.BlacklistDomain(ctx, request.Domain, userID)
+		return tx.BlacklistDomain(ctx, entry)
 	})
 	if err := u.handleDBError(err); err != nil {
 		u.log.WithError(err)
@@ -68,11 +123,40 @@ func (u *serverImpl) AddDomainToBlacklist(ctx context.Context, request models.Do
 	return nil
 }
 
-func (u *serverImpl) RemoveDomainFromBlacklist(ctx context.Context, domain string) error {
-	u.log.WithField("domain", domain).Info("removing domain from blacklist")
+func (u *serverImpl) ImportDomains(ctx context.Context, request models.DomainImportRequest) (*models.DomainImportResponse, error) {
+	u.log.WithField("domains", len(request.Domains)).Info("importing domains")
+
+	userID := httputil.MustGetUserID(ctx)
+
+	entries := make([]db.DomainBlacklistEntry, 0, len(request.Domains))
+	for _, domain := range request.Domains {
+		entries = append(entries, db.NewDomainRule(domain, request.Kind, request.List, request.Reason, userID, request.ExpiresAt))
+	}
+
+	var imported int
+	err := u.svc.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) (err error) {
+		imported, err = tx.ImportDomains(ctx, entries)
+		return err
+	})
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return nil, cherry.ErrUnableImportDomains()
+	}
+
+	return &models.DomainImportResponse{
+		Imported: imported,
+		Skipped:  len(entries) - imported,
+	}, nil
+}
+
+func (u *serverImpl) RemoveDomainFromBlacklist(ctx context.Context, list, domain string) error {
+	u.log.WithField("domain", domain).WithField("list", list).Info("removing domain from blacklist")
+	if list == "" {
+		list = models.DomainListBlock
+	}
 
 	err := u.svc.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) error {
-		return tx.UnBlacklistDomain(ctx, domain)
+		return tx.UnBlacklistDomain(ctx, list, domain)
 	})
 	if err := u.handleDBError(err); err != nil {
 		u.log.WithError(err)
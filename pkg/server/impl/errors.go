@@ -4,8 +4,12 @@ const (
 	waitForResend           = "Can`t resend link now, please wait %d seconds"
 	resourceAccessGetFailed = "Resource access get failed"
 	domainInBlacklist       = "Email domain %s is in blacklist"
+	domainNotInAllowlist    = "Email domain %s is not in allowlist"
+	ipBlocked               = "IP address %s is blocked"
+	ipNotInAllowlist        = "IP address %s is not in allowlist"
 	blacklistYourself       = "You can't blacklist yourself"
 	blacklistAdmin          = "You can't blacklist admin"
 	linkNotFound            = "Link %s was not found or already used or expired"
 	resourceNotSupported    = "Resource %s is not supported"
+	restorePeriodExpired    = "Account was deleted more than %v ago"
 )
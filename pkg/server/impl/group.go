@@ -8,11 +8,61 @@ import (
 
 	"git.containerum.net/ch/user-manager/pkg/db"
 	"git.containerum.net/ch/user-manager/pkg/models"
+	m "git.containerum.net/ch/user-manager/pkg/router/middleware"
 	cherry "git.containerum.net/ch/user-manager/pkg/umerrors"
 	kube_types "github.com/containerum/kube-client/pkg/model"
 	"github.com/containerum/utils/httputil"
+	"github.com/lib/pq"
 )
 
+func groupToModel(group db.UserGroup) models.UserGroup {
+	ret := models.UserGroup{
+		UserGroup: kube_types.UserGroup{
+			ID:         group.ID,
+			Label:      group.Label,
+			OwnerID:    group.OwnerID,
+			OwnerLogin: group.OwnerLogin,
+			CreatedAt:  group.CreatedAt.Time.Format(time.RFC3339),
+		},
+		Description: group.Description,
+		Labels:      group.Labels,
+		OrgID:       group.OrgID,
+	}
+	if group.UpdatedAt.Valid {
+		ret.UpdatedAt = group.UpdatedAt.Time.Format(time.RFC3339)
+	}
+	return ret
+}
+
+// expiresIn returns number of seconds left until t.
+func expiresIn(t time.Time) *int64 {
+	left := int64(time.Until(t).Seconds())
+	if left < 0 {
+		left = 0
+	}
+	return &left
+}
+
+// setGroupMembers fills both kube-client members list used for permission checks and members list with expiration info.
+func setGroupMembers(group *models.UserGroup, members []db.UserGroupMember) {
+	group.UserGroupMembers = &kube_types.UserGroupMembers{Members: make([]kube_types.UserGroupMember, 0)}
+	group.Members = make([]models.GroupMember, 0)
+	for _, member := range members {
+		kubeMember := kube_types.UserGroupMember{
+			Username: member.Login,
+			ID:       member.UserID,
+			Access:   kube_types.AccessLevel(member.Access),
+		}
+		group.UserGroupMembers.Members = append(group.UserGroupMembers.Members, kubeMember)
+		groupMember := models.GroupMember{UserGroupMember: kubeMember}
+		if member.ExpiresAt.Valid {
+			groupMember.ExpiresAt = &member.ExpiresAt.Time
+			groupMember.ExpiresIn = expiresIn(member.ExpiresAt.Time)
+		}
+		group.Members = append(group.Members, groupMember)
+	}
+}
+
 func (u *serverImpl) CreateGroup(ctx context.Context, request kube_types.UserGroup) (*string, error) {
 	u.log.WithField("label", request.Label).Info("creating group")
 
@@ -34,6 +84,7 @@ func (u *serverImpl) CreateGroup(ctx context.Context, request kube_types.UserGro
 	}
 
 	newGroup.OwnerLogin = usr.Login
+	newGroup.OrgID = usr.OrgID
 
 	err = u.svc.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) error {
 		return tx.CreateGroup(ctx, newGroup)
@@ -58,7 +109,11 @@ func (u *serverImpl) CreateGroup(ctx context.Context, request kube_types.UserGro
 	}
 
 	if request.UserGroupMembers != nil {
-		if err := u.AddGroupMembers(ctx, newGroup.Label, *request.UserGroupMembers); err != nil {
+		members := models.GroupMembers{}
+		for _, member := range request.UserGroupMembers.Members {
+			members.Members = append(members.Members, models.GroupMember{UserGroupMember: member})
+		}
+		if err := u.AddGroupMembers(ctx, newGroup.Label, members); err != nil {
 			u.log.WithError(err).Warnln("Unable to add group member")
 		}
 	}
@@ -70,7 +125,7 @@ func (u *serverImpl) CreateGroup(ctx context.Context, request kube_types.UserGro
 	return &newGroup.ID, nil
 }
 
-func (u *serverImpl) AddGroupMembers(ctx context.Context, groupLabel string, request kube_types.UserGroupMembers) error {
+func (u *serverImpl) AddGroupMembers(ctx context.Context, groupLabel string, request models.GroupMembers) error {
 	u.log.WithField("groupLabel", groupLabel).Info("adding group members")
 	group, err := u.svc.DB.GetGroupByLabel(ctx, groupLabel)
 	if err != nil {
@@ -105,11 +160,20 @@ func (u *serverImpl) AddGroupMembers(ctx context.Context, groupLabel string, req
 			continue
 		}
 
+		if usr.OrgID != group.OrgID {
+			u.log.WithError(cherry.ErrUserNotInOrganization().AddDetails(member.Username))
+			errs = append(errs, cherry.ErrUserNotInOrganization().AddDetails(member.Username))
+			continue
+		}
+
 		newGroupMember := &db.UserGroupMember{
 			UserID:  usr.ID,
 			GroupID: group.ID,
 			Access:  string(member.Access),
 		}
+		if member.ExpiresAt != nil {
+			newGroupMember.ExpiresAt = pq.NullTime{Time: member.ExpiresAt.UTC(), Valid: true}
+		}
 
 		err = u.svc.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) error {
 			return tx.AddGroupMembers(ctx, newGroupMember)
@@ -131,7 +195,7 @@ func (u *serverImpl) AddGroupMembers(ctx context.Context, groupLabel string, req
 	return nil
 }
 
-func (u *serverImpl) GetGroupByID(ctx context.Context, groupID string) (*kube_types.UserGroup, error) {
+func (u *serverImpl) GetGroupByID(ctx context.Context, groupID string) (*models.UserGroup, error) {
 	u.log.WithField("groupID", groupID).Info("getting group")
 
 	group, err := u.svc.DB.GetGroupByID(ctx, groupID)
@@ -144,13 +208,7 @@ func (u *serverImpl) GetGroupByID(ctx context.Context, groupID string) (*kube_ty
 		return nil, cherry.ErrGroupNotExist()
 	}
 
-	ret := kube_types.UserGroup{
-		ID:         group.ID,
-		Label:      group.Label,
-		OwnerID:    group.OwnerID,
-		OwnerLogin: group.OwnerLogin,
-		CreatedAt:  group.CreatedAt.Time.Format(time.RFC3339),
-	}
+	ret := groupToModel(*group)
 
 	members, err := u.svc.DB.GetGroupMembers(ctx, group.ID)
 	if err != nil {
@@ -158,18 +216,11 @@ func (u *serverImpl) GetGroupByID(ctx context.Context, groupID string) (*kube_ty
 		return nil, cherry.ErrUnableGetGroup()
 	}
 
-	ret.UserGroupMembers = &kube_types.UserGroupMembers{Members: make([]kube_types.UserGroupMember, 0)}
-	for _, member := range members {
-		ret.Members = append(ret.Members, kube_types.UserGroupMember{
-			Username: member.Login,
-			ID:       member.UserID,
-			Access:   kube_types.AccessLevel(member.Access),
-		})
-	}
+	setGroupMembers(&ret, members)
 	return &ret, nil
 }
 
-func (u *serverImpl) GetGroupByLabel(ctx context.Context, groupLabel string) (*kube_types.UserGroup, error) {
+func (u *serverImpl) GetGroupByLabel(ctx context.Context, groupLabel string) (*models.UserGroup, error) {
 	u.log.WithField("groupLabel", groupLabel).Info("getting group")
 
 	group, err := u.svc.DB.GetGroupByLabel(ctx, groupLabel)
@@ -182,13 +233,7 @@ func (u *serverImpl) GetGroupByLabel(ctx context.Context, groupLabel string) (*k
 		return nil, cherry.ErrGroupNotExist()
 	}
 
-	ret := kube_types.UserGroup{
-		ID:         group.ID,
-		Label:      group.Label,
-		OwnerID:    group.OwnerID,
-		OwnerLogin: group.OwnerLogin,
-		CreatedAt:  group.CreatedAt.Time.Format(time.RFC3339),
-	}
+	ret := groupToModel(*group)
 
 	members, err := u.svc.DB.GetGroupMembers(ctx, group.ID)
 	if err != nil {
@@ -196,18 +241,11 @@ func (u *serverImpl) GetGroupByLabel(ctx context.Context, groupLabel string) (*k
 		return nil, cherry.ErrUnableGetGroup()
 	}
 
-	ret.UserGroupMembers = &kube_types.UserGroupMembers{Members: make([]kube_types.UserGroupMember, 0)}
-	for _, member := range members {
-		ret.Members = append(ret.Members, kube_types.UserGroupMember{
-			Username: member.Login,
-			ID:       member.UserID,
-			Access:   kube_types.AccessLevel(member.Access),
-		})
-	}
+	setGroupMembers(&ret, members)
 	return &ret, nil
 }
 
-func (u *serverImpl) GetGroupsList(ctx context.Context, userID string) (*kube_types.UserGroups, error) {
+func (u *serverImpl) GetGroupsList(ctx context.Context, userID string) (*models.UserGroups, error) {
 	role := httputil.MustGetUserRole(ctx)
 	u.log.WithField("userID", userID).Info("getting groups list")
 
@@ -217,7 +255,28 @@ func (u *serverImpl) GetGroupsList(ctx context.Context, userID string) (*kube_ty
 		return nil, cherry.ErrUnableGetGroup()
 	}
 
-	groups := make([]kube_types.UserGroup, 0)
+	if role != "admin" {
+		user, err := u.svc.DB.GetUserByID(ctx, userID)
+		if err != nil {
+			u.log.WithError(err)
+			return nil, cherry.ErrUnableGetGroup()
+		}
+		// organization admins manage all groups of organization
+		if user != nil && user.IsOrgAdmin {
+			orgGroups, err := u.svc.DB.GetOrganizationGroups(ctx, user.OrgID)
+			if err != nil {
+				u.log.WithError(err)
+				return nil, cherry.ErrUnableGetGroup()
+			}
+			for _, group := range orgGroups {
+				if _, ok := groupsLabels[group.Label]; !ok {
+					groupsLabels[group.Label] = db.GroupAccess{Access: string(kube_types.OwnerAccess)}
+				}
+			}
+		}
+	}
+
+	groups := make([]models.UserGroup, 0)
 	for gr, perm := range groupsLabels {
 		group, err := u.svc.DB.GetGroupByLabel(ctx, gr)
 		if err != nil {
@@ -233,18 +292,17 @@ func (u *serverImpl) GetGroupsList(ctx context.Context, userID string) (*kube_ty
 			u.log.WithError(err)
 			return nil, cherry.ErrUnableGetGroup()
 		}
-		userGroup := kube_types.UserGroup{
-			UserAccess:   kube_types.AccessLevel(perm),
-			ID:           group.ID,
-			Label:        group.Label,
-			OwnerID:      group.OwnerID,
-			OwnerLogin:   group.OwnerLogin,
-			CreatedAt:    group.CreatedAt.Time.Format(time.RFC3339),
-			MembersCount: *membersCount,
+		userGroup := groupToModel(*group)
+		userGroup.UserAccess = kube_types.AccessLevel(perm.Access)
+		userGroup.MembersCount = *membersCount
+		userGroup.Inherited = perm.Inherited
+		if perm.ExpiresAt.Valid {
+			userGroup.ExpiresAt = &perm.ExpiresAt.Time
+			userGroup.ExpiresIn = expiresIn(perm.ExpiresAt.Time)
 		}
 		groups = append(groups, userGroup)
 	}
-	return &kube_types.UserGroups{Groups: groups}, nil
+	return &models.UserGroups{Groups: groups}, nil
 }
 
 func (u *serverImpl) DeleteGroupMember(ctx context.Context, group kube_types.UserGroup, username string) error {
@@ -282,7 +340,7 @@ func (u *serverImpl) DeleteGroupMember(ctx context.Context, group kube_types.Use
 	return nil
 }
 
-func (u *serverImpl) UpdateGroupMemberAccess(ctx context.Context, group kube_types.UserGroup, username, access string) error {
+func (u *serverImpl) UpdateGroupMemberAccess(ctx context.Context, group kube_types.UserGroup, username, access string, expiresAt *time.Time, removeExpiration bool) error {
 	u.log.WithField("groupID", group.ID).WithField("username", username).WithField("access", access).Info("updating group member access")
 
 	usr, err := u.svc.DB.GetUserByLogin(ctx, username)
@@ -300,7 +358,21 @@ func (u *serverImpl) UpdateGroupMemberAccess(ctx context.Context, group kube_typ
 	}
 
 	err = u.svc.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) error {
-		return tx.UpdateGroupMember(ctx, usr.ID, group.ID, access)
+		member, err := tx.GetGroupMember(ctx, group.ID, usr.ID)
+		if err != nil {
+			return err
+		}
+		if member == nil {
+			return errors.New("user is not in this group")
+		}
+		// keep current expiration unless it was changed or removed explicitly
+		expires := member.ExpiresAt
+		if expiresAt != nil {
+			expires = pq.NullTime{Time: expiresAt.UTC(), Valid: true}
+		} else if removeExpiration {
+			expires = pq.NullTime{}
+		}
+		return tx.UpdateGroupMember(ctx, usr.ID, group.ID, access, expires)
 	})
 	if err := u.handleDBError(err); err != nil {
 		u.log.WithError(err)
@@ -312,6 +384,104 @@ func (u *serverImpl) UpdateGroupMemberAccess(ctx context.Context, group kube_typ
 	return nil
 }
 
+func (u *serverImpl) TransferGroupOwnership(ctx context.Context, group kube_types.UserGroup, newOwnerLogin string) error {
+	u.log.WithField("groupID", group.ID).WithField("new_owner", newOwnerLogin).Info("transferring group ownership")
+
+	usr, err := u.svc.DB.GetUserByLogin(ctx, newOwnerLogin)
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return cherry.ErrUnableTransferGroupOwnership()
+	}
+	if err := u.loginUserChecks(usr); err != nil {
+		return err
+	}
+	if !usr.IsActive {
+		return cherry.ErrNotActivated().AddDetails(newOwnerLogin)
+	}
+	if usr.Role == m.RoleAdmin {
+		return cherry.ErrAddAdminGroup()
+	}
+	if usr.ID == group.OwnerID {
+		return nil
+	}
+	if err := u.checkUserInGroupOrg(ctx, group.ID, usr); err != nil {
+		return err
+	}
+
+	member, err := u.svc.DB.GetGroupMember(ctx, group.ID, usr.ID)
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return cherry.ErrUnableTransferGroupOwnership()
+	}
+
+	err = u.svc.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) error {
+		// previous owner stays in group with highest non-owner access
+		return tx.TransferGroupOwnership(ctx, group.ID, usr, string(kube_types.AdminAccess))
+	})
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return cherry.ErrUnableTransferGroupOwnership()
+	}
+
+	if member == nil {
+		if err := u.svc.EventsClient.UserAddedToGroup(ctx, usr.Login, group.Label); err != nil {
+			u.log.WithError(err).Warnln("Unable to add event")
+		}
+	}
+	return nil
+}
+
+func (u *serverImpl) UpdateGroup(ctx context.Context, group kube_types.UserGroup, request models.GroupUpdateRequest) (*models.UserGroup, error) {
+	u.log.WithField("groupID", group.ID).Info("updating group")
+
+	dbGroup, err := u.svc.DB.GetGroupByID(ctx, group.ID)
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return nil, cherry.ErrUpdateGroup()
+	}
+	if dbGroup == nil {
+		return nil, cherry.ErrGroupNotExist()
+	}
+
+	oldLabel := dbGroup.Label
+	if request.Label != nil && *request.Label != dbGroup.Label {
+		existing, err := u.svc.DB.GetGroupByLabel(ctx, *request.Label)
+		if err := u.handleDBError(err); err != nil {
+			u.log.WithError(err)
+			return nil, cherry.ErrUpdateGroup()
+		}
+		if existing != nil {
+			return nil, cherry.ErrGroupAlreadyExist().AddDetails(*request.Label)
+		}
+		dbGroup.Label = *request.Label
+	}
+	if request.Description != nil {
+		dbGroup.Description = *request.Description
+	}
+	if request.Labels != nil {
+		dbGroup.Labels = request.Labels
+	}
+
+	err = u.svc.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) error {
+		return tx.UpdateGroup(ctx, dbGroup)
+	})
+	if pqerr, ok := err.(*pq.Error); ok && pqerr.Constraint == "unique_label" {
+		// group with same label was created concurrently
+		return nil, cherry.ErrGroupAlreadyExist().AddDetails(dbGroup.Label)
+	}
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return nil, cherry.ErrUpdateGroup()
+	}
+
+	if err := u.svc.EventsClient.GroupUpdated(ctx, oldLabel, dbGroup.Label); err != nil {
+		u.log.WithError(err).Warnln("Unable to add event")
+	}
+
+	ret := groupToModel(*dbGroup)
+	return &ret, nil
+}
+
 func (u *serverImpl) DeleteGroup(ctx context.Context, group kube_types.UserGroup) error {
 	u.log.WithField("groupLabel", group.Label).Info("deleting group")
 
@@ -345,7 +515,7 @@ func (u *serverImpl) GetGroupListLabelID(ctx context.Context, ids []string) (*mo
 	return &resp, nil
 }
 
-func (u *serverImpl) GetGroupListByIDs(ctx context.Context, ids []string) (*kube_types.UserGroups, error) {
+func (u *serverImpl) GetGroupListByIDs(ctx context.Context, ids []string) (*models.UserGroups, error) {
 	u.log.Info("get groups list by ids")
 	groups, err := u.svc.DB.GetGroupListByIDs(ctx, ids)
 	if err := u.handleDBError(err); err != nil {
@@ -353,16 +523,10 @@ func (u *serverImpl) GetGroupListByIDs(ctx context.Context, ids []string) (*kube
 		return nil, cherry.ErrUnableGetUsersList()
 	}
 
-	resp := make([]kube_types.UserGroup, 0)
+	resp := make([]models.UserGroup, 0)
 
 	for _, v := range groups {
-		group := kube_types.UserGroup{
-			ID:         v.ID,
-			Label:      v.Label,
-			OwnerID:    v.OwnerID,
-			OwnerLogin: v.OwnerLogin,
-			CreatedAt:  v.CreatedAt.Time.Format(time.RFC3339),
-		}
+		group := groupToModel(v)
 
 		members, err := u.svc.DB.GetGroupMembers(ctx, v.ID)
 		if err != nil {
@@ -370,17 +534,10 @@ func (u *serverImpl) GetGroupListByIDs(ctx context.Context, ids []string) (*kube
 			return nil, cherry.ErrUnableGetGroup()
 		}
 
-		group.UserGroupMembers = &kube_types.UserGroupMembers{Members: make([]kube_types.UserGroupMember, 0)}
-		for _, member := range members {
-			group.Members = append(group.Members, kube_types.UserGroupMember{
-				Username: member.Login,
-				ID:       member.UserID,
-				Access:   kube_types.AccessLevel(member.Access),
-			})
-		}
+		setGroupMembers(&group, members)
 
 		resp = append(resp, group)
 	}
 
-	return &kube_types.UserGroups{Groups: resp}, nil
+	return &models.UserGroups{Groups: resp}, nil
 }
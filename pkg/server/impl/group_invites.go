@@ -0,0 +1,304 @@
+package impl
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	mttypes "git.containerum.net/ch/mail-templater/pkg/models"
+	"git.containerum.net/ch/user-manager/pkg/db"
+	"git.containerum.net/ch/user-manager/pkg/models"
+	m "git.containerum.net/ch/user-manager/pkg/router/middleware"
+	cherry "git.containerum.net/ch/user-manager/pkg/umerrors"
+	"git.containerum.net/ch/user-manager/pkg/utils"
+	kube_types "github.com/containerum/kube-client/pkg/model"
+	"github.com/containerum/utils/httputil"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	groupInviteTokenType  = "group_invite"
+	defaultInviteLifetime = 7 * 24 * time.Hour
+)
+
+func groupInviteToModel(invite db.GroupInvite) models.GroupInvite {
+	return models.GroupInvite{
+		ID:         invite.ID,
+		GroupID:    invite.GroupID,
+		GroupLabel: invite.GroupLabel,
+		Email:      invite.Email,
+		Access:     invite.Access,
+		InvitedBy:  invite.InvitedBy,
+		CreatedAt:  invite.CreatedAt,
+		ExpiresAt:  invite.ExpiresAt,
+	}
+}
+
+func (u *serverImpl) inviteLifetime() time.Duration {
+	if u.cfg.InviteLifetime > 0 {
+		return u.cfg.InviteLifetime
+	}
+	return defaultInviteLifetime
+}
+
+// getInviteByToken verifies invite token and returns invite it was issued for.
+func (u *serverImpl) getInviteByToken(ctx context.Context, token string) (*db.GroupInvite, error) {
+	if u.cfg.InviteKeyring == nil {
+		return nil, cherry.ErrInvalidGroupInvite()
+	}
+	claims, err := u.cfg.InviteKeyring.Verify(token)
+	if err != nil {
+		u.log.WithError(err).Info("invalid group invite token")
+		return nil, cherry.ErrInvalidGroupInvite()
+	}
+	if claims.Type != groupInviteTokenType {
+		return nil, cherry.ErrInvalidGroupInvite()
+	}
+
+	invite, err := u.svc.DB.GetGroupInvite(ctx, claims.UserID)
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return nil, cherry.ErrUnableAcceptGroupInvite()
+	}
+	// invite may be renewed after token was issued, so token becomes outdated
+	if invite == nil || invite.CreatedAt.Unix() != claims.IssuedAt.Unix() || !time.Now().UTC().Before(invite.ExpiresAt) {
+		return nil, cherry.ErrInvalidGroupInvite()
+	}
+	return invite, nil
+}
+
+// applyGroupInvite adds user to invite group and removes invite. Must be called inside transaction.
+func (u *serverImpl) applyGroupInvite(ctx context.Context, tx db.DB, invite db.GroupInvite, user *db.User) (added bool, err error) {
+	member, err := tx.GetGroupMember(ctx, invite.GroupID, user.ID)
+	if err != nil {
+		return false, err
+	}
+	if member == nil {
+		if err := tx.AddGroupMembers(ctx, &db.UserGroupMember{
+			UserID:  user.ID,
+			GroupID: invite.GroupID,
+			Access:  invite.Access,
+		}); err != nil {
+			return false, err
+		}
+	}
+	return member == nil, tx.DeleteGroupInvite(ctx, invite.ID)
+}
+
+// applyPendingGroupInvites adds user to all groups he was invited to by email.
+// It is called when user confirms his email, so errors are only logged.
+func (u *serverImpl) applyPendingGroupInvites(ctx context.Context, user *db.User) {
+	if user.Role == m.RoleAdmin {
+		return
+	}
+	invites, err := u.svc.DB.GetPendingGroupInvites(ctx, strings.ToLower(user.Login))
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err).Warnln("Unable to get pending group invites")
+		return
+	}
+	for _, invite := range invites {
+		if err := u.checkUserInGroupOrg(ctx, invite.GroupID, user); err != nil {
+			u.log.WithError(err).WithField("invite_id", invite.ID).Warnln("Group invite is not applicable")
+			continue
+		}
+		var added bool
+		err := u.svc.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) (err error) {
+			added, err = u.applyGroupInvite(ctx, tx, invite, user)
+			return err
+		})
+		if err := u.handleDBError(err); err != nil {
+			u.log.WithError(err).WithField("invite_id", invite.ID).Warnln("Unable to apply group invite")
+			continue
+		}
+		if added {
+			if err := u.svc.EventsClient.UserAddedToGroup(ctx, user.Login, invite.GroupLabel); err != nil {
+				u.log.WithError(err).Warnln("Unable to add event")
+			}
+		}
+	}
+}
+
+func (u *serverImpl) CreateGroupInvite(ctx context.Context, group kube_types.UserGroup, request models.GroupInviteRequest) (*models.GroupInvite, error) {
+	email := strings.ToLower(request.Email)
+	u.log.WithFields(logrus.Fields{
+		"group_id": group.ID,
+		"email":    email,
+		"access":   request.Access,
+	}).Info("inviting user to group")
+
+	if u.cfg.InviteKeyring == nil {
+		u.log.Error("group invite signing keys not configured")
+		return nil, cherry.ErrUnableCreateGroupInvite()
+	}
+
+	usr, err := u.svc.DB.GetAnyUserByLogin(ctx, email)
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return nil, cherry.ErrUnableCreateGroupInvite()
+	}
+	if usr != nil {
+		if err := u.loginUserChecks(usr); err != nil {
+			return nil, err
+		}
+		// registered users should be added to group directly
+		if usr.IsActive {
+			return nil, cherry.ErrUserAlreadyExists().AddDetails(email)
+		}
+		if err := u.checkUserInGroupOrg(ctx, group.ID, usr); err != nil {
+			return nil, err
+		}
+	} else {
+		// new users are registered in default organization
+		if err := u.checkUserInGroupOrg(ctx, group.ID, &db.User{Login: email, OrgID: db.DefaultOrganizationID}); err != nil {
+			return nil, err
+		}
+	}
+
+	inviter, err := u.svc.DB.GetUserByID(ctx, httputil.MustGetUserID(ctx))
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return nil, cherry.ErrUnableCreateGroupInvite()
+	}
+	if err := u.loginUserChecks(inviter); err != nil {
+		return nil, err
+	}
+
+	invite := &db.GroupInvite{
+		GroupID:    group.ID,
+		GroupLabel: group.Label,
+		Email:      email,
+		Access:     request.Access,
+		InvitedBy:  inviter.ID,
+		ExpiresAt:  time.Now().UTC().Add(u.inviteLifetime()),
+	}
+	err = u.svc.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) error {
+		if createErr := tx.CreateGroupInvite(ctx, invite); createErr != nil {
+			return createErr
+		}
+		token := u.cfg.InviteKeyring.Sign(utils.LinkClaims{
+			UserID:    invite.ID,
+			Type:      groupInviteTokenType,
+			IssuedAt:  invite.CreatedAt,
+			ExpiresAt: invite.ExpiresAt,
+		})
+		return u.svc.MailClient.SendGroupInviteMail(ctx, &mttypes.Recipient{
+			Name:  email,
+			Email: email,
+			Variables: map[string]interface{}{
+				"TOKEN":   token,
+				"GROUP":   group.Label,
+				"ACCESS":  request.Access,
+				"INVITER": inviter.Login,
+			},
+		})
+	})
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return nil, cherry.ErrUnableCreateGroupInvite()
+	}
+
+	ret := groupInviteToModel(*invite)
+	return &ret, nil
+}
+
+func (u *serverImpl) GetGroupInvites(ctx context.Context, group kube_types.UserGroup) (*models.GroupInvites, error) {
+	u.log.WithField("group_id", group.ID).Info("get group invites")
+
+	invites, err := u.svc.DB.GetGroupInvites(ctx, group.ID)
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return nil, cherry.ErrUnableGetGroupInvites()
+	}
+
+	resp := models.GroupInvites{Invites: []models.GroupInvite{}}
+	for _, v := range invites {
+		resp.Invites = append(resp.Invites, groupInviteToModel(v))
+	}
+	return &resp, nil
+}
+
+func (u *serverImpl) DeleteGroupInvite(ctx context.Context, group kube_types.UserGroup, inviteID string) error {
+	u.log.WithField("group_id", group.ID).WithField("invite_id", inviteID).Info("deleting group invite")
+
+	invite, err := u.svc.DB.GetGroupInvite(ctx, inviteID)
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return cherry.ErrUnableDeleteGroupInvite()
+	}
+	if invite == nil || invite.GroupID != group.ID {
+		return cherry.ErrGroupInviteNotExist()
+	}
+
+	err = u.svc.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) error {
+		return tx.DeleteGroupInvite(ctx, invite.ID)
+	})
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return cherry.ErrUnableDeleteGroupInvite()
+	}
+	return nil
+}
+
+func (u *serverImpl) AcceptGroupInvite(ctx context.Context, request models.GroupInviteToken) error {
+	userID := httputil.MustGetUserID(ctx)
+	u.log.WithField("user_id", userID).Info("accepting group invite")
+
+	invite, err := u.getInviteByToken(ctx, request.Token)
+	if err != nil {
+		return err
+	}
+
+	user, err := u.svc.DB.GetUserByID(ctx, userID)
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return cherry.ErrUnableAcceptGroupInvite()
+	}
+	if err := u.loginUserChecks(user); err != nil {
+		return err
+	}
+	// invite token may be forwarded, so only user with invited email can accept it
+	if strings.ToLower(user.Login) != invite.Email {
+		return cherry.ErrInvalidGroupInvite()
+	}
+	if user.Role == m.RoleAdmin {
+		return cherry.ErrAddAdminGroup()
+	}
+	if err := u.checkUserInGroupOrg(ctx, invite.GroupID, user); err != nil {
+		return err
+	}
+
+	var added bool
+	err = u.svc.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) (err error) {
+		added, err = u.applyGroupInvite(ctx, tx, *invite, user)
+		return err
+	})
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return cherry.ErrUnableAcceptGroupInvite()
+	}
+
+	if added {
+		if err := u.svc.EventsClient.UserAddedToGroup(ctx, user.Login, invite.GroupLabel); err != nil {
+			u.log.WithError(err).Warnln("Unable to add event")
+		}
+	}
+	return nil
+}
+
+func (u *serverImpl) DeclineGroupInvite(ctx context.Context, request models.GroupInviteToken) error {
+	u.log.Info("declining group invite")
+
+	invite, err := u.getInviteByToken(ctx, request.Token)
+	if err != nil {
+		return err
+	}
+
+	err = u.svc.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) error {
+		return tx.DeleteGroupInvite(ctx, invite.ID)
+	})
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return cherry.ErrUnableDeleteGroupInvite()
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package impl
+
+import (
+	"context"
+	"math"
+
+	"git.containerum.net/ch/user-manager/pkg/db"
+	"git.containerum.net/ch/user-manager/pkg/models"
+	cherry "git.containerum.net/ch/user-manager/pkg/umerrors"
+	kube_types "github.com/containerum/kube-client/pkg/model"
+	"github.com/sirupsen/logrus"
+)
+
+func pagesCount(total, perPage uint) uint {
+	return uint(math.Ceil(float64(total) / float64(perPage)))
+}
+
+func (u *serverImpl) GetGroupMembersPage(ctx context.Context, groupLabel string, page, perPage uint, filter models.GroupMembersFilter) (*models.GroupMembersPage, error) {
+	u.log.WithFields(logrus.Fields{
+		"groupLabel": groupLabel,
+		"page":       page,
+		"per_page":   perPage,
+	}).Info("get group members")
+
+	group, err := u.svc.DB.GetGroupByLabel(ctx, groupLabel)
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return nil, cherry.ErrUnableGetGroupMembers()
+	}
+	if group == nil {
+		return nil, cherry.ErrGroupNotExist()
+	}
+	if err := u.CheckGroupManager(ctx, groupToModel(*group).UserGroup); err != nil {
+		return nil, err
+	}
+
+	members, total, err := u.svc.DB.GetGroupMembersPage(ctx, group.ID, db.GroupMembersFilter{
+		Access: filter.Access,
+		Login:  filter.Login,
+	}, perPage, (page-1)*perPage)
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return nil, cherry.ErrUnableGetGroupMembers()
+	}
+
+	resp := models.GroupMembersPage{
+		Members: []models.GroupMemberInfo{},
+		Total:   total,
+		Pages:   pagesCount(total, perPage),
+	}
+	for _, v := range members {
+		member := models.GroupMemberInfo{
+			GroupMember: models.GroupMember{
+				UserGroupMember: kube_types.UserGroupMember{
+					ID:       v.UserID,
+					Username: v.Login,
+					Access:   kube_types.AccessLevel(v.Access),
+				},
+			},
+			IsActive:      v.IsActive,
+			IsInBlacklist: v.IsInBlacklist,
+		}
+		if v.ExpiresAt.Valid {
+			member.ExpiresAt = &v.ExpiresAt.Time
+			member.ExpiresIn = expiresIn(v.ExpiresAt.Time)
+		}
+		if v.AddedAt.Valid {
+			member.AddedAt = &v.AddedAt.Time
+		}
+		if v.LastLogin.Valid {
+			member.LastLogin = &v.LastLogin.Time
+		}
+		resp.Members = append(resp.Members, member)
+	}
+	return &resp, nil
+}
+
+func (u *serverImpl) GetUserGroupMemberships(ctx context.Context, userID string, page, perPage uint) (*models.UserGroupMemberships, error) {
+	u.log.WithFields(logrus.Fields{
+		"user_id":  userID,
+		"page":     page,
+		"per_page": perPage,
+	}).Info("get user groups")
+
+	user, err := u.svc.DB.GetUserByID(ctx, userID)
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return nil, cherry.ErrUnableGetUserGroups()
+	}
+	if user == nil {
+		return nil, cherry.ErrUserNotExist()
+	}
+	if err := u.checkUserInScope(ctx, user); err != nil {
+		return nil, err
+	}
+
+	memberships, total, err := u.svc.DB.GetUserGroupMemberships(ctx, user.ID, perPage, (page-1)*perPage)
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return nil, cherry.ErrUnableGetUserGroups()
+	}
+
+	resp := models.UserGroupMemberships{
+		Groups: []models.UserGroupMembership{},
+		Total:  total,
+		Pages:  pagesCount(total, perPage),
+	}
+	for _, v := range memberships {
+		membership := models.UserGroupMembership{
+			GroupID:    v.GroupID,
+			GroupLabel: v.GroupLabel,
+			OwnerID:    v.OwnerID,
+			OwnerLogin: v.OwnerLogin,
+			Access:     kube_types.AccessLevel(v.Access),
+		}
+		if v.AddedAt.Valid {
+			membership.AddedAt = &v.AddedAt.Time
+		}
+		if v.ExpiresAt.Valid {
+			membership.ExpiresAt = &v.ExpiresAt.Time
+			membership.ExpiresIn = expiresIn(v.ExpiresAt.Time)
+		}
+		resp.Groups = append(resp.Groups, membership)
+	}
+	return &resp, nil
+}
@@ -0,0 +1,175 @@
+package impl
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"git.containerum.net/ch/user-manager/pkg/db"
+	"git.containerum.net/ch/user-manager/pkg/models"
+	m "git.containerum.net/ch/user-manager/pkg/router/middleware"
+	cherry "git.containerum.net/ch/user-manager/pkg/umerrors"
+	kube_types "github.com/containerum/kube-client/pkg/model"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+func memberExpiresAt(expiresAt *time.Time) pq.NullTime {
+	if expiresAt == nil {
+		return pq.NullTime{}
+	}
+	// postgres stores timestamps with microsecond precision
+	return pq.NullTime{Time: expiresAt.UTC().Truncate(time.Microsecond), Valid: true}
+}
+
+func sameExpiration(a, b pq.NullTime) bool {
+	if a.Valid != b.Valid {
+		return false
+	}
+	return !a.Valid || a.Time.Equal(b.Time)
+}
+
+// errSyncOwnerChanged is returned from sync transaction if request changes group owner access.
+var errSyncOwnerChanged = errors.New("group owner access can not be changed")
+
+func (u *serverImpl) SyncGroupMembers(ctx context.Context, group kube_types.UserGroup, request models.GroupMembers, dryRun bool) (*models.GroupMembersSyncResult, error) {
+	u.log.WithFields(logrus.Fields{
+		"groupID": group.ID,
+		"members": len(request.Members),
+		"dry_run": dryRun,
+	}).Info("syncing group members")
+
+	dbGroup, err := u.svc.DB.GetGroupByID(ctx, group.ID)
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return nil, cherry.ErrUnableSyncGroupMembers()
+	}
+	if dbGroup == nil {
+		return nil, cherry.ErrGroupNotExist()
+	}
+
+	wanted := make([]db.UserGroupMember, 0, len(request.Members))
+	for _, member := range request.Members {
+		usr, err := u.svc.DB.GetUserByLogin(ctx, member.Username)
+		if err := u.handleDBError(err); err != nil {
+			u.log.WithError(err)
+			return nil, cherry.ErrUnableSyncGroupMembers()
+		}
+		if usr == nil {
+			return nil, cherry.ErrUserNotExist().AddDetails(member.Username)
+		}
+		if err := u.loginUserChecks(usr); err != nil {
+			return nil, err
+		}
+		if !usr.IsActive {
+			return nil, cherry.ErrNotActivated().AddDetails(member.Username)
+		}
+		if usr.Role == m.RoleAdmin {
+			return nil, cherry.ErrAddAdminGroup().AddDetails(member.Username)
+		}
+		if usr.OrgID != dbGroup.OrgID {
+			return nil, cherry.ErrUserNotInOrganization().AddDetails(member.Username)
+		}
+		wanted = append(wanted, db.UserGroupMember{
+			UserID:    usr.ID,
+			Login:     usr.Login,
+			GroupID:   group.ID,
+			Access:    string(member.Access),
+			ExpiresAt: memberExpiresAt(member.ExpiresAt),
+		})
+	}
+
+	var result models.GroupMembersSyncResult
+	var toAdd, toRemove []db.UserGroupMember
+	// members are compared and changed under lock so concurrent changes are not lost
+	err = u.svc.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) error {
+		current, err := tx.LockGroupMembers(ctx, group.ID)
+		if err != nil {
+			return err
+		}
+		currentByID := make(map[string]db.UserGroupMember, len(current))
+		for _, member := range current {
+			currentByID[member.UserID] = member
+		}
+
+		result = models.GroupMembersSyncResult{
+			Added:   []models.GroupMember{},
+			Updated: []models.GroupMember{},
+			Removed: []string{},
+			DryRun:  dryRun,
+		}
+		var toUpdate []db.UserGroupMember
+		requested := make(map[string]bool, len(wanted))
+		for i, member := range wanted {
+			requested[member.UserID] = true
+			existing, isMember := currentByID[member.UserID]
+			switch {
+			case member.UserID == group.OwnerID:
+				if isMember && (existing.Access != member.Access || !sameExpiration(existing.ExpiresAt, member.ExpiresAt)) {
+					return errSyncOwnerChanged
+				}
+				result.Unchanged++
+			case !isMember:
+				toAdd = append(toAdd, member)
+				result.Added = append(result.Added, request.Members[i])
+			case existing.Access != member.Access || !sameExpiration(existing.ExpiresAt, member.ExpiresAt):
+				toUpdate = append(toUpdate, member)
+				result.Updated = append(result.Updated, request.Members[i])
+			default:
+				result.Unchanged++
+			}
+		}
+
+		for _, member := range current {
+			// owner is never removed even if not listed
+			if requested[member.UserID] || member.UserID == group.OwnerID {
+				continue
+			}
+			toRemove = append(toRemove, member)
+			result.Removed = append(result.Removed, member.Login)
+		}
+
+		if dryRun {
+			return nil
+		}
+		for i := range toAdd {
+			if err := tx.AddGroupMembers(ctx, &toAdd[i]); err != nil {
+				return err
+			}
+		}
+		for _, member := range toUpdate {
+			if err := tx.UpdateGroupMember(ctx, member.UserID, group.ID, member.Access, member.ExpiresAt); err != nil {
+				return err
+			}
+		}
+		for _, member := range toRemove {
+			if err := tx.DeleteGroupMember(ctx, member.UserID, group.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == errSyncOwnerChanged {
+		return nil, cherry.ErrUnableChangeOwnerPermissions()
+	}
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return nil, cherry.ErrUnableSyncGroupMembers()
+	}
+	if dryRun {
+		return &result, nil
+	}
+
+	for _, member := range toAdd {
+		if err := u.svc.EventsClient.UserAddedToGroup(ctx, member.Login, group.Label); err != nil {
+			u.log.WithError(err).Warnln("Unable to add event")
+		}
+	}
+	for _, member := range toRemove {
+		if err := u.svc.EventsClient.UserRemovedFromGroup(ctx, member.Login, group.Label); err != nil {
+			u.log.WithError(err).Warnln("Unable to add event")
+		}
+	}
+
+	return &result, nil
+}
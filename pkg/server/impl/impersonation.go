@@ -0,0 +1,190 @@
+package impl
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"git.containerum.net/ch/auth/proto"
+	mttypes "git.containerum.net/ch/mail-templater/pkg/models"
+	"git.containerum.net/ch/user-manager/pkg/clients"
+	"git.containerum.net/ch/user-manager/pkg/db"
+	"git.containerum.net/ch/user-manager/pkg/models"
+	m "git.containerum.net/ch/user-manager/pkg/router/middleware"
+	cherry "git.containerum.net/ch/user-manager/pkg/umerrors"
+	"git.containerum.net/ch/user-manager/pkg/utils"
+	"git.containerum.net/ch/user-manager/pkg/validation"
+	"github.com/containerum/utils/httputil"
+	"github.com/sirupsen/logrus"
+)
+
+func impersonationToModel(impersonation db.Impersonation) models.Impersonation {
+	return models.Impersonation{
+		ID:                impersonation.ID,
+		ImpersonatorID:    impersonation.ImpersonatorID,
+		ImpersonatorLogin: impersonation.ImpersonatorLogin,
+		UserID:            impersonation.UserID,
+		UserLogin:         impersonation.UserLogin,
+		Reason:            impersonation.Reason,
+		IP:                impersonation.IP,
+		CreatedAt:         impersonation.CreatedAt,
+		ExpiresAt:         impersonation.ExpiresAt,
+	}
+}
+
+func (u *serverImpl) Impersonate(ctx context.Context, request models.ImpersonationRequest) (*authProto.CreateTokenResponse, error) {
+	u.log.WithFields(logrus.Fields{
+		"login":        request.Login,
+		"impersonator": httputil.MustGetUserID(ctx),
+	}).Info("impersonating user")
+
+	request.Reason = strings.TrimSpace(request.Reason)
+	if request.Reason == "" {
+		return nil, cherry.ErrImpersonationReasonRequired()
+	}
+	if errs := validation.ValidateUserLogin(models.UserLogin{Login: request.Login}); errs != nil {
+		return nil, cherry.ErrRequestValidationFailed().AddDetailsErr(errs...)
+	}
+
+	impersonator, err := u.svc.DB.GetUserByID(ctx, httputil.MustGetUserID(ctx))
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return nil, cherry.ErrUnableImpersonate()
+	}
+	if err := u.loginUserChecks(impersonator); err != nil {
+		return nil, err
+	}
+
+	user, err := u.svc.DB.GetUserByLogin(ctx, request.Login)
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return nil, cherry.ErrUnableImpersonate()
+	}
+	if err := u.loginUserChecks(user); err != nil {
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, cherry.ErrNotActivated()
+	}
+	// admin session gives more access than any admin role holder has, so admins are never impersonated
+	if user.Role == m.RoleAdmin {
+		return nil, cherry.ErrImpersonateAdmin()
+	}
+	// organization admins and admin roles holders may be impersonated only by admins
+	if err := u.checkAdminTarget(ctx, user); err != nil {
+		return nil, err
+	}
+
+	// audit record is saved before token creation, so there are no sessions without it
+	impersonation := db.Impersonation{
+		ImpersonatorID:    impersonator.ID,
+		ImpersonatorLogin: impersonator.Login,
+		UserID:            user.ID,
+		UserLogin:         user.Login,
+		Reason:            request.Reason,
+		IP:                httputil.MustGetClientIP(ctx),
+		ExpiresAt:         time.Now().UTC().Add(u.cfg.ImpersonationTTL),
+	}
+	err = u.svc.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) error {
+		return tx.CreateImpersonation(ctx, &impersonation)
+	})
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return nil, cherry.ErrUnableImpersonate()
+	}
+
+	tokens, err := u.createTokens(clients.WithImpersonation(ctx, impersonator.ID, u.cfg.ImpersonationTTL), user)
+	if err != nil {
+		u.log.WithError(err)
+		return nil, cherry.ErrUnableImpersonate()
+	}
+	// auth may ignore impersonation parameters and issue usual long-living tokens
+	for _, token := range []string{tokens.AccessToken, tokens.RefreshToken} {
+		if err := checkImpersonationToken(token, impersonation); err != nil {
+			u.log.WithError(err).Error("auth issued invalid impersonation token")
+			u.revokeTokens(ctx, user.ID, tokens.AccessToken, tokens.RefreshToken)
+			return nil, cherry.ErrUnableImpersonate()
+		}
+	}
+
+	if err := u.svc.MailClient.SendImpersonationMail(ctx, &mttypes.Recipient{
+		ID:    user.ID,
+		Name:  user.Login,
+		Email: user.Login,
+		Variables: map[string]interface{}{
+			"IMPERSONATOR": impersonator.Login,
+			"REASON":       impersonation.Reason,
+			"EXPIRES_AT":   impersonation.ExpiresAt.Format(time.RFC3339),
+		},
+	}); err != nil {
+		u.log.WithError(err).Error("impersonation email send failed")
+	}
+
+	return tokens, nil
+}
+
+func (u *serverImpl) GetUserImpersonations(ctx context.Context, userID string, page, perPage uint) (*models.Impersonations, error) {
+	u.log.WithField("user_id", userID).Info("get user impersonations")
+
+	user, err := u.svc.DB.GetAnyUserByID(ctx, userID)
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return nil, cherry.ErrUnableGetImpersonations()
+	}
+	if user == nil {
+		return nil, cherry.ErrUserNotExist()
+	}
+	if err := u.checkAdminTarget(ctx, user); err != nil {
+		return nil, err
+	}
+
+	impersonations, err := u.svc.DB.GetUserImpersonations(ctx, user.ID, int(perPage), int((page-1)*perPage))
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return nil, cherry.ErrUnableGetImpersonations()
+	}
+
+	resp := models.Impersonations{Impersonations: []models.Impersonation{}}
+	for _, v := range impersonations {
+		resp.Impersonations = append(resp.Impersonations, impersonationToModel(v))
+	}
+	return &resp, nil
+}
+
+// checkImpersonationToken checks that token is marked with impersonator and expires with impersonation.
+func checkImpersonationToken(token string, impersonation db.Impersonation) error {
+	// token is received directly from auth, so signature is not checked
+	claims, err := utils.JWTClaims(token)
+	if err != nil {
+		return err
+	}
+	if claims[m.ImpersonatorClaim] != impersonation.ImpersonatorID {
+		return errors.New("token is not marked with impersonator")
+	}
+	if exp, _ := claims["exp"].(float64); exp == 0 || time.Unix(int64(exp), 0).After(impersonation.ExpiresAt.Add(time.Minute)) {
+		return errors.New("token lifetime exceeds impersonation lifetime")
+	}
+	return nil
+}
+
+// revokeTokens deletes tokens identified by "jti" claim.
+func (u *serverImpl) revokeTokens(ctx context.Context, userID string, tokens ...string) {
+	for _, token := range tokens {
+		claims, err := utils.JWTClaims(token)
+		if err != nil {
+			continue
+		}
+		jti, _ := claims["jti"].(string)
+		if jti == "" {
+			u.log.Error("unable to revoke token without ID")
+			continue
+		}
+		if _, err := u.svc.AuthClient.DeleteToken(ctx, &authProto.DeleteTokenRequest{
+			TokenId: jti,
+			UserId:  userID,
+		}); err != nil {
+			u.log.WithError(err).Error("unable to revoke token")
+		}
+	}
+}
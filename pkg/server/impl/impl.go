@@ -7,6 +7,7 @@ import (
 	"errors"
 
 	"git.containerum.net/ch/user-manager/pkg/db"
+	"git.containerum.net/ch/user-manager/pkg/models"
 	"git.containerum.net/ch/user-manager/pkg/server"
 
 	"context"
@@ -16,22 +17,39 @@ import (
 
 	"git.containerum.net/ch/auth/proto"
 	mttypes "git.containerum.net/ch/mail-templater/pkg/models"
+	"git.containerum.net/ch/user-manager/pkg/clients"
 	cherry "git.containerum.net/ch/user-manager/pkg/umerrors"
 	"github.com/containerum/utils/httputil"
 	"github.com/lib/pq"
 	"github.com/sirupsen/logrus"
 )
 
+const defaultLinkLifetime = 24 * time.Hour
+
 type serverImpl struct {
-	svc server.Services
-	log *logrus.Entry
+	svc   server.Services
+	cfg   server.Config
+	log   *logrus.Entry
+	users *userCache // nil if users caching disabled
 }
 
 // NewUserManagerImpl returns a main UserManager implementation
-func NewUserManagerImpl(services server.Services) server.UserManager {
+func NewUserManagerImpl(services server.Services, config server.Config) server.UserManager {
+	log := logrus.WithField("component", "user_manager_impl")
+	users := newUserCache(config.UserCacheTTL, config.UserCacheSize)
+	if users != nil || config.UserCacheNotify {
+		services.DB = &userCacheDB{
+			DB:     services.DB,
+			cache:  users,
+			notify: config.UserCacheNotify,
+			log:    log,
+		}
+	}
 	return &serverImpl{
-		svc: services,
-		log: logrus.WithField("component", "user_manager_impl"),
+		svc:   services,
+		cfg:   config,
+		log:   log,
+		users: users,
 	}
 }
 
@@ -54,29 +72,56 @@ func (u *serverImpl) Close() error {
 	return errors.New(strerr)
 }
 
+func (u *serverImpl) linkPolicy(linkType models.LinkType) server.LinkPolicy {
+	if policy, ok := u.cfg.LinkPolicies[linkType]; ok {
+		return policy
+	}
+	return server.LinkPolicy{Lifetime: defaultLinkLifetime}
+}
+
 func (u *serverImpl) checkLinkResendTime(link *db.Link) error {
-	if tdiff := time.Now().UTC().Sub(link.SentAt.Time); link.SentAt.Valid && tdiff < 5*time.Minute {
-		return fmt.Errorf(waitForResend, int(tdiff.Seconds()))
+	cooldown := u.linkPolicy(link.Type).ResendCooldown
+	if tdiff := time.Now().UTC().Sub(link.SentAt.Time); link.SentAt.Valid && tdiff < cooldown {
+		return fmt.Errorf(waitForResend, int((cooldown - tdiff).Seconds()))
+	}
+	return nil
+}
+
+func (u *serverImpl) checkLinkResendCount(link *db.Link) error {
+	maxResends := u.linkPolicy(link.Type).MaxResends
+	if link.SentAt.Valid && maxResends > 0 && link.ResendCount >= maxResends {
+		return cherry.ErrLinkResendLimitExceeded()
 	}
 	return nil
 }
 
+// markLinkSent updates link send time and resends counter. Should be called after successful link send.
+func markLinkSent(link *db.Link) {
+	if link.SentAt.Valid {
+		link.ResendCount++
+	}
+	link.SentAt.Time = time.Now().UTC()
+	link.SentAt.Valid = true
+}
+
 func (u *serverImpl) linkSend(ctx context.Context, link *db.Link) error {
 	if link == nil {
 		return errors.New("invalid link")
 	}
+	recipient := &mttypes.Recipient{
+		ID:        link.User.ID,
+		Name:      link.User.Login,
+		Email:     link.User.Login,
+		Variables: map[string]interface{}{"CONFIRM": link.Link},
+	}
 	err := u.svc.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) error {
-		err := u.svc.MailClient.SendConfirmationMail(ctx, &mttypes.Recipient{
-			ID:        link.User.ID,
-			Name:      link.User.Login,
-			Email:     link.User.Login,
-			Variables: map[string]interface{}{"CONFIRM": link.Link},
+		err := u.sendMail(ctx, func(ctx context.Context) error {
+			return u.svc.MailClient.SendConfirmationMail(ctx, recipient)
 		})
 		if err != nil {
 			return err
 		}
-		link.SentAt.Time = time.Now().UTC()
-		link.SentAt.Valid = true
+		markLinkSent(link)
 		return tx.UpdateLink(ctx, link)
 	})
 	err = u.handleDBError(err)
@@ -96,7 +141,7 @@ func (u *serverImpl) createTokens(ctx context.Context, user *db.User) (resp *aut
 		return nil, errors.New(resourceAccessGetFailed)
 	}
 
-	resp, err = u.svc.AuthClient.CreateToken(ctx, &authProto.CreateTokenRequest{
+	resp, err = u.svc.AuthClient.CreateToken(clients.WithOrganization(ctx, user.OrgID), &authProto.CreateTokenRequest{
 		Fingerprint: httputil.MustGetFingerprint(ctx),
 		UserAgent:   httputil.MustGetUserAgent(ctx),
 		UserId:      user.ID,
@@ -122,13 +167,14 @@ func (u *serverImpl) loginUserChecks(user *db.User) error {
 	return nil
 }
 
-func (u *serverImpl) checkReCaptcha(ctx context.Context, clientResponse string) error {
-	remoteIP := httputil.MustGetClientIP(ctx)
+func (u *serverImpl) checkCaptcha(ctx context.Context, clientResponse string) error {
+	// remote IP is optional for captcha verification
+	remoteIP := clientIP(ctx)
 	u.log.WithFields(logrus.Fields{
 		"remote_ip":       remoteIP,
 		"client_response": clientResponse,
-	}).Info("checking recaptcha")
-	resp, err := u.svc.ReCaptchaClient.Check(ctx, remoteIP, clientResponse)
+	}).Info("checking captcha")
+	resp, err := u.svc.CaptchaClient.Check(ctx, remoteIP, clientResponse)
 	if err != nil {
 		return cherry.ErrLoginFailed()
 	}
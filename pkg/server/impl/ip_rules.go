@@ -0,0 +1,171 @@
+package impl
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"git.containerum.net/ch/user-manager/pkg/db"
+	"git.containerum.net/ch/user-manager/pkg/models"
+	cherry "git.containerum.net/ch/user-manager/pkg/umerrors"
+	"git.containerum.net/ch/user-manager/pkg/utils"
+	"github.com/containerum/utils/httputil"
+	"github.com/lib/pq"
+)
+
+func ipRuleFromDB(rule db.IPRule) models.IPRule {
+	ret := models.IPRule{
+		CIDR:      rule.CIDR,
+		List:      rule.List,
+		Reason:    rule.Reason,
+		AddedBy:   rule.AddedBy.String,
+		CreatedAt: rule.CreatedAt,
+	}
+	if rule.ExpiresAt.Valid {
+		ret.ExpiresAt = &rule.ExpiresAt.Time
+	}
+	return ret
+}
+
+// checkClientIP rejects request if client IP is blocked or, in allowlist mode, isn't allowed.
+// Database errors are returned as is so handlers respond with their default error.
+func (u *serverImpl) checkClientIP(ctx context.Context) error {
+	ip := httputil.MustGetClientIP(ctx)
+	if _, err := utils.ParseNetwork(ip); err != nil {
+		// header value is set by gateway, so invalid address may be only forged
+		u.log.WithError(err).Warnln("Invalid client IP")
+		return cherry.ErrIPBlocked().AddDetailsErr(err)
+	}
+
+	rule, err := u.svc.DB.MatchIPRule(ctx, ip)
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return err
+	}
+	switch {
+	case rule != nil && rule.List == models.IPListAllow:
+		return nil
+	case rule != nil:
+		u.log.WithError(fmt.Errorf(ipBlocked, ip)).WithField("cidr", rule.CIDR).Info("request rejected")
+		return cherry.ErrIPBlocked().AddDetailsErr(fmt.Errorf(ipBlocked, ip))
+	case u.cfg.IPAllowlist:
+		u.log.WithError(fmt.Errorf(ipNotInAllowlist, ip))
+		return cherry.ErrIPBlocked().AddDetailsErr(fmt.Errorf(ipNotInAllowlist, ip))
+	}
+	return nil
+}
+
+func (u *serverImpl) AddIPRule(ctx context.Context, request models.IPRule) (*models.IPRule, error) {
+	u.log.WithField("cidr", request.CIDR).Info("adding IP rule")
+
+	network, err := utils.ParseNetwork(request.CIDR)
+	if err != nil {
+		u.log.WithError(err)
+		return nil, cherry.ErrRequestValidationFailed().AddDetailsErr(err)
+	}
+
+	userID := httputil.MustGetUserID(ctx)
+	rule := db.IPRule{
+		CIDR:    network.String(),
+		List:    request.List,
+		Reason:  request.Reason,
+		AddedBy: sql.NullString{String: userID, Valid: true},
+	}
+	if rule.List == "" {
+		rule.List = models.IPListBlock
+	}
+	if request.ExpiresAt != nil {
+		rule.ExpiresAt = pq.NullTime{Time: request.ExpiresAt.UTC(), Valid: true}
+	}
+
+	var added *db.IPRule
+	err = u.svc.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) error {
+		if err := tx.AddIPRule(ctx, rule); err != nil {
+			return err
+		}
+		added, err = tx.GetIPRule(ctx, rule.CIDR)
+		return err
+	})
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return nil, cherry.ErrUnableAddIPRule()
+	}
+
+	resp := ipRuleFromDB(*added)
+	return &resp, nil
+}
+
+func (u *serverImpl) DeleteIPRule(ctx context.Context, cidr string) error {
+	u.log.WithField("cidr", cidr).Info("deleting IP rule")
+
+	network, err := utils.ParseNetwork(cidr)
+	if err != nil {
+		u.log.WithError(err)
+		return cherry.ErrRequestValidationFailed().AddDetailsErr(err)
+	}
+
+	err = u.svc.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) error {
+		return tx.DeleteIPRule(ctx, network.String())
+	})
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return cherry.ErrUnableDeleteIPRule()
+	}
+
+	return nil
+}
+
+func (u *serverImpl) GetIPRule(ctx context.Context, cidr string) (*models.IPRule, error) {
+	u.log.WithField("cidr", cidr).Info("get IP rule")
+
+	network, err := utils.ParseNetwork(cidr)
+	if err != nil {
+		u.log.WithError(err)
+		return nil, cherry.ErrRequestValidationFailed().AddDetailsErr(err)
+	}
+
+	rule, err := u.svc.DB.GetIPRule(ctx, network.String())
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return nil, cherry.ErrUnableGetIPRules()
+	}
+
+	if ones, bits := network.Mask.Size(); rule == nil && ones == bits {
+		// single address may be covered by wider network rule
+		rule, err = u.svc.DB.MatchIPRule(ctx, network.IP.String())
+		if err := u.handleDBError(err); err != nil {
+			u.log.WithError(err)
+			return nil, cherry.ErrUnableGetIPRules()
+		}
+	}
+
+	if rule == nil {
+		u.log.WithError(cherry.ErrIPRuleNotFound())
+		return nil, cherry.ErrIPRuleNotFound()
+	}
+
+	resp := ipRuleFromDB(*rule)
+	return &resp, nil
+}
+
+func (u *serverImpl) GetIPRules(ctx context.Context, list string) (*models.IPRules, error) {
+	u.log.WithField("list", list).Info("get IP rules")
+	if list == "" {
+		list = models.IPListBlock
+	}
+
+	rules, err := u.svc.DB.GetIPRules(ctx, list)
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return nil, cherry.ErrUnableGetIPRules()
+	}
+
+	resp := models.IPRules{
+		IPRules: make([]models.IPRule, 0, len(rules)),
+	}
+	for _, v := range rules {
+		resp.IPRules = append(resp.IPRules, ipRuleFromDB(v))
+	}
+
+	return &resp, nil
+}
@@ -0,0 +1,232 @@
+package impl
+
+import (
+	"context"
+	"expvar"
+	"time"
+
+	mttypes "git.containerum.net/ch/mail-templater/pkg/models"
+	"git.containerum.net/ch/user-manager/pkg/db"
+	"github.com/sirupsen/logrus"
+)
+
+// janitorLockKey identifies advisory lock which guarantees that only one replica performs cleanup at a time.
+const janitorLockKey int64 = 0x756d6a616e69746f // "umjanito"
+
+var (
+	janitorLinksRemoved  = expvar.NewInt("janitor_links_removed")
+	janitorTokensRemoved = expvar.NewInt("janitor_tokens_removed")
+	janitorRuns          = expvar.NewInt("janitor_runs")
+
+	janitorMembersRemoved  = expvar.NewInt("janitor_group_members_removed")
+	janitorMembersNotified = expvar.NewInt("janitor_group_members_notified")
+
+	janitorPasswordsNotified = expvar.NewInt("janitor_passwords_expiry_notified")
+
+	janitorLoginFailuresRemoved = expvar.NewInt("janitor_login_failures_removed")
+
+	janitorDomainsRemoved = expvar.NewInt("janitor_domain_rules_removed")
+	janitorIPRulesRemoved = expvar.NewInt("janitor_ip_rules_removed")
+
+	janitorRateLimitsRemoved = expvar.NewInt("janitor_rate_limits_removed")
+)
+
+// RunJanitor periodically removes expired links, inactive one-time tokens and expired group memberships until ctx is done.
+func (u *serverImpl) RunJanitor(ctx context.Context) {
+	if u.cfg.JanitorInterval <= 0 {
+		u.log.Infoln("Janitor disabled")
+		return
+	}
+	ticker := time.NewTicker(u.cfg.JanitorInterval)
+	defer ticker.Stop()
+	for {
+		u.cleanup(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (u *serverImpl) cleanup(ctx context.Context) {
+	before := time.Now().UTC().Add(-u.cfg.JanitorRetention)
+	entry := u.log.WithField("before", before.Format(time.ANSIC))
+
+	var links, tokens, members, notified, passwords, failures, domains, ipRules, rateLimits int64
+	acquired, err := u.svc.DB.WithAdvisoryLock(ctx, janitorLockKey, func(ctx context.Context) error {
+		var err error
+		if links, err = u.deleteInBatches(ctx, before, u.svc.DB.DeleteExpiredLinks, janitorLinksRemoved); err != nil {
+			return err
+		}
+		if tokens, err = u.deleteInBatches(ctx, before, u.svc.DB.DeleteInactiveTokens, janitorTokensRemoved); err != nil {
+			return err
+		}
+		// failures are not counted after window end anyway
+		failuresBefore := time.Now().UTC().Add(-u.cfg.CaptchaFailureWindow)
+		if failures, err = u.deleteInBatches(ctx, failuresBefore, u.svc.DB.DeleteStaleLoginFailures, janitorLoginFailuresRemoved); err != nil {
+			return err
+		}
+		if domains, err = u.deleteInBatches(ctx, time.Now().UTC(), u.svc.DB.DeleteExpiredDomains, janitorDomainsRemoved); err != nil {
+			return err
+		}
+		if ipRules, err = u.deleteInBatches(ctx, time.Now().UTC(), u.svc.DB.DeleteExpiredIPRules, janitorIPRulesRemoved); err != nil {
+			return err
+		}
+		if rateLimits, err = u.deleteInBatches(ctx, time.Now().UTC(), u.svc.DB.DeleteFullRateLimits, janitorRateLimitsRemoved); err != nil {
+			return err
+		}
+		if members, err = u.reapExpiredGroupMembers(ctx); err != nil {
+			return err
+		}
+		if notified, err = u.notifyExpiringGroupMembers(ctx); err != nil {
+			return err
+		}
+		passwords, err = u.notifyExpiringPasswords(ctx)
+		return err
+	})
+	if err != nil {
+		entry.WithError(err).Errorln("Cleanup failed")
+	}
+	if !acquired {
+		entry.Debugln("Cleanup is performed by another instance")
+		return
+	}
+	janitorRuns.Add(1)
+	entry.WithFields(logrus.Fields{
+		"links_removed":          links,
+		"tokens_removed":         tokens,
+		"group_members_removed":  members,
+		"group_members_notified": notified,
+		"passwords_notified":     passwords,
+		"login_failures_removed": failures,
+		"domain_rules_removed":   domains,
+		"ip_rules_removed":       ipRules,
+		"rate_limits_removed":    rateLimits,
+	}).Infoln("Cleanup finished")
+}
+
+func (u *serverImpl) deleteInBatches(ctx context.Context, before time.Time,
+	del func(ctx context.Context, before time.Time, limit int) (int64, error), counter *expvar.Int) (total int64, err error) {
+	for {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+		var removed int64
+		removed, err = del(ctx, before, u.cfg.JanitorBatchSize)
+		if err != nil {
+			return
+		}
+		total += removed
+		counter.Add(removed)
+		if removed < int64(u.cfg.JanitorBatchSize) {
+			return
+		}
+	}
+}
+
+// reapExpiredGroupMembers removes expired group memberships and emits events for each removed member.
+func (u *serverImpl) reapExpiredGroupMembers(ctx context.Context) (total int64, err error) {
+	for {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+		var removed []db.ExpiringGroupMember
+		err = u.svc.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) (err error) {
+			removed, err = tx.DeleteExpiredGroupMembers(ctx, u.cfg.JanitorBatchSize)
+			return err
+		})
+		if err != nil {
+			return
+		}
+		for _, member := range removed {
+			if err := u.svc.EventsClient.UserRemovedFromGroup(ctx, member.Login, member.GroupLabel); err != nil {
+				u.log.WithError(err).Warnln("Unable to add event")
+			}
+		}
+		total += int64(len(removed))
+		janitorMembersRemoved.Add(int64(len(removed)))
+		if len(removed) < u.cfg.JanitorBatchSize {
+			return
+		}
+	}
+}
+
+// notifyExpiringGroupMembers sends mails to group owners about memberships which expire soon. Each membership is notified once.
+func (u *serverImpl) notifyExpiringGroupMembers(ctx context.Context) (total int64, err error) {
+	if u.cfg.MemberExpiryNotice <= 0 {
+		return 0, nil
+	}
+	for {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+		var expiring []db.ExpiringGroupMember
+		expiring, err = u.svc.DB.GetExpiringGroupMembers(ctx, time.Now().UTC().Add(u.cfg.MemberExpiryNotice), u.cfg.JanitorBatchSize)
+		if err != nil {
+			return
+		}
+		for _, member := range expiring {
+			if mailErr := u.svc.MailClient.SendGroupMemberExpiringMail(ctx, &mttypes.Recipient{
+				ID:    member.OwnerID,
+				Name:  member.OwnerLogin,
+				Email: member.OwnerLogin,
+				Variables: map[string]interface{}{
+					"GROUP":      member.GroupLabel,
+					"MEMBER":     member.Login,
+					"EXPIRES_AT": member.ExpiresAt.Format(time.RFC3339),
+				},
+			}); mailErr != nil {
+				u.log.WithError(mailErr).Warnln("Unable to send group member expiring mail")
+			}
+			// mark anyway to not spam owner if mail-templater rejects message
+			if err = u.svc.DB.MarkGroupMemberExpiryNotified(ctx, member.GroupID, member.UserID); err != nil {
+				return
+			}
+		}
+		total += int64(len(expiring))
+		janitorMembersNotified.Add(int64(len(expiring)))
+		if len(expiring) < u.cfg.JanitorBatchSize {
+			return
+		}
+	}
+}
+
+// notifyExpiringPasswords sends mails to users which passwords expire soon. User is notified once after each password change.
+func (u *serverImpl) notifyExpiringPasswords(ctx context.Context) (total int64, err error) {
+	if u.cfg.PasswordMaxAge <= 0 {
+		return 0, nil
+	}
+	for {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+		var expiring []db.User
+		expiring, err = u.svc.DB.GetUsersWithExpiringPasswords(ctx, time.Now().UTC().Add(u.cfg.PasswordExpiryNotice-u.cfg.PasswordMaxAge), u.cfg.JanitorBatchSize)
+		if err != nil {
+			return
+		}
+		for i := range expiring {
+			user := &expiring[i]
+			if mailErr := u.svc.MailClient.SendPasswordExpiringMail(ctx, &mttypes.Recipient{
+				ID:    user.ID,
+				Name:  user.Login,
+				Email: user.Login,
+				Variables: map[string]interface{}{
+					"EXPIRES_AT": u.passwordExpiresAt(user).Format(time.RFC3339),
+				},
+			}); mailErr != nil {
+				u.log.WithError(mailErr).Warnln("Unable to send password expiring mail")
+			}
+			// mark anyway to not spam user if mail-templater rejects message
+			if err = u.svc.DB.MarkPasswordExpiryNotified(ctx, user.ID); err != nil {
+				return
+			}
+		}
+		total += int64(len(expiring))
+		janitorPasswordsNotified.Add(int64(len(expiring)))
+		if len(expiring) < u.cfg.JanitorBatchSize {
+			return
+		}
+	}
+}
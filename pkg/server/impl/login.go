@@ -11,6 +11,7 @@ import (
 	"git.containerum.net/ch/user-manager/pkg/clients"
 	"git.containerum.net/ch/user-manager/pkg/db"
 	"git.containerum.net/ch/user-manager/pkg/models"
+	m "git.containerum.net/ch/user-manager/pkg/router/middleware"
 	cherry "git.containerum.net/ch/user-manager/pkg/umerrors"
 	"git.containerum.net/ch/user-manager/pkg/utils"
 	"github.com/containerum/utils/httputil"
@@ -23,6 +24,13 @@ func (u *serverImpl) BasicLogin(ctx context.Context, request models.LoginRequest
 		"username": request.Login,
 	}).Debugln("Basic login details")
 
+	if err := u.checkClientIP(ctx); err != nil {
+		return nil, err
+	}
+	if err := u.checkCaptchaOnRisk(ctx, request.Login, request.ReCaptcha); err != nil {
+		return nil, err
+	}
+
 	user, err := u.svc.DB.GetUserByLogin(ctx, request.Login)
 	if dbErr := u.handleDBError(err); dbErr != nil {
 		u.log.WithError(dbErr)
@@ -30,8 +38,16 @@ func (u *serverImpl) BasicLogin(ctx context.Context, request models.LoginRequest
 	}
 
 	if err = u.loginUserChecks(user); err != nil {
+		if user == nil {
+			u.registerLoginFailure(ctx, request.Login)
+		}
 		return nil, err
 	}
+	if user.Role == m.RoleService {
+		// service accounts may login only with API keys
+		u.log.WithError(cherry.ErrInvalidLogin())
+		return nil, cherry.ErrInvalidLogin()
+	}
 
 	profile, err := u.svc.DB.GetProfileByUser(ctx, user)
 	if dbErr := u.handleDBError(err); dbErr != nil {
@@ -41,8 +57,10 @@ func (u *serverImpl) BasicLogin(ctx context.Context, request models.LoginRequest
 
 	if !utils.CheckPassword(request.Login, request.Password, user.Salt, user.PasswordHash) {
 		u.log.WithError(cherry.ErrInvalidLogin())
+		u.registerLoginFailure(ctx, request.Login)
 		return nil, cherry.ErrInvalidLogin()
 	}
+	u.resetLoginFailures(ctx, request.Login)
 	if user.IsInBlacklist {
 		return nil, cherry.ErrAccountBlocked()
 	}
@@ -56,7 +74,7 @@ func (u *serverImpl) BasicLogin(ctx context.Context, request models.LoginRequest
 		if link == nil {
 			err := u.svc.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) error {
 				var err error
-				link, err = tx.CreateLink(ctx, models.LinkTypeConfirm, 24*time.Hour, user)
+				link, err = tx.CreateLink(ctx, models.LinkTypeConfirm, u.linkPolicy(models.LinkTypeConfirm).Lifetime, user)
 				return err
 			})
 			if err := u.handleDBError(err); err != nil {
@@ -64,6 +82,10 @@ func (u *serverImpl) BasicLogin(ctx context.Context, request models.LoginRequest
 				return nil, cherry.ErrInvalidLogin()
 			}
 		}
+		if err := u.checkLinkResendCount(link); err != nil {
+			u.log.WithError(err)
+			return nil, cherry.ErrNotActivated()
+		}
 		if err := u.checkLinkResendTime(link); err != nil {
 			u.log.WithError(err)
 			return nil, err
@@ -74,6 +96,11 @@ func (u *serverImpl) BasicLogin(ctx context.Context, request models.LoginRequest
 		return nil, cherry.ErrNotActivated()
 	}
 
+	// user gets tokens only after password change
+	if err := u.checkPasswordRotation(user); err != nil {
+		return nil, err
+	}
+
 	loginerr := u.svc.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) error {
 		return tx.UpdateLastLogin(ctx, profile.ID.String, time.Now().Format(time.RFC3339))
 	})
@@ -138,6 +165,9 @@ func (u *serverImpl) OAuthLogin(ctx context.Context, request models.OAuthLoginRe
 		"resource":        request.Resource,
 		"key_to_exchange": request.AccessToken,
 	}).Debugln("OAuth login credentials")
+	if err := u.checkClientIP(ctx); err != nil {
+		return nil, err
+	}
 	resource, exist := clients.OAuthClientByResource(request.Resource)
 	if !exist {
 		u.log.WithError(fmt.Errorf(resourceNotSupported, request.Resource))
@@ -188,6 +218,9 @@ func (u *serverImpl) OAuthLogin(ctx context.Context, request models.OAuthLoginRe
 		return nil, cherry.ErrLoginFailed()
 	}
 
+	// email is confirmed by OAuth provider
+	u.applyPendingGroupInvites(ctx, user)
+
 	loginerr := u.svc.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) error {
 		return tx.UpdateLastLogin(ctx, profile.ID.String, time.Now().Format(time.RFC3339))
 	})
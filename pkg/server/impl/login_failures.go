@@ -0,0 +1,91 @@
+package impl
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	cherry "git.containerum.net/ch/user-manager/pkg/umerrors"
+	"github.com/containerum/utils/httputil"
+)
+
+// clientIP returns client IP or empty string. Client IP header is not required by some routes.
+func clientIP(ctx context.Context) string {
+	ip, _ := ctx.Value(httputil.ClientIPContextKey).(string)
+	return ip
+}
+
+// loginFailureKeys returns keys of failure counters for login and client IP.
+// Requests without client IP are counted by login only.
+func loginFailureKeys(ctx context.Context, login string) []string {
+	keys := []string{"login:" + strings.ToLower(login)}
+	if ip := clientIP(ctx); ip != "" {
+		keys = append(keys, "ip:"+ip)
+	}
+	return keys
+}
+
+// passwordResetKeys returns keys of password reset counters for login and client IP.
+// They are separate from login failures, so reset requests can't force captcha on login of other user.
+func passwordResetKeys(ctx context.Context, login string) []string {
+	keys := loginFailureKeys(ctx, login)
+	for i := range keys {
+		keys[i] = "reset:" + keys[i]
+	}
+	return keys
+}
+
+// checkCaptchaOnRisk requires captcha if login or client IP exceeded failures threshold.
+func (u *serverImpl) checkCaptchaOnRisk(ctx context.Context, login, captcha string) error {
+	return u.checkCaptchaOnCounters(ctx, loginFailureKeys(ctx, login), captcha)
+}
+
+// checkCaptchaOnCounters requires captcha if any of counters exceeded failures threshold.
+func (u *serverImpl) checkCaptchaOnCounters(ctx context.Context, keys []string, captcha string) error {
+	if u.cfg.CaptchaFailureThreshold <= 0 {
+		return nil
+	}
+	failures, err := u.svc.DB.GetLoginFailures(ctx, keys, time.Now().UTC().Add(-u.cfg.CaptchaFailureWindow))
+	if err != nil {
+		// captcha is required if failures count is unknown
+		u.log.WithError(err).Warnln("Unable to get login failures")
+	} else if failures < u.cfg.CaptchaFailureThreshold {
+		return nil
+	}
+	if captcha == "" {
+		return cherry.ErrCaptchaRequired()
+	}
+	if err := u.checkCaptcha(ctx, captcha); err != nil {
+		u.log.WithError(err)
+		return cherry.ErrInvalidRecaptcha()
+	}
+	return nil
+}
+
+func (u *serverImpl) registerLoginFailure(ctx context.Context, login string) {
+	u.incrementCounters(ctx, loginFailureKeys(ctx, login))
+}
+
+func (u *serverImpl) registerPasswordReset(ctx context.Context, login string) {
+	u.incrementCounters(ctx, passwordResetKeys(ctx, login))
+}
+
+func (u *serverImpl) incrementCounters(ctx context.Context, keys []string) {
+	if u.cfg.CaptchaFailureThreshold <= 0 {
+		return
+	}
+	if err := u.svc.DB.AddLoginFailure(ctx, keys, time.Now().UTC().Add(-u.cfg.CaptchaFailureWindow)); err != nil {
+		u.log.WithError(err).Warnln("Unable to increment failures counters")
+	}
+}
+
+// resetLoginFailures resets failures counter of login after successful login. Client IP counter is not reset
+// because one IP may be used to guess passwords of many users.
+func (u *serverImpl) resetLoginFailures(ctx context.Context, login string) {
+	if u.cfg.CaptchaFailureThreshold <= 0 {
+		return
+	}
+	if err := u.svc.DB.ResetLoginFailures(ctx, loginFailureKeys(ctx, login)[0]); err != nil {
+		u.log.WithError(err).Warnln("Unable to reset login failures")
+	}
+}
@@ -0,0 +1,167 @@
+package impl
+
+import (
+	"context"
+	"strings"
+
+	"git.containerum.net/ch/auth/proto"
+	"git.containerum.net/ch/user-manager/pkg/db"
+	"git.containerum.net/ch/user-manager/pkg/models"
+	m "git.containerum.net/ch/user-manager/pkg/router/middleware"
+	cherry "git.containerum.net/ch/user-manager/pkg/umerrors"
+	"github.com/containerum/utils/httputil"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+func organizationToModel(org db.Organization) models.Organization {
+	return models.Organization{
+		ID:        org.ID,
+		Name:      org.Name,
+		CreatedAt: org.CreatedAt,
+	}
+}
+
+// orgScope returns organization current user is restricted to. Admins are not restricted, so empty string is returned for them.
+func (u *serverImpl) orgScope(ctx context.Context) (string, error) {
+	if httputil.MustGetUserRole(ctx) == m.RoleAdmin {
+		return "", nil
+	}
+	user, err := u.getCachedUser(ctx, httputil.MustGetUserID(ctx))
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return "", cherry.ErrPermissionsError()
+	}
+	if err := u.loginUserChecks(user); err != nil {
+		return "", err
+	}
+	return user.OrgID, nil
+}
+
+// checkUserInScope checks if user belongs to organization current user is restricted to.
+func (u *serverImpl) checkUserInScope(ctx context.Context, user *db.User) error {
+	orgID, err := u.orgScope(ctx)
+	if err != nil {
+		return err
+	}
+	if orgID != "" && user.OrgID != orgID {
+		return cherry.ErrUserNotInOrganization().AddDetails(user.Login)
+	}
+	return nil
+}
+
+// checkUserInGroupOrg checks if user belongs to the same organization as group.
+func (u *serverImpl) checkUserInGroupOrg(ctx context.Context, groupID string, user *db.User) error {
+	group, err := u.svc.DB.GetGroupByID(ctx, groupID)
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return cherry.ErrPermissionsError()
+	}
+	if group == nil {
+		return cherry.ErrGroupNotExist()
+	}
+	if group.OrgID != user.OrgID {
+		return cherry.ErrUserNotInOrganization().AddDetails(user.Login)
+	}
+	return nil
+}
+
+func (u *serverImpl) CheckOrgAdmin(ctx context.Context) error {
+	userID := httputil.MustGetUserID(ctx)
+	u.log.WithField("user_id", userID).Info("checking if user is organization admin")
+	user, err := u.getCachedUser(ctx, userID)
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return cherry.ErrPermissionsError()
+	}
+	if err := u.loginUserChecks(user); err != nil {
+		return err
+	}
+
+	if user.Role != m.RoleAdmin && !user.IsOrgAdmin {
+		return cherry.ErrOrgAdminRequired()
+	}
+
+	return nil
+}
+
+func (u *serverImpl) CreateOrganization(ctx context.Context, request models.OrganizationCreateRequest) (*models.Organization, error) {
+	u.log.WithField("name", request.Name).Info("creating organization")
+
+	org := &db.Organization{Name: strings.TrimSpace(request.Name)}
+	err := u.svc.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) error {
+		return tx.CreateOrganization(ctx, org)
+	})
+	if pqerr, ok := err.(*pq.Error); ok && pqerr.Constraint == "unique_organization_name" {
+		return nil, cherry.ErrOrganizationAlreadyExist()
+	}
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return nil, cherry.ErrUnableCreateOrganization()
+	}
+
+	ret := organizationToModel(*org)
+	return &ret, nil
+}
+
+func (u *serverImpl) GetOrganizations(ctx context.Context) (*models.Organizations, error) {
+	u.log.Info("get organizations")
+
+	orgs, err := u.svc.DB.GetOrganizations(ctx)
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return nil, cherry.ErrUnableGetOrganizations()
+	}
+
+	resp := models.Organizations{Organizations: []models.Organization{}}
+	for _, v := range orgs {
+		resp.Organizations = append(resp.Organizations, organizationToModel(v))
+	}
+	return &resp, nil
+}
+
+func (u *serverImpl) SetUserOrganization(ctx context.Context, userID string, request models.UserOrganizationRequest) error {
+	u.log.WithFields(logrus.Fields{
+		"user_id":   userID,
+		"org_id":    request.OrgID,
+		"org_admin": request.OrgAdmin,
+	}).Info("setting user organization")
+
+	user, err := u.svc.DB.GetUserByID(ctx, userID)
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return cherry.ErrUnableSetUserOrganization()
+	}
+	if err := u.loginUserChecks(user); err != nil {
+		return err
+	}
+
+	org, err := u.svc.DB.GetOrganization(ctx, request.OrgID)
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return cherry.ErrUnableSetUserOrganization()
+	}
+	if org == nil {
+		return cherry.ErrOrganizationNotExist()
+	}
+
+	err = u.svc.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) error {
+		return tx.SetUserOrganization(ctx, user.ID, org.ID, request.OrgAdmin)
+	})
+	if err == db.ErrUserOwnsGroups {
+		return cherry.ErrUserOwnsGroups().AddDetails(user.Login)
+	}
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return cherry.ErrUnableSetUserOrganization()
+	}
+
+	// issued tokens carry previous organization
+	if _, err := u.svc.AuthClient.DeleteUserTokens(ctx, &authProto.DeleteUserTokensRequest{
+		UserId: user.ID,
+	}); err != nil {
+		u.log.WithError(err)
+		return cherry.ErrUnableSetUserOrganization()
+	}
+	return nil
+}
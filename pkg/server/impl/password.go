@@ -3,14 +3,14 @@ package impl
 import (
 	"context"
 
-	"time"
-
 	"fmt"
+	"time"
 
 	"git.containerum.net/ch/auth/proto"
 	mttypes "git.containerum.net/ch/mail-templater/pkg/models"
 	"git.containerum.net/ch/user-manager/pkg/db"
 	"git.containerum.net/ch/user-manager/pkg/models"
+	m "git.containerum.net/ch/user-manager/pkg/router/middleware"
 	cherry "git.containerum.net/ch/user-manager/pkg/umerrors"
 	"git.containerum.net/ch/user-manager/pkg/utils"
 	"github.com/containerum/utils/httputil"
@@ -34,11 +34,62 @@ func (u *serverImpl) ChangePassword(ctx context.Context, request models.Password
 		return nil, cherry.ErrInvalidLogin()
 	}
 
+	return u.changeUserPassword(ctx, user, request.NewPassword)
+}
+
+// ChangeExpiredPassword changes password of user which must change it before login.
+// It issues tokens, so it is protected in the same way as login.
+func (u *serverImpl) ChangeExpiredPassword(ctx context.Context, request models.ExpiredPasswordChangeRequest) (*authProto.CreateTokenResponse, error) {
+	u.log.WithField("login", request.Login).Info("changing expired password")
+
+	if err := u.checkClientIP(ctx); err != nil {
+		return nil, err
+	}
+	if err := u.checkCaptchaOnRisk(ctx, request.Login, request.ReCaptcha); err != nil {
+		return nil, err
+	}
+
+	user, err := u.svc.DB.GetUserByLogin(ctx, request.Login)
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return nil, cherry.ErrUnableChangePassword()
+	}
+	if err := u.loginUserChecks(user); err != nil {
+		if user == nil {
+			u.registerLoginFailure(ctx, request.Login)
+		}
+		return nil, err
+	}
+	if user.Role == m.RoleService || !utils.CheckPassword(user.Login, request.CurrentPassword, user.Salt, user.PasswordHash) {
+		u.log.WithError(cherry.ErrInvalidLogin())
+		u.registerLoginFailure(ctx, request.Login)
+		return nil, cherry.ErrInvalidLogin()
+	}
+	u.resetLoginFailures(ctx, request.Login)
+	if !user.IsActive {
+		return nil, cherry.ErrNotActivated()
+	}
+	if u.checkPasswordRotation(user) == nil {
+		return nil, cherry.ErrPasswordChangeNotRequired()
+	}
+
+	return u.changeUserPassword(ctx, user, request.NewPassword)
+}
+
+// changeUserPassword sets new password of authenticated user, revokes user tokens and creates new ones.
+func (u *serverImpl) changeUserPassword(ctx context.Context, user *db.User, newPassword string) (*authProto.CreateTokenResponse, error) {
+	if err := u.checkPasswordPolicy(ctx, user.Login, user, newPassword); err != nil {
+		return nil, err
+	}
+
 	var tokens *authProto.CreateTokenResponse
 
-	user.PasswordHash = utils.GetKey(user.Login, request.NewPassword, user.Salt)
-	err = u.svc.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) error {
-		return tx.UpdateUser(ctx, user)
+	setUserPassword(user, newPassword, false)
+	err := u.svc.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) error {
+		if err := tx.UpdateUser(ctx, user); err != nil {
+			return err
+		}
+		return u.savePasswordHistory(ctx, tx, user, newPassword)
 	})
 	if err = u.handleDBError(err); err != nil {
 		return nil, cherry.ErrUnableChangePassword()
@@ -65,8 +116,27 @@ func (u *serverImpl) ChangePassword(ctx context.Context, request models.Password
 	return tokens, nil
 }
 
-func (u *serverImpl) ResetPassword(ctx context.Context, request models.UserLogin) error {
+func (u *serverImpl) ResetPassword(ctx context.Context, request models.PasswordResetRequest) error {
 	u.log.WithField("login", request.Login).Info("resetting password")
+	defer u.hideTiming(time.Now())
+
+	keys := loginFailureKeys(ctx, request.Login)
+	if u.cfg.PrivacyMode {
+		keys = append(keys, passwordResetKeys(ctx, request.Login)...)
+	}
+	if err := u.checkCaptchaOnCounters(ctx, keys, request.ReCaptcha); err != nil {
+		return err
+	}
+	if u.cfg.PrivacyMode {
+		// counting only unknown logins would make captcha requirement reveal account existence
+		u.registerPasswordReset(ctx, request.Login)
+	}
+
+	return u.concealAccountState(u.resetPassword(ctx, request))
+}
+
+func (u *serverImpl) resetPassword(ctx context.Context, request models.PasswordResetRequest) error {
+
 	user, err := u.svc.DB.GetUserByLogin(ctx, request.Login)
 
 	if err := u.handleDBError(err); err != nil {
@@ -74,30 +144,60 @@ func (u *serverImpl) ResetPassword(ctx context.Context, request models.UserLogin
 		return cherry.ErrUnableResetPassword()
 	}
 	if err := u.loginUserChecks(user); err != nil {
+		// protects from users enumeration
+		if user == nil && !u.cfg.PrivacyMode {
+			u.registerLoginFailure(ctx, request.Login)
+		}
 		return err
 	}
+	if user.Role == m.RoleService {
+		return cherry.ErrUnableResetPassword()
+	}
+
+	link, err := u.svc.DB.GetLinkForUser(ctx, models.LinkTypePwdChange, user)
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return cherry.ErrUnableResetPassword()
+	}
+	if link != nil {
+		if err := u.checkLinkResendCount(link); err != nil {
+			u.log.WithError(err)
+			return err
+		}
+		if err := u.checkLinkResendTime(link); err != nil {
+			u.log.WithError(err)
+			return cherry.ErrUnableResetPassword().AddDetailsErr(err)
+		}
+	}
 
-	var link *db.Link
 	err = u.svc.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) error {
 		var err error
-		link, err = tx.CreateLink(ctx, models.LinkTypePwdChange, 24*time.Hour, user)
-		return err
+		link, err = tx.CreateLink(ctx, models.LinkTypePwdChange, u.linkPolicy(models.LinkTypePwdChange).Lifetime, user)
+		if err != nil {
+			return err
+		}
+
+		recipient := &mttypes.Recipient{
+			ID:        user.ID,
+			Name:      user.Login,
+			Email:     user.Login,
+			Variables: map[string]interface{}{"TOKEN": link.Link},
+		}
+		if err := u.sendMail(ctx, func(ctx context.Context) error {
+			return u.svc.MailClient.SendPasswordResetMail(ctx, recipient)
+		}); err != nil {
+			u.log.WithError(err).Error("password reset email send failed")
+			return err
+		}
+
+		markLinkSent(link)
+		return tx.UpdateLink(ctx, link)
 	})
 	if err := u.handleDBError(err); err != nil {
 		u.log.WithError(err)
 		return cherry.ErrUnableResetPassword()
 	}
 
-	if err := u.svc.MailClient.SendPasswordResetMail(ctx, &mttypes.Recipient{
-		ID:        user.ID,
-		Name:      user.Login,
-		Email:     user.Login,
-		Variables: map[string]interface{}{"TOKEN": link.Link},
-	}); err != nil {
-		u.log.WithError(err).Error("password reset email send failed")
-		return err
-	}
-
 	return nil
 }
 
@@ -119,9 +219,16 @@ func (u *serverImpl) RestorePassword(ctx context.Context, request models.Passwor
 		return nil, cherry.ErrInvalidLink().AddDetailsErr(fmt.Errorf(linkNotFound, request.Link))
 	}
 
-	link.User.PasswordHash = utils.GetKey(link.User.Login, request.NewPassword, link.User.Salt)
+	if err := u.checkPasswordPolicy(ctx, link.User.Login, link.User, request.NewPassword); err != nil {
+		return nil, err
+	}
+
+	setUserPassword(link.User, request.NewPassword, false)
 	err = u.svc.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) error {
-		return tx.UpdateUser(ctx, link.User)
+		if err := tx.UpdateUser(ctx, link.User); err != nil {
+			return err
+		}
+		return u.savePasswordHistory(ctx, tx, link.User, request.NewPassword)
 	})
 	if err := u.handleDBError(err); err != nil {
 		u.log.WithError(err)
@@ -0,0 +1,130 @@
+package impl
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"git.containerum.net/ch/user-manager/pkg/db"
+	m "git.containerum.net/ch/user-manager/pkg/router/middleware"
+	cherry "git.containerum.net/ch/user-manager/pkg/umerrors"
+	"git.containerum.net/ch/user-manager/pkg/utils"
+	"git.containerum.net/ch/user-manager/pkg/validation"
+)
+
+const (
+	generatedPasswordMinLength = 10
+	generatedPasswordAttempts  = 100
+)
+
+// checkPasswordPolicy returns error listing all violated password policy rules. User is nil if account is not created yet.
+func (u *serverImpl) checkPasswordPolicy(ctx context.Context, login string, user *db.User, password string) error {
+	policy := u.cfg.PasswordPolicy
+	errs := policy.Check(login, password)
+
+	if user != nil && policy.HistorySize > 0 {
+		history, err := u.svc.DB.GetPasswordHistory(ctx, user.ID, policy.HistorySize)
+		if err := u.handleDBError(err); err != nil {
+			u.log.WithError(err)
+			return cherry.ErrUnableChangePassword()
+		}
+		// users may have no history if they changed password before history was introduced
+		reused := utils.CheckPassword(user.Login, password, user.Salt, user.PasswordHash)
+		hash := passwordHistoryKey(user, password)
+		for _, v := range history {
+			reused = reused || v == hash
+		}
+		if reused {
+			errs = append(errs, &validation.PasswordRuleError{
+				Rule:    validation.PasswordRuleNotReused,
+				Message: "password was used recently",
+			})
+		}
+	}
+
+	if u.svc.BreachedPasswords != nil {
+		breached, err := u.svc.BreachedPasswords.Contains(password)
+		if err != nil {
+			// list is a local file, so it is unlikely to be fixed by retry, better to not block users
+			u.log.WithError(err).Error("breached passwords check failed")
+		} else if breached {
+			errs = append(errs, &validation.PasswordRuleError{
+				Rule:    validation.PasswordRuleBreached,
+				Message: "password was found in data breach",
+			})
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	ret := cherry.ErrPasswordPolicyViolated().AddDetailsErr(errs...)
+	for _, v := range errs {
+		if ruleErr, ok := v.(*validation.PasswordRuleError); ok {
+			ret.WithField(ruleErr.Rule, ruleErr.Message)
+		}
+	}
+	return ret
+}
+
+// setUserPassword updates password hash and password rotation state.
+func setUserPassword(user *db.User, password string, mustChange bool) {
+	user.PasswordHash = utils.GetKey(user.Login, password, user.Salt)
+	user.PasswordChangedAt = time.Now().UTC()
+	user.MustChangePassword = mustChange
+}
+
+// passwordExpiresAt returns time when user must change password. Zero time is returned if password never expires.
+func (u *serverImpl) passwordExpiresAt(user *db.User) time.Time {
+	if u.cfg.PasswordMaxAge <= 0 || user.Role == m.RoleService {
+		return time.Time{}
+	}
+	return user.PasswordChangedAt.Add(u.cfg.PasswordMaxAge)
+}
+
+// checkPasswordRotation returns error if user must change password before login.
+func (u *serverImpl) checkPasswordRotation(user *db.User) error {
+	if user.MustChangePassword {
+		return cherry.ErrPasswordChangeRequired()
+	}
+	if expiresAt := u.passwordExpiresAt(user); !expiresAt.IsZero() && time.Now().After(expiresAt) {
+		return cherry.ErrPasswordChangeRequired().AddDetailF("password expired at %s", expiresAt.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// passwordHistoryKey returns password hash saved to history. Unlike password hash it doesn't depend on login,
+// so history is kept if login is changed.
+func passwordHistoryKey(user *db.User, password string) string {
+	return utils.GetKey(user.ID, password, user.Salt)
+}
+
+// savePasswordHistory remembers current user password if password reuse is restricted.
+func (u *serverImpl) savePasswordHistory(ctx context.Context, tx db.DB, user *db.User, password string) error {
+	if u.cfg.PasswordPolicy.HistorySize <= 0 {
+		return nil
+	}
+	return tx.AddPasswordHistory(ctx, user.ID, passwordHistoryKey(user, password), u.cfg.PasswordPolicy.HistorySize)
+}
+
+// generatePassword returns random password satisfying password policy.
+func (u *serverImpl) generatePassword(login string) (string, error) {
+	length := u.cfg.PasswordPolicy.MinLength
+	if length < generatedPasswordMinLength {
+		length = generatedPasswordMinLength
+	}
+	for i := 0; i < generatedPasswordAttempts; i++ {
+		password, err := utils.SecureRandomString(length)
+		if err != nil {
+			return "", err
+		}
+		// random string contains only letters and digits
+		if u.cfg.PasswordPolicy.RequireSymbol {
+			password += "-"
+		}
+		if len(u.cfg.PasswordPolicy.Check(login, password)) == 0 {
+			return password, nil
+		}
+	}
+	return "", errors.New("unable to generate password satisfying policy")
+}
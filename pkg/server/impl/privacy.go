@@ -0,0 +1,64 @@
+package impl
+
+import (
+	"context"
+	"time"
+
+	cherry "git.containerum.net/ch/user-manager/pkg/umerrors"
+	cherrypkg "github.com/containerum/cherry"
+)
+
+// hideTiming delays response until minimal response time passes, so response time doesn't reveal account existence.
+// Should be deferred at the beginning of the method.
+func (u *serverImpl) hideTiming(start time.Time) {
+	if !u.cfg.PrivacyMode {
+		return
+	}
+	time.Sleep(time.Until(start.Add(u.cfg.PrivacyResponseTime)))
+}
+
+// privacyMailTimeout limits sending of mail detached from request in privacy mode.
+const privacyMailTimeout = time.Minute
+
+// detachedContext keeps values of parent context but is never canceled with it.
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+// sendMail sends mail in background in privacy mode, so mail service latency and errors don't reveal account existence.
+// Otherwise mail is sent before return and send error is returned.
+func (u *serverImpl) sendMail(ctx context.Context, send func(ctx context.Context) error) error {
+	if !u.cfg.PrivacyMode {
+		return send(ctx)
+	}
+	// request headers are kept in context values and forwarded to mail service
+	ctx, cancel := context.WithTimeout(detachedContext{ctx}, privacyMailTimeout)
+	go func() {
+		defer cancel()
+		if err := send(ctx); err != nil {
+			u.log.WithError(err).Error("mail send failed")
+		}
+	}()
+	return nil
+}
+
+// concealAccountState hides errors which may reveal account existence or state in privacy mode.
+// Errors caused by request itself are returned as is.
+func (u *serverImpl) concealAccountState(err error) error {
+	if !u.cfg.PrivacyMode || err == nil {
+		return err
+	}
+	if cherrypkg.In(err,
+		cherry.ErrCaptchaRequired(),
+		cherry.ErrInvalidRecaptcha(),
+		cherry.ErrIPBlocked(),
+		cherry.ErrRequestValidationFailed()) {
+		return err
+	}
+	u.log.WithError(err).Infoln("Error hidden in privacy mode")
+	return nil
+}
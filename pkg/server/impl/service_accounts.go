@@ -0,0 +1,281 @@
+package impl
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"git.containerum.net/ch/auth/proto"
+	"git.containerum.net/ch/user-manager/pkg/db"
+	"git.containerum.net/ch/user-manager/pkg/models"
+	m "git.containerum.net/ch/user-manager/pkg/router/middleware"
+	cherry "git.containerum.net/ch/user-manager/pkg/umerrors"
+	"git.containerum.net/ch/user-manager/pkg/utils"
+	"github.com/containerum/utils/httputil"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	apiKeyScheme       = "umk"
+	apiKeyPrefixLength = 8
+	apiKeySecretLength = 40
+)
+
+// generateAPIKey returns full key value in format "umk_<prefix>_<secret>", its prefix and hash for storing.
+func generateAPIKey() (key, prefix, hash string, err error) {
+	if prefix, err = utils.SecureRandomString(apiKeyPrefixLength); err != nil {
+		return
+	}
+	secret, err := utils.SecureRandomString(apiKeySecretLength)
+	if err != nil {
+		return
+	}
+	key = apiKeyScheme + "_" + prefix + "_" + secret
+	return key, prefix, hashAPIKey(key), nil
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func apiKeyPrefix(key string) (string, bool) {
+	parts := strings.SplitN(key, "_", 3)
+	if len(parts) != 3 || parts[0] != apiKeyScheme || len(parts[1]) != apiKeyPrefixLength {
+		return "", false
+	}
+	return parts[1], true
+}
+
+func apiKeyToModel(key db.APIKey) models.APIKey {
+	ret := models.APIKey{
+		ID:        key.ID,
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		CreatedAt: key.CreatedAt,
+	}
+	if key.ExpiresAt.Valid {
+		ret.ExpiresAt = &key.ExpiresAt.Time
+	}
+	if key.LastUsedAt.Valid {
+		ret.LastUsedAt = &key.LastUsedAt.Time
+	}
+	return ret
+}
+
+func serviceAccountToModel(account db.ServiceAccount) models.ServiceAccount {
+	return models.ServiceAccount{
+		ID:        account.User.ID,
+		Login:     account.User.Login,
+		OwnerID:   account.OwnerID,
+		CreatedAt: account.CreatedAt,
+	}
+}
+
+// getOwnedServiceAccount returns service account if it is owned by current user. Admins have access to all service accounts.
+func (u *serverImpl) getOwnedServiceAccount(ctx context.Context, accountID string) (*db.ServiceAccount, error) {
+	account, err := u.svc.DB.GetServiceAccount(ctx, accountID)
+	if err := u.handleDBError(err); err != nil {
+		return nil, cherry.ErrUnableGetServiceAccounts()
+	}
+	if account == nil ||
+		(account.OwnerID != httputil.MustGetUserID(ctx) && httputil.MustGetUserRole(ctx) != m.RoleAdmin) {
+		return nil, cherry.ErrServiceAccountNotExist()
+	}
+	return account, nil
+}
+
+func (u *serverImpl) CreateServiceAccount(ctx context.Context, request models.ServiceAccountCreateRequest) (*models.ServiceAccount, error) {
+	ownerID := httputil.MustGetUserID(ctx)
+	u.log.WithFields(logrus.Fields{
+		"name":     request.Name,
+		"owner_id": ownerID,
+	}).Info("creating service account")
+
+	user, err := u.svc.DB.GetAnyUserByLogin(ctx, request.Name)
+	if err := u.handleDBError(err); err != nil {
+		return nil, cherry.ErrUnableCreateServiceAccount()
+	}
+	if user != nil {
+		return nil, cherry.ErrUserAlreadyExists()
+	}
+
+	// service accounts can't login with password, so it's random and never shown
+	password, err := utils.SecureRandomString(32)
+	if err != nil {
+		u.log.WithError(err)
+		return nil, cherry.ErrUnableCreateServiceAccount()
+	}
+	salt := utils.GenSalt(request.Name, request.Name, request.Name)
+	account := &db.ServiceAccount{
+		OwnerID: ownerID,
+		User: &db.User{
+			Login:        request.Name,
+			PasswordHash: utils.GetKey(request.Name, password, salt),
+			Salt:         salt,
+			Role:         m.RoleService,
+			IsActive:     true,
+		},
+	}
+
+	err = u.svc.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) error {
+		if createErr := tx.CreateUser(ctx, account.User); createErr != nil {
+			return createErr
+		}
+		if createErr := tx.CreateProfile(ctx, &db.Profile{
+			User:      account.User,
+			Access:    sql.NullString{String: "rw", Valid: true},
+			CreatedAt: pq.NullTime{Time: time.Now().UTC(), Valid: true},
+		}); createErr != nil {
+			return createErr
+		}
+		return tx.CreateServiceAccount(ctx, account)
+	})
+	if err := u.handleDBError(err); err != nil {
+		return nil, cherry.ErrUnableCreateServiceAccount()
+	}
+
+	ret := serviceAccountToModel(*account)
+	return &ret, nil
+}
+
+func (u *serverImpl) GetServiceAccounts(ctx context.Context) (*models.ServiceAccounts, error) {
+	ownerID := httputil.MustGetUserID(ctx)
+	u.log.WithField("owner_id", ownerID).Info("get service accounts")
+
+	accounts, err := u.svc.DB.GetServiceAccountsByOwner(ctx, ownerID)
+	if err := u.handleDBError(err); err != nil {
+		return nil, cherry.ErrUnableGetServiceAccounts()
+	}
+
+	resp := models.ServiceAccounts{ServiceAccounts: []models.ServiceAccount{}}
+	for _, v := range accounts {
+		resp.ServiceAccounts = append(resp.ServiceAccounts, serviceAccountToModel(v))
+	}
+	return &resp, nil
+}
+
+func (u *serverImpl) CreateAPIKey(ctx context.Context, accountID string, request models.APIKeyCreateRequest) (*models.APIKey, error) {
+	u.log.WithFields(logrus.Fields{
+		"account_id": accountID,
+		"name":       request.Name,
+	}).Info("creating API key")
+
+	account, err := u.getOwnedServiceAccount(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	fullKey, prefix, hash, err := generateAPIKey()
+	if err != nil {
+		u.log.WithError(err)
+		return nil, cherry.ErrUnableCreateAPIKey()
+	}
+	key := &db.APIKey{
+		UserID:  account.User.ID,
+		Name:    request.Name,
+		Prefix:  prefix,
+		KeyHash: hash,
+	}
+	if request.ExpiresAt != nil {
+		key.ExpiresAt = pq.NullTime{Time: request.ExpiresAt.UTC(), Valid: true}
+	}
+
+	err = u.svc.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) error {
+		return tx.CreateAPIKey(ctx, key)
+	})
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return nil, cherry.ErrUnableCreateAPIKey().AddDetailsErr(err)
+	}
+
+	ret := apiKeyToModel(*key)
+	ret.Key = fullKey
+	return &ret, nil
+}
+
+func (u *serverImpl) GetAPIKeys(ctx context.Context, accountID string) (*models.APIKeys, error) {
+	u.log.WithField("account_id", accountID).Info("get API keys")
+
+	account, err := u.getOwnedServiceAccount(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := u.svc.DB.GetAPIKeys(ctx, account.User.ID)
+	if err := u.handleDBError(err); err != nil {
+		return nil, cherry.ErrUnableGetAPIKeys()
+	}
+
+	resp := models.APIKeys{Keys: []models.APIKey{}}
+	for _, v := range keys {
+		resp.Keys = append(resp.Keys, apiKeyToModel(v))
+	}
+	return &resp, nil
+}
+
+func (u *serverImpl) RevokeAPIKey(ctx context.Context, accountID, keyID string) error {
+	u.log.WithFields(logrus.Fields{
+		"account_id": accountID,
+		"key_id":     keyID,
+	}).Info("revoking API key")
+
+	account, err := u.getOwnedServiceAccount(ctx, accountID)
+	if err != nil {
+		return err
+	}
+
+	err = u.svc.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) error {
+		return tx.DeleteAPIKey(ctx, account.User.ID, keyID)
+	})
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return cherry.ErrUnableRevokeAPIKey().AddDetailsErr(err)
+	}
+	return nil
+}
+
+func (u *serverImpl) APIKeyLogin(ctx context.Context, request models.APIKeyLoginRequest) (*authProto.CreateTokenResponse, error) {
+	u.log.Info("API key login")
+
+	prefix, ok := apiKeyPrefix(request.APIKey)
+	if !ok {
+		return nil, cherry.ErrInvalidAPIKey()
+	}
+
+	key, err := u.svc.DB.GetAPIKeyByPrefix(ctx, prefix)
+	if err := u.handleDBError(err); err != nil {
+		return nil, cherry.ErrLoginFailed()
+	}
+	if key == nil ||
+		subtle.ConstantTimeCompare([]byte(key.KeyHash), []byte(hashAPIKey(request.APIKey))) != 1 ||
+		(key.ExpiresAt.Valid && !key.ExpiresAt.Time.After(time.Now().UTC())) {
+		u.log.WithField("prefix", prefix).Info("invalid or expired API key")
+		return nil, cherry.ErrInvalidAPIKey()
+	}
+
+	account, err := u.svc.DB.GetServiceAccount(ctx, key.UserID)
+	if err := u.handleDBError(err); err != nil {
+		return nil, cherry.ErrLoginFailed()
+	}
+	if account == nil {
+		return nil, cherry.ErrInvalidAPIKey()
+	}
+	if err := u.loginUserChecks(account.User); err != nil {
+		return nil, err
+	}
+
+	err = u.svc.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) error {
+		return tx.UpdateAPIKeyLastUsed(ctx, key.ID)
+	})
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err).Warnln("Unable to update API key last usage")
+	}
+
+	return u.createTokens(ctx, account.User)
+}
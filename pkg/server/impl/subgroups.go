@@ -0,0 +1,98 @@
+package impl
+
+import (
+	"context"
+
+	"git.containerum.net/ch/user-manager/pkg/db"
+	"git.containerum.net/ch/user-manager/pkg/models"
+	cherry "git.containerum.net/ch/user-manager/pkg/umerrors"
+	kube_types "github.com/containerum/kube-client/pkg/model"
+	"github.com/sirupsen/logrus"
+)
+
+func (u *serverImpl) AddSubgroup(ctx context.Context, group kube_types.UserGroup, request models.SubgroupAddRequest) error {
+	u.log.WithFields(logrus.Fields{
+		"group_id": group.ID,
+		"subgroup": request.Label,
+		"access":   request.Access,
+	}).Info("adding subgroup")
+
+	subgroup, err := u.svc.DB.GetGroupByLabel(ctx, request.Label)
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return cherry.ErrUnableAddSubgroup()
+	}
+	if subgroup == nil {
+		return cherry.ErrGroupNotExist().AddDetails(request.Label)
+	}
+	parent, err := u.svc.DB.GetGroupByID(ctx, group.ID)
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return cherry.ErrUnableAddSubgroup()
+	}
+	// groups of other organizations are not visible
+	if parent == nil || parent.OrgID != subgroup.OrgID {
+		return cherry.ErrGroupNotExist().AddDetails(request.Label)
+	}
+	if subgroup.ID == group.ID {
+		return cherry.ErrGroupCycle()
+	}
+
+	err = u.svc.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) error {
+		return tx.AddSubgroup(ctx, group.ID, subgroup.ID, request.Access)
+	})
+	if err == db.ErrGroupCycle {
+		return cherry.ErrGroupCycle().AddDetails(request.Label)
+	}
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return cherry.ErrUnableAddSubgroup()
+	}
+	return nil
+}
+
+func (u *serverImpl) GetSubgroups(ctx context.Context, group kube_types.UserGroup) (*models.Subgroups, error) {
+	u.log.WithField("group_id", group.ID).Info("get subgroups")
+
+	subgroups, err := u.svc.DB.GetSubgroups(ctx, group.ID)
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return nil, cherry.ErrUnableGetSubgroups()
+	}
+
+	resp := models.Subgroups{Subgroups: []models.Subgroup{}}
+	for _, v := range subgroups {
+		resp.Subgroups = append(resp.Subgroups, models.Subgroup{
+			ID:      v.ChildID,
+			Label:   v.Label,
+			Access:  v.Access,
+			AddedAt: v.AddedAt,
+		})
+	}
+	return &resp, nil
+}
+
+func (u *serverImpl) DeleteSubgroup(ctx context.Context, group kube_types.UserGroup, subgroupLabel string) error {
+	u.log.WithField("group_id", group.ID).WithField("subgroup", subgroupLabel).Info("deleting subgroup")
+
+	subgroup, err := u.svc.DB.GetGroupByLabel(ctx, subgroupLabel)
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return cherry.ErrUnableDeleteSubgroup()
+	}
+	if subgroup == nil {
+		return cherry.ErrGroupNotExist().AddDetails(subgroupLabel)
+	}
+
+	err = u.svc.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) error {
+		return tx.DeleteSubgroup(ctx, group.ID, subgroup.ID)
+	})
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		if err.Error() == "group is not a subgroup" {
+			return cherry.ErrNotSubgroup().AddDetails(subgroupLabel)
+		}
+		return cherry.ErrUnableDeleteSubgroup()
+	}
+	return nil
+}
@@ -0,0 +1,265 @@
+package impl
+
+import (
+	"context"
+	"expvar"
+	"sync"
+	"time"
+
+	"git.containerum.net/ch/user-manager/pkg/db"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	userCacheHits          = expvar.NewInt("user_cache_hits")
+	userCacheMisses        = expvar.NewInt("user_cache_misses")
+	userCacheInvalidations = expvar.NewInt("user_cache_invalidations")
+)
+
+func init() {
+	expvar.Publish("user_cache_hit_rate", expvar.Func(func() interface{} {
+		hits, misses := userCacheHits.Value(), userCacheMisses.Value()
+		if hits+misses == 0 {
+			return 0.0
+		}
+		return float64(hits) / float64(hits+misses)
+	}))
+}
+
+type userCacheEntry struct {
+	user      db.User
+	expiresAt time.Time
+}
+
+// userCache keeps users for permission checks performed on each request.
+// Methods of nil cache are no-op.
+type userCache struct {
+	ttl     time.Duration
+	size    int
+	mu      sync.Mutex
+	entries map[string]userCacheEntry
+	// generation is incremented on each invalidation so users read from db before it are not cached
+	generation uint64
+}
+
+// newUserCache returns nil if ttl or size is not positive.
+func newUserCache(ttl time.Duration, size int) *userCache {
+	if ttl <= 0 || size <= 0 {
+		return nil
+	}
+	return &userCache{
+		ttl:     ttl,
+		size:    size,
+		entries: make(map[string]userCacheEntry),
+	}
+}
+
+// get returns copy of cached user and current generation which should be passed to put.
+func (c *userCache) get(userID string) (*db.User, uint64) {
+	if c == nil {
+		return nil, 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[userID]
+	if !ok || !time.Now().Before(entry.expiresAt) {
+		userCacheMisses.Add(1)
+		return nil, c.generation
+	}
+	userCacheHits.Add(1)
+	user := entry.user
+	return &user, c.generation
+}
+
+func (c *userCache) put(user *db.User, generation uint64) {
+	if c == nil || user == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation != c.generation {
+		return
+	}
+	now := time.Now()
+	if _, ok := c.entries[user.ID]; !ok && len(c.entries) >= c.size {
+		for id, entry := range c.entries {
+			if !now.Before(entry.expiresAt) {
+				delete(c.entries, id)
+			}
+		}
+		// no expired entries, evict random one
+		for id := range c.entries {
+			if len(c.entries) < c.size {
+				break
+			}
+			delete(c.entries, id)
+		}
+	}
+	c.entries[user.ID] = userCacheEntry{user: *user, expiresAt: now.Add(c.ttl)}
+}
+
+func (c *userCache) invalidate(userID string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	delete(c.entries, userID)
+	userCacheInvalidations.Add(1)
+}
+
+func (c *userCache) reset() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	c.entries = make(map[string]userCacheEntry)
+	userCacheInvalidations.Add(1)
+}
+
+// userCacheDB invalidates cached users after their modification and optionally broadcasts changes to other replicas.
+type userCacheDB struct {
+	db.DB
+	cache  *userCache
+	notify bool
+	log    *logrus.Entry
+	// changed collects users modified inside transaction, they are invalidated after transaction end
+	changed *[]string
+}
+
+func (c *userCacheDB) userChanged(ctx context.Context, userID string) {
+	if c.changed != nil {
+		*c.changed = append(*c.changed, userID)
+	} else {
+		c.cache.invalidate(userID)
+	}
+	if c.notify {
+		if err := c.DB.NotifyUserChanged(ctx, userID); err != nil {
+			c.log.WithError(err).WithField("user_id", userID).Warnln("Unable to broadcast user change")
+		}
+	}
+}
+
+func (c *userCacheDB) UpdateUser(ctx context.Context, user *db.User) error {
+	if err := c.DB.UpdateUser(ctx, user); err != nil {
+		return err
+	}
+	c.userChanged(ctx, user.ID)
+	return nil
+}
+
+func (c *userCacheDB) UpdateUserWOContext(user *db.User) error {
+	if err := c.DB.UpdateUserWOContext(user); err != nil {
+		return err
+	}
+	c.userChanged(context.Background(), user.ID)
+	return nil
+}
+
+func (c *userCacheDB) BlacklistUser(ctx context.Context, user *db.User) error {
+	if err := c.DB.BlacklistUser(ctx, user); err != nil {
+		return err
+	}
+	c.userChanged(ctx, user.ID)
+	return nil
+}
+
+func (c *userCacheDB) UnBlacklistUser(ctx context.Context, user *db.User) error {
+	if err := c.DB.UnBlacklistUser(ctx, user); err != nil {
+		return err
+	}
+	c.userChanged(ctx, user.ID)
+	return nil
+}
+
+func (c *userCacheDB) SetUserOrganization(ctx context.Context, userID, orgID string, isOrgAdmin bool) error {
+	if err := c.DB.SetUserOrganization(ctx, userID, orgID, isOrgAdmin); err != nil {
+		return err
+	}
+	c.userChanged(ctx, userID)
+	return nil
+}
+
+// granted roles are not cached yet, users are invalidated anyway so permissions may be cached with them
+
+func (c *userCacheDB) GrantAdminRole(ctx context.Context, userID, roleName string) error {
+	if err := c.DB.GrantAdminRole(ctx, userID, roleName); err != nil {
+		return err
+	}
+	c.userChanged(ctx, userID)
+	return nil
+}
+
+func (c *userCacheDB) RevokeAdminRole(ctx context.Context, userID, roleName string) error {
+	if err := c.DB.RevokeAdminRole(ctx, userID, roleName); err != nil {
+		return err
+	}
+	c.userChanged(ctx, userID)
+	return nil
+}
+
+func (c *userCacheDB) Transactional(ctx context.Context, f func(ctx context.Context, tx db.DB) error) error {
+	var changed []string
+	err := c.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) error {
+		return f(ctx, &userCacheDB{
+			DB:      tx,
+			cache:   c.cache,
+			notify:  c.notify,
+			log:     c.log,
+			changed: &changed,
+		})
+	})
+	// invalidate even if transaction was rolled back: user may be read from db while transaction was running
+	for _, userID := range changed {
+		if c.changed != nil {
+			*c.changed = append(*c.changed, userID)
+		} else {
+			c.cache.invalidate(userID)
+		}
+	}
+	return err
+}
+
+// getCachedUser returns user for permission checks. User may be up to cache TTL old if changed on other replica
+// and changes broadcasting is disabled.
+func (u *serverImpl) getCachedUser(ctx context.Context, userID string) (*db.User, error) {
+	user, generation := u.users.get(userID)
+	if user != nil {
+		return user, nil
+	}
+	user, err := u.svc.DB.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	u.users.put(user, generation)
+	return user, nil
+}
+
+// RunUserCacheListener invalidates cached users changed on other replicas until ctx is done.
+func (u *serverImpl) RunUserCacheListener(ctx context.Context) {
+	if !u.cfg.UserCacheNotify {
+		return
+	}
+	for {
+		err := u.svc.DB.ListenUserChanges(ctx, func(userID string) {
+			if userID == "" {
+				u.users.reset()
+				return
+			}
+			u.users.invalidate(userID)
+		})
+		// changes may be missed while not listening
+		u.users.reset()
+		if err != nil {
+			u.log.WithError(err).Warnln("User changes listening failed")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(10 * time.Second):
+		}
+	}
+}
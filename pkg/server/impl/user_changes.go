@@ -3,7 +3,6 @@ package impl
 import (
 	"context"
 
-	"strings"
 	"time"
 
 	"database/sql"
@@ -24,20 +23,22 @@ import (
 
 func (u *serverImpl) CreateUser(ctx context.Context, request models.RegisterRequest) (*models.UserLogin, error) {
 	u.log.WithField("login", request.Login).Info("creating user")
-	if err := u.checkReCaptcha(ctx, request.ReCaptcha); err != nil {
+	defer u.hideTiming(time.Now())
+	if err := u.checkClientIP(ctx); err != nil {
+		return nil, err
+	}
+	if err := u.checkCaptcha(ctx, request.ReCaptcha); err != nil {
 		u.log.WithError(err)
 		return nil, cherry.ErrInvalidRecaptcha()
 	}
 
-	domain := strings.Split(request.Login, "@")[1]
-	blacklisted, err := u.svc.DB.IsDomainBlacklisted(ctx, domain)
-	if err := u.handleDBError(err); err != nil {
-		u.log.WithError(err)
-		return nil, cherry.ErrUnableCreateUser()
+	if err := u.checkEmailDomain(ctx, request.Login); err != nil {
+		return nil, err
 	}
-	if blacklisted {
-		u.log.WithError(fmt.Errorf(domainInBlacklist, domain))
-		return nil, cherry.ErrUnableCreateUser().AddDetailsErr(fmt.Errorf(domainInBlacklist, domain))
+
+	// checked before account lookup, so policy violation doesn't depend on account existence
+	if err := u.checkPasswordPolicy(ctx, request.Login, nil, request.Password); err != nil {
+		return nil, err
 	}
 
 	user, err := u.svc.DB.GetAnyUserByLogin(ctx, request.Login)
@@ -58,12 +59,30 @@ func (u *serverImpl) CreateUser(ctx context.Context, request models.RegisterRequ
 			newUser = user
 			newUser.IsDeleted = false
 			newUser.IsActive = false
+		} else if u.cfg.PrivacyMode {
+			u.log.WithError(cherry.ErrUserAlreadyExists()).Infoln("Error hidden in privacy mode")
+			recipient := &mttypes.Recipient{
+				ID:    user.ID,
+				Name:  user.Login,
+				Email: user.Login,
+			}
+			u.sendMail(ctx, func(ctx context.Context) error {
+				return u.svc.MailClient.SendAlreadyRegisteredMail(ctx, recipient)
+			})
+			return &models.UserLogin{Login: request.Login}, nil
 		} else {
 			u.log.WithError(cherry.ErrUserAlreadyExists())
 			return nil, cherry.ErrUserAlreadyExists()
 		}
 	}
 
+	if reactivatingOldUser {
+		// password history of previous account
+		if err := u.checkPasswordPolicy(ctx, request.Login, user, request.Password); err != nil {
+			return nil, err
+		}
+	}
+
 	salt := utils.GenSalt(request.Login, request.Login, request.Login) // compatibility with old client db
 	passwordHash := utils.GetKey(request.Login, request.Password, salt)
 	if !reactivatingOldUser {
@@ -78,6 +97,8 @@ func (u *serverImpl) CreateUser(ctx context.Context, request models.RegisterRequ
 	} else {
 		newUser.Salt = salt
 		newUser.PasswordHash = passwordHash
+		newUser.PasswordChangedAt = time.Now().UTC()
+		newUser.MustChangePassword = false
 	}
 
 	var link *db.Link
@@ -100,9 +121,18 @@ func (u *serverImpl) CreateUser(ctx context.Context, request models.RegisterRequ
 			if createErr := tx.UpdateUser(ctx, newUser); createErr != nil {
 				return err
 			}
+			if dropErr := tx.DropDeletedGroupMembers(ctx, newUser.ID); dropErr != nil {
+				return dropErr
+			}
+			if updErr := tx.UpdateDeletedAt(ctx, newUser.ID, pq.NullTime{}); updErr != nil {
+				return updErr
+			}
+		}
+		if histErr := u.savePasswordHistory(ctx, tx, newUser, request.Password); histErr != nil {
+			return histErr
 		}
 
-		link, err = tx.CreateLink(ctx, models.LinkTypeConfirm, 24*time.Hour, newUser)
+		link, err = tx.CreateLink(ctx, models.LinkTypeConfirm, u.linkPolicy(models.LinkTypeConfirm).Lifetime, newUser)
 		if err != nil {
 			return err
 		}
@@ -127,6 +157,10 @@ func (u *serverImpl) CreateUser(ctx context.Context, request models.RegisterRequ
 		}
 	}
 
+	if u.cfg.PrivacyMode {
+		// response must not differ from response for registered email
+		return &models.UserLogin{Login: newUser.Login}, nil
+	}
 	return &models.UserLogin{
 		ID:    newUser.ID,
 		Login: newUser.Login,
@@ -181,6 +215,8 @@ func (u *serverImpl) ActivateUser(ctx context.Context, request models.Link) (*au
 		u.log.WithError(err).Warnln("Unable to add event")
 	}
 
+	u.applyPendingGroupInvites(ctx, link.User)
+
 	if u.svc.TelegramClient != nil {
 		err := u.svc.TelegramClient.SendActivationMessage(ctx, link.User.Login)
 		if err != nil {
@@ -211,6 +247,9 @@ func (u *serverImpl) BlacklistUser(ctx context.Context, request models.UserLogin
 	if user.Role == m.RoleAdmin {
 		return cherry.ErrRequestValidationFailed().AddDetails(blacklistAdmin)
 	}
+	if err := u.checkUserInScope(ctx, user); err != nil {
+		return err
+	}
 
 	err = u.svc.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) error {
 		return tx.BlacklistUser(ctx, user)
@@ -259,6 +298,9 @@ func (u *serverImpl) UnBlacklistUser(ctx context.Context, request models.UserLog
 		u.log.WithError(cherry.ErrUserNotExist())
 		return cherry.ErrUserNotExist()
 	}
+	if err := u.checkUserInScope(ctx, user); err != nil {
+		return err
+	}
 	if !user.IsInBlacklist {
 		u.log.WithError(cherry.ErrUserNotBlacklisted())
 		return cherry.ErrUserNotBlacklisted()
@@ -348,8 +390,17 @@ func (u *serverImpl) PartiallyDeleteUser(ctx context.Context) error {
 	}
 
 	user.IsDeleted = true
+	var link *db.Link
 	err = u.svc.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) error {
-		return tx.UpdateUser(ctx, user)
+		if err := tx.UpdateUser(ctx, user); err != nil {
+			return err
+		}
+		if err := tx.UpdateDeletedAt(ctx, user.ID, pq.NullTime{Time: time.Now().UTC(), Valid: true}); err != nil {
+			return err
+		}
+		var err error
+		link, err = tx.CreateLink(ctx, models.LinkTypeDelete, u.linkPolicy(models.LinkTypeDelete).Lifetime, user)
+		return err
 	})
 	if err := u.handleDBError(err); err != nil {
 		u.log.WithError(err)
@@ -368,6 +419,10 @@ func (u *serverImpl) PartiallyDeleteUser(ctx context.Context) error {
 	}
 
 	err = u.svc.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) error {
+		// remember memberships to bring them back if user restores account
+		if err := tx.SaveDeletedGroupMembers(ctx, user.ID); err != nil {
+			return err
+		}
 		return tx.DeleteGroupMemberFromAllGroups(ctx, user.ID)
 	})
 	if err := u.handleDBError(err); err != nil {
@@ -376,9 +431,10 @@ func (u *serverImpl) PartiallyDeleteUser(ctx context.Context) error {
 	}
 
 	if err := u.svc.MailClient.SendAccDeletedMail(ctx, &mttypes.Recipient{
-		ID:    user.ID,
-		Name:  user.Login,
-		Email: user.Login,
+		ID:        user.ID,
+		Name:      user.Login,
+		Email:     user.Login,
+		Variables: map[string]interface{}{"RESTORE": link.Link},
 	}); err != nil {
 		u.log.WithError(err).Error("delete account email send failed")
 	}
@@ -401,12 +457,29 @@ func (u *serverImpl) CompletelyDeleteUser(ctx context.Context, userID string) er
 		u.log.WithError(cherry.ErrUserNotExist())
 		return cherry.ErrUserNotExist()
 	}
+	if err := u.checkAdminTarget(ctx, user); err != nil {
+		return err
+	}
 	if !user.IsDeleted {
 		u.log.WithError(cherry.ErrUnableDeleteUser())
 		return cherry.ErrUnableDeleteUser()
 	}
+	link, err := u.svc.DB.GetLinkForUser(ctx, models.LinkTypeDelete, user)
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return cherry.ErrUnableDeleteUser()
+	}
 	err = u.svc.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) error {
-		return tx.DeleteGroupMemberFromAllGroups(ctx, user.ID)
+		if err := tx.DeleteGroupMemberFromAllGroups(ctx, user.ID); err != nil {
+			return err
+		}
+		if link != nil {
+			link.IsActive = false
+			if err := tx.UpdateLink(ctx, link); err != nil {
+				return err
+			}
+		}
+		return tx.DropDeletedGroupMembers(ctx, user.ID)
 	})
 	if err := u.handleDBError(err); err != nil {
 		u.log.WithError(err)
@@ -424,3 +497,81 @@ func (u *serverImpl) CompletelyDeleteUser(ctx context.Context, userID string) er
 	}
 	return nil
 }
+
+func (u *serverImpl) RestoreUser(ctx context.Context, request models.Link) (*authProto.CreateTokenResponse, error) {
+	u.log.Info("restoring user")
+	u.log.WithField("link", request.Link).Debugln("restoring user details")
+	link, err := u.svc.DB.GetLinkFromString(ctx, request.Link)
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return nil, cherry.ErrUnableRestoreUser()
+	}
+	if link == nil {
+		u.log.WithError(fmt.Errorf(linkNotFound, request.Link))
+		return nil, cherry.ErrInvalidLink().AddDetailsErr(fmt.Errorf(linkNotFound, request.Link))
+	} else if link.Type != models.LinkTypeDelete {
+		u.log.WithError(fmt.Errorf(linkNotFound, request.Link))
+		return nil, cherry.ErrInvalidLink().AddDetailsErr(fmt.Errorf(linkNotFound, request.Link))
+	}
+
+	if err := u.restoreUser(ctx, link.User, link); err != nil {
+		return nil, err
+	}
+
+	return u.createTokens(ctx, link.User)
+}
+
+// restoreUser brings back partially deleted user and his group memberships.
+// Link (if not nil) is deactivated in the same transaction.
+func (u *serverImpl) restoreUser(ctx context.Context, user *db.User, link *db.Link) error {
+	if !user.IsDeleted {
+		u.log.WithError(cherry.ErrUserNotDeleted())
+		return cherry.ErrUserNotDeleted()
+	}
+	if user.IsInBlacklist {
+		u.log.WithError(cherry.ErrAccountBlocked())
+		return cherry.ErrAccountBlocked()
+	}
+
+	profile, err := u.svc.DB.GetProfileByUser(ctx, user)
+	if err := u.handleDBError(err); err != nil || profile == nil {
+		u.log.WithError(err)
+		return cherry.ErrUnableRestoreUser()
+	}
+	if profile.DeletedAt.Valid && time.Now().UTC().Sub(profile.DeletedAt.Time) > u.cfg.RestorePeriod {
+		u.log.WithError(fmt.Errorf(restorePeriodExpired, u.cfg.RestorePeriod))
+		return cherry.ErrRestorePeriodExpired().AddDetailsErr(fmt.Errorf(restorePeriodExpired, u.cfg.RestorePeriod))
+	}
+
+	var groups map[string]string
+	user.IsDeleted = false
+	err = u.svc.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) error {
+		if err := tx.UpdateUser(ctx, user); err != nil {
+			return err
+		}
+		if err := tx.UpdateDeletedAt(ctx, user.ID, pq.NullTime{}); err != nil {
+			return err
+		}
+		if link != nil {
+			link.IsActive = false
+			if err := tx.UpdateLink(ctx, link); err != nil {
+				return err
+			}
+		}
+		var err error
+		groups, err = tx.RestoreDeletedGroupMembers(ctx, user.ID)
+		return err
+	})
+	if err := u.handleDBError(err); err != nil {
+		u.log.WithError(err)
+		return cherry.ErrUnableRestoreUser()
+	}
+
+	for group := range groups {
+		if err := u.svc.EventsClient.UserAddedToGroup(ctx, user.Login, group); err != nil {
+			u.log.WithError(err).Warnln("Unable to add event")
+		}
+	}
+
+	return nil
+}
@@ -24,6 +24,10 @@ func (u *serverImpl) GetUserLinks(ctx context.Context, userID string) (*models.L
 		u.log.WithError(cherry.ErrUserNotExist())
 		return nil, cherry.ErrUserNotExist()
 	}
+	// links may be used to login, so they are hidden from users which can't manage this account
+	if err := u.checkAdminTarget(ctx, user); err != nil {
+		return nil, err
+	}
 
 	links, err := u.svc.DB.GetUserLinks(ctx, user)
 	if err := u.handleDBError(err); err != nil {
@@ -37,12 +41,13 @@ func (u *serverImpl) GetUserLinks(ctx context.Context, userID string) (*models.L
 			sentAt = v.SentAt.Time
 		}
 		resp.Links = append(resp.Links, models.Link{
-			Link:      v.Link,
-			Type:      v.Type,
-			CreatedAt: v.CreatedAt,
-			ExpiredAt: v.ExpiredAt,
-			IsActive:  v.IsActive,
-			SentAt:    sentAt,
+			Link:        v.Link,
+			Type:        v.Type,
+			CreatedAt:   v.CreatedAt,
+			ExpiredAt:   v.ExpiredAt,
+			IsActive:    v.IsActive,
+			SentAt:      sentAt,
+			ResendCount: v.ResendCount,
 		})
 	}
 
@@ -69,6 +74,11 @@ func (u *serverImpl) GetUserInfo(ctx context.Context) (*models.User, error) {
 		return nil, cherry.ErrUnableGetUserInfo()
 	}
 
+	adminRoles, err := u.svc.DB.GetUserAdminRoles(ctx, user.ID)
+	if err := u.handleDBError(err); err != nil {
+		return nil, cherry.ErrUnableGetUserInfo()
+	}
+
 	ret := models.User{
 		UserLogin: &models.UserLogin{
 			Login: user.Login,
@@ -78,8 +88,16 @@ func (u *serverImpl) GetUserInfo(ctx context.Context) (*models.User, error) {
 			Data:      profile.Data,
 			CreatedAt: profile.CreatedAt.Time.Format(time.RFC3339),
 		},
-		Role:     user.Role,
-		IsActive: user.IsActive,
+		Role:       user.Role,
+		IsActive:   user.IsActive,
+		OrgID:      user.OrgID,
+		IsOrgAdmin: user.IsOrgAdmin,
+		AdminRoles: adminRoles,
+
+		MustChangePassword: user.MustChangePassword,
+	}
+	if expiresAt := u.passwordExpiresAt(user); !expiresAt.IsZero() {
+		ret.PasswordExpiresAt = &expiresAt
 	}
 	return &ret, nil
 }
@@ -143,7 +161,11 @@ func (u *serverImpl) GetUserInfoByLogin(ctx context.Context, login string) (*mod
 
 func (u *serverImpl) GetBlacklistedUsers(ctx context.Context, page int, perPage int) (*models.UserList, error) {
 	u.log.WithField("per_page", perPage).WithField("page", page).Info("get blacklisted users")
-	blacklisted, err := u.svc.DB.GetBlacklistedUsers(ctx, perPage, (page-1)*perPage)
+	orgID, err := u.orgScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	blacklisted, err := u.svc.DB.GetBlacklistedUsers(ctx, orgID, perPage, (page-1)*perPage)
 	if err := u.handleDBError(err); err != nil {
 		return nil, cherry.ErrUnableGetUsersList()
 	}
@@ -161,7 +183,11 @@ func (u *serverImpl) GetBlacklistedUsers(ctx context.Context, page int, perPage
 
 func (u *serverImpl) GetUsers(ctx context.Context, page uint, perPage uint, filters ...string) (*models.UserList, error) {
 	u.log.WithField("per_page", perPage).WithField("page", page).Info("get users")
-	profiles, totalUsers, err := u.svc.DB.GetAllProfiles(ctx, perPage, (page-1)*perPage)
+	orgID, err := u.orgScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	profiles, totalUsers, err := u.svc.DB.GetAllProfiles(ctx, orgID, perPage, (page-1)*perPage)
 	if err := u.handleDBError(err); err != nil {
 		u.log.WithError(err)
 		return nil, cherry.ErrUnableGetUsersList()
@@ -211,6 +237,8 @@ func (u *serverImpl) GetUsers(ctx context.Context, page uint, perPage uint, filt
 			IsActive:      v.User.IsActive,
 			IsInBlacklist: v.User.IsInBlacklist,
 			IsDeleted:     v.User.IsDeleted,
+			OrgID:         v.User.OrgID,
+			IsOrgAdmin:    v.User.IsOrgAdmin,
 		}
 
 		if !v.Profile.CreatedAt.Time.IsZero() {
@@ -254,6 +282,12 @@ func (u *serverImpl) GetUsersLoginID(ctx context.Context, ids []string) (*models
 
 func (u *serverImpl) LinkResend(ctx context.Context, request models.UserLogin) error {
 	u.log.WithField("login", request.Login).Info("resending link")
+	defer u.hideTiming(time.Now())
+
+	return u.concealAccountState(u.linkResend(ctx, request))
+}
+
+func (u *serverImpl) linkResend(ctx context.Context, request models.UserLogin) error {
 	user, err := u.svc.DB.GetUserByLogin(ctx, request.Login)
 	if err := u.handleDBError(err); err != nil {
 		u.log.WithError(err)
@@ -275,7 +309,7 @@ func (u *serverImpl) LinkResend(ctx context.Context, request models.UserLogin) e
 	if link == nil {
 		err := u.svc.DB.Transactional(ctx, func(ctx context.Context, tx db.DB) error {
 			var err error
-			link, err = tx.CreateLink(ctx, models.LinkTypeConfirm, 24*time.Hour, user)
+			link, err = tx.CreateLink(ctx, models.LinkTypeConfirm, u.linkPolicy(models.LinkTypeConfirm).Lifetime, user)
 			return err
 		})
 		if err := u.handleDBError(err); err != nil {
@@ -283,6 +317,10 @@ func (u *serverImpl) LinkResend(ctx context.Context, request models.UserLogin) e
 			return cherry.ErrUnableResendLink()
 		}
 	}
+	if err := u.checkLinkResendCount(link); err != nil {
+		u.log.WithError(err)
+		return err
+	}
 	if err := u.checkLinkResendTime(link); err != nil {
 		u.log.WithError(err)
 		return cherry.ErrUnableResendLink()
@@ -293,3 +331,18 @@ func (u *serverImpl) LinkResend(ctx context.Context, request models.UserLogin) e
 	}
 	return nil
 }
+
+func (u *serverImpl) GetLinkPolicies(ctx context.Context) (*models.LinkPolicies, error) {
+	u.log.Info("get link policies")
+	resp := models.LinkPolicies{Policies: []models.LinkPolicy{}}
+	for _, linkType := range []models.LinkType{models.LinkTypeConfirm, models.LinkTypePwdChange, models.LinkTypeDelete} {
+		policy := u.linkPolicy(linkType)
+		resp.Policies = append(resp.Policies, models.LinkPolicy{
+			Type:           linkType,
+			Lifetime:       int64(policy.Lifetime.Seconds()),
+			ResendCooldown: int64(policy.ResendCooldown.Seconds()),
+			MaxResends:     policy.MaxResends,
+		})
+	}
+	return &resp, nil
+}
@@ -5,12 +5,16 @@ import (
 
 	"io"
 
+	"time"
+
 	kube_types "github.com/containerum/kube-client/pkg/model"
 
 	"git.containerum.net/ch/auth/proto"
 	"git.containerum.net/ch/user-manager/pkg/clients"
 	"git.containerum.net/ch/user-manager/pkg/db"
 	"git.containerum.net/ch/user-manager/pkg/models"
+	"git.containerum.net/ch/user-manager/pkg/utils"
+	"git.containerum.net/ch/user-manager/pkg/validation"
 )
 
 // UserManager is an interface for server "business logic"
@@ -18,9 +22,12 @@ type UserManager interface {
 	BasicLogin(ctx context.Context, request models.LoginRequest) (*authProto.CreateTokenResponse, error)
 	OneTimeTokenLogin(ctx context.Context, request models.OneTimeTokenLoginRequest) (*authProto.CreateTokenResponse, error)
 	OAuthLogin(ctx context.Context, request models.OAuthLoginRequest) (*authProto.CreateTokenResponse, error)
+	APIKeyLogin(ctx context.Context, request models.APIKeyLoginRequest) (*authProto.CreateTokenResponse, error)
 
 	ChangePassword(ctx context.Context, request models.PasswordChangeRequest) (*authProto.CreateTokenResponse, error)
-	ResetPassword(ctx context.Context, request models.UserLogin) error
+	// Changes password of user which can't login until password is changed
+	ChangeExpiredPassword(ctx context.Context, request models.ExpiredPasswordChangeRequest) (*authProto.CreateTokenResponse, error)
+	ResetPassword(ctx context.Context, request models.PasswordResetRequest) error
 	RestorePassword(ctx context.Context, request models.PasswordRestoreRequest) (*authProto.CreateTokenResponse, error)
 
 	Logout(ctx context.Context) error
@@ -32,6 +39,7 @@ type UserManager interface {
 	UnBlacklistUser(ctx context.Context, request models.UserLogin) error
 	UpdateUser(ctx context.Context, newData map[string]interface{}) (*models.User, error)
 	PartiallyDeleteUser(ctx context.Context) error
+	RestoreUser(ctx context.Context, request models.Link) (*authProto.CreateTokenResponse, error)
 	CompletelyDeleteUser(ctx context.Context, userID string) error
 	AddBoundAccount(ctx context.Context, request models.OAuthLoginRequest) error
 	DeleteBoundAccount(ctx context.Context, request models.BoundAccountDeleteRequest) error
@@ -43,6 +51,11 @@ type UserManager interface {
 	AdminResetPassword(ctx context.Context, request models.UserLogin) (*models.UserLogin, error)
 	AdminSetAdmin(ctx context.Context, request models.UserLogin) error
 	AdminUnsetAdmin(ctx context.Context, request models.UserLogin) error
+	AdminRestoreUser(ctx context.Context, request models.UserLogin) error
+	AdminSetPasswordChangeRequirement(ctx context.Context, request models.PasswordChangeRequirement) error
+	// Creates short-living tokens of user for admin. Impersonation is recorded and user is notified.
+	Impersonate(ctx context.Context, request models.ImpersonationRequest) (*authProto.CreateTokenResponse, error)
+	GetUserImpersonations(ctx context.Context, userID string, page, perPage uint) (*models.Impersonations, error)
 
 	// not changes DB state
 	GetUserLinks(ctx context.Context, userID string) (*models.Links, error)
@@ -53,32 +66,84 @@ type UserManager interface {
 	GetBlacklistedUsers(ctx context.Context, page int, perPage int) (*models.UserList, error)
 	GetUsers(ctx context.Context, page uint, perPage uint, filters ...string) (*models.UserList, error)
 	GetBoundAccounts(ctx context.Context) (models.BoundAccounts, error)
+	GetLinkPolicies(ctx context.Context) (*models.LinkPolicies, error)
 
 	LinkResend(ctx context.Context, request models.UserLogin) error
 
 	// checks
 	CheckAdmin(ctx context.Context) error
 	CheckUserExist(ctx context.Context) error
+	// CheckService checks if current user is service account or admin
+	CheckService(ctx context.Context) error
+	CheckGroupManager(ctx context.Context, group kube_types.UserGroup) error
+	CheckOrgAdmin(ctx context.Context) error
+	CheckPermission(ctx context.Context, permission models.Permission) error
+
+	// Admin roles
+	GetAdminRoles(ctx context.Context) (*models.AdminRoles, error)
+	SetAdminRole(ctx context.Context, name string, request models.AdminRoleRequest) (*models.AdminRole, error)
+	DeleteAdminRole(ctx context.Context, name string) error
+	GrantAdminRole(ctx context.Context, name, userID string) error
+	RevokeAdminRole(ctx context.Context, name, userID string) error
+
+	// Organizations
+	CreateOrganization(ctx context.Context, request models.OrganizationCreateRequest) (*models.Organization, error)
+	GetOrganizations(ctx context.Context) (*models.Organizations, error)
+	SetUserOrganization(ctx context.Context, userID string, request models.UserOrganizationRequest) error
 
 	// Domain blacklist
 	AddDomainToBlacklist(ctx context.Context, request models.Domain) error
-	RemoveDomainFromBlacklist(ctx context.Context, domain string) error
-	GetBlacklistedDomain(ctx context.Context, domain string) (*models.Domain, error)
-	GetBlacklistedDomainsList(ctx context.Context) (*models.DomainListResponse, error)
+	RemoveDomainFromBlacklist(ctx context.Context, list, domain string) error
+	GetBlacklistedDomain(ctx context.Context, list, domain string) (*models.Domain, error)
+	GetBlacklistedDomainsList(ctx context.Context, list string) (*models.DomainListResponse, error)
+	ImportDomains(ctx context.Context, request models.DomainImportRequest) (*models.DomainImportResponse, error)
+
+	// IP rules
+	AddIPRule(ctx context.Context, request models.IPRule) (*models.IPRule, error)
+	DeleteIPRule(ctx context.Context, cidr string) error
+	GetIPRule(ctx context.Context, cidr string) (*models.IPRule, error)
+	GetIPRules(ctx context.Context, list string) (*models.IPRules, error)
+
+	// Service accounts
+	CreateServiceAccount(ctx context.Context, request models.ServiceAccountCreateRequest) (*models.ServiceAccount, error)
+	GetServiceAccounts(ctx context.Context) (*models.ServiceAccounts, error)
+	CreateAPIKey(ctx context.Context, accountID string, request models.APIKeyCreateRequest) (*models.APIKey, error)
+	GetAPIKeys(ctx context.Context, accountID string) (*models.APIKeys, error)
+	RevokeAPIKey(ctx context.Context, accountID, keyID string) error
 
 	//User groups
-	GetGroupsList(ctx context.Context, userID string) (*kube_types.UserGroups, error)
-	GetGroupByID(ctx context.Context, groupID string) (*kube_types.UserGroup, error)
-	GetGroupByLabel(ctx context.Context, groupLabel string) (*kube_types.UserGroup, error)
+	GetGroupsList(ctx context.Context, userID string) (*models.UserGroups, error)
+	// Checks if current user can manage group
+	GetGroupMembersPage(ctx context.Context, groupLabel string, page, perPage uint, filter models.GroupMembersFilter) (*models.GroupMembersPage, error)
+	GetUserGroupMemberships(ctx context.Context, userID string, page, perPage uint) (*models.UserGroupMemberships, error)
+	GetGroupByID(ctx context.Context, groupID string) (*models.UserGroup, error)
+	GetGroupByLabel(ctx context.Context, groupLabel string) (*models.UserGroup, error)
 	GetGroupListLabelID(ctx context.Context, ids []string) (*models.LoginID, error)
-	GetGroupListByIDs(ctx context.Context, ids []string) (*kube_types.UserGroups, error)
+	GetGroupListByIDs(ctx context.Context, ids []string) (*models.UserGroups, error)
 	CreateGroup(ctx context.Context, request kube_types.UserGroup) (*string, error)
-	AddGroupMembers(ctx context.Context, groupID string, request kube_types.UserGroupMembers) error
+	AddGroupMembers(ctx context.Context, groupID string, request models.GroupMembers) error
 	DeleteGroupMember(ctx context.Context, group kube_types.UserGroup, username string) error
-	UpdateGroupMemberAccess(ctx context.Context, group kube_types.UserGroup, username, access string) error
+	// Makes group members list equal to request. Changes are not applied in dry run mode
+	SyncGroupMembers(ctx context.Context, group kube_types.UserGroup, request models.GroupMembers, dryRun bool) (*models.GroupMembersSyncResult, error)
+	UpdateGroupMemberAccess(ctx context.Context, group kube_types.UserGroup, username, access string, expiresAt *time.Time, removeExpiration bool) error
+	TransferGroupOwnership(ctx context.Context, group kube_types.UserGroup, newOwnerLogin string) error
+	UpdateGroup(ctx context.Context, group kube_types.UserGroup, request models.GroupUpdateRequest) (*models.UserGroup, error)
 	DeleteGroup(ctx context.Context, group kube_types.UserGroup) error
+	AddSubgroup(ctx context.Context, group kube_types.UserGroup, request models.SubgroupAddRequest) error
+	GetSubgroups(ctx context.Context, group kube_types.UserGroup) (*models.Subgroups, error)
+	DeleteSubgroup(ctx context.Context, group kube_types.UserGroup, subgroupLabel string) error
+	CreateGroupInvite(ctx context.Context, group kube_types.UserGroup, request models.GroupInviteRequest) (*models.GroupInvite, error)
+	GetGroupInvites(ctx context.Context, group kube_types.UserGroup) (*models.GroupInvites, error)
+	DeleteGroupInvite(ctx context.Context, group kube_types.UserGroup, inviteID string) error
+	AcceptGroupInvite(ctx context.Context, request models.GroupInviteToken) error
+	DeclineGroupInvite(ctx context.Context, request models.GroupInviteToken) error
 
 	CreateFirstAdmin(password string) error
+
+	// background tasks
+	RunJanitor(ctx context.Context)
+	RunUserCacheListener(ctx context.Context)
+
 	io.Closer
 }
 
@@ -87,8 +152,71 @@ type Services struct {
 	MailClient        clients.MailClient
 	DB                db.DB
 	AuthClient        clients.AuthClient
-	ReCaptchaClient   clients.ReCaptchaClient
+	CaptchaClient     clients.CaptchaClient
 	PermissionsClient clients.PermissionsClient
 	TelegramClient    clients.TelegramClient
 	EventsClient      clients.EventsClient
+	// BreachedPasswords may be nil if breached passwords check is disabled
+	BreachedPasswords *utils.BreachedPasswords
+}
+
+// Config is a collection of tunable parameters for server functionality.
+type Config struct {
+	// RestorePeriod is a time during which partially deleted user can restore his account.
+	RestorePeriod time.Duration
+	// JanitorInterval is a period between expired links and tokens cleanups. Zero value disables cleanup.
+	JanitorInterval time.Duration
+	// JanitorRetention is a time during which expired links and inactive tokens are kept before removal.
+	JanitorRetention time.Duration
+	// JanitorBatchSize is a maximum number of rows removed by one query.
+	JanitorBatchSize int
+	// LinkPolicies contains lifetime and resend restrictions for each link type.
+	LinkPolicies map[models.LinkType]LinkPolicy
+	// MemberExpiryNotice is a time before group membership expiration when group owner is notified. Zero value disables notifications.
+	MemberExpiryNotice time.Duration
+	// InviteLifetime is a time during which group invite can be accepted.
+	InviteLifetime time.Duration
+	// InviteKeyring signs and verifies group invite tokens.
+	InviteKeyring *utils.LinkKeyring
+	// ImpersonationTTL is a lifetime of tokens issued by impersonation.
+	ImpersonationTTL time.Duration
+	// PasswordPolicy describes requirements for passwords set by users and generated by server.
+	PasswordPolicy validation.PasswordPolicy
+	// PasswordMaxAge is a time after which user must change password. Zero value disables password expiration.
+	PasswordMaxAge time.Duration
+	// PasswordExpiryNotice is a time before password expiration when user is notified.
+	PasswordExpiryNotice time.Duration
+	// CaptchaFailureThreshold is a number of failed login attempts from login or IP after which captcha is required
+	// on login and password reset. Zero value disables the check.
+	CaptchaFailureThreshold int
+	// CaptchaFailureWindow is a period during which failed login attempts are counted.
+	CaptchaFailureWindow time.Duration
+
+	// DomainAllowlist enables private install mode: only users with emails matching "allow" domain rules may register.
+	DomainAllowlist bool
+	// IPAllowlist allows registration and login only from networks matching "allow" IP rules.
+	IPAllowlist bool
+
+	// PrivacyMode makes sign up, password reset and link resend responses independent of account existence and state.
+	// Outcome is reported to account owner by email.
+	PrivacyMode bool
+	// PrivacyResponseTime is a minimal response time of endpoints affected by privacy mode.
+	PrivacyResponseTime time.Duration
+
+	// UserCacheTTL is a time during which users loaded for permission checks are cached. Zero value disables caching.
+	UserCacheTTL time.Duration
+	// UserCacheSize is a maximum number of cached users.
+	UserCacheSize int
+	// UserCacheNotify enables broadcasting of user changes to other replicas, so they can invalidate cached users.
+	UserCacheNotify bool
+}
+
+// LinkPolicy describes lifetime and resend restrictions of links of some type.
+type LinkPolicy struct {
+	// Lifetime is a period during which link is valid.
+	Lifetime time.Duration
+	// ResendCooldown is a minimal interval between link sends.
+	ResendCooldown time.Duration
+	// MaxResends is a maximum number of link resends. Zero value means no limit.
+	MaxResends int
 }
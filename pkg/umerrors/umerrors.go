@@ -686,6 +686,762 @@ func ErrGroupAlreadyExist(params ...func(*cherry.Err)) *cherry.Err {
 	}
 	return err
 }
+
+func ErrUserNotDeleted(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "User is not deleted", StatusHTTP: 400, ID: cherry.ErrID{SID: "UserManager", Kind: 0x38}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrUnableRestoreUser(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Unable to restore user", StatusHTTP: 500, ID: cherry.ErrID{SID: "UserManager", Kind: 0x39}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrRestorePeriodExpired(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Account restore period expired", StatusHTTP: 403, ID: cherry.ErrID{SID: "UserManager", Kind: 0x3a}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrLinkResendLimitExceeded(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Link resend limit exceeded", StatusHTTP: 429, ID: cherry.ErrID{SID: "UserManager", Kind: 0x3b}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrUnableCreateServiceAccount(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Unable to create service account", StatusHTTP: 500, ID: cherry.ErrID{SID: "UserManager", Kind: 0x3c}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrUnableGetServiceAccounts(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Unable to get service accounts", StatusHTTP: 500, ID: cherry.ErrID{SID: "UserManager", Kind: 0x3d}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrUnableCreateAPIKey(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Unable to create API key", StatusHTTP: 500, ID: cherry.ErrID{SID: "UserManager", Kind: 0x3e}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrUnableGetAPIKeys(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Unable to get API keys", StatusHTTP: 500, ID: cherry.ErrID{SID: "UserManager", Kind: 0x3f}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrUnableRevokeAPIKey(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Unable to revoke API key", StatusHTTP: 500, ID: cherry.ErrID{SID: "UserManager", Kind: 0x40}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrInvalidAPIKey(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Invalid API key", StatusHTTP: 401, ID: cherry.ErrID{SID: "UserManager", Kind: 0x41}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrServiceAccountNotExist(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Service account doesn't exist", StatusHTTP: 404, ID: cherry.ErrID{SID: "UserManager", Kind: 0x42}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrUnableTransferGroupOwnership(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Unable to transfer group ownership", StatusHTTP: 500, ID: cherry.ErrID{SID: "UserManager", Kind: 0x43}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrUnableCreateGroupInvite(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Unable to create group invite", StatusHTTP: 500, ID: cherry.ErrID{SID: "UserManager", Kind: 0x44}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrUnableGetGroupInvites(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Unable to get group invites", StatusHTTP: 500, ID: cherry.ErrID{SID: "UserManager", Kind: 0x45}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrGroupInviteNotExist(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Group invite does not exist", StatusHTTP: 404, ID: cherry.ErrID{SID: "UserManager", Kind: 0x46}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrInvalidGroupInvite(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Group invite is invalid or expired", StatusHTTP: 400, ID: cherry.ErrID{SID: "UserManager", Kind: 0x47}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrUnableAcceptGroupInvite(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Unable to accept group invite", StatusHTTP: 500, ID: cherry.ErrID{SID: "UserManager", Kind: 0x48}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrUnableDeleteGroupInvite(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Unable to delete group invite", StatusHTTP: 500, ID: cherry.ErrID{SID: "UserManager", Kind: 0x49}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrGroupCycle(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Group already contains parent group", StatusHTTP: 409, ID: cherry.ErrID{SID: "UserManager", Kind: 0x4a}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrUnableAddSubgroup(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Unable to add subgroup", StatusHTTP: 500, ID: cherry.ErrID{SID: "UserManager", Kind: 0x4b}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrUnableGetSubgroups(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Unable to get subgroups", StatusHTTP: 500, ID: cherry.ErrID{SID: "UserManager", Kind: 0x4c}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrNotSubgroup(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Group is not a subgroup", StatusHTTP: 404, ID: cherry.ErrID{SID: "UserManager", Kind: 0x4d}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrUnableDeleteSubgroup(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Unable to delete subgroup", StatusHTTP: 500, ID: cherry.ErrID{SID: "UserManager", Kind: 0x4e}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrUnableSyncGroupMembers(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Unable to sync group members", StatusHTTP: 500, ID: cherry.ErrID{SID: "UserManager", Kind: 0x4f}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrUnableGetGroupMembers(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Unable to get group members", StatusHTTP: 500, ID: cherry.ErrID{SID: "UserManager", Kind: 0x50}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrUnableGetUserGroups(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Unable to get user groups", StatusHTTP: 500, ID: cherry.ErrID{SID: "UserManager", Kind: 0x51}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrOrgAdminRequired(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Organization admin or admin required", StatusHTTP: 403, ID: cherry.ErrID{SID: "UserManager", Kind: 0x52}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrOrganizationNotExist(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Organization doesn't exist", StatusHTTP: 404, ID: cherry.ErrID{SID: "UserManager", Kind: 0x53}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrOrganizationAlreadyExist(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Organization already exists", StatusHTTP: 409, ID: cherry.ErrID{SID: "UserManager", Kind: 0x54}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrUserNotInOrganization(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "User belongs to other organization", StatusHTTP: 403, ID: cherry.ErrID{SID: "UserManager", Kind: 0x55}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrUnableCreateOrganization(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Unable to create organization", StatusHTTP: 500, ID: cherry.ErrID{SID: "UserManager", Kind: 0x56}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrUnableGetOrganizations(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Unable to get organizations", StatusHTTP: 500, ID: cherry.ErrID{SID: "UserManager", Kind: 0x57}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrUnableSetUserOrganization(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Unable to set user organization", StatusHTTP: 500, ID: cherry.ErrID{SID: "UserManager", Kind: 0x58}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrPermissionRequired(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Permission required", StatusHTTP: 403, ID: cherry.ErrID{SID: "UserManager", Kind: 0x59}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrAdminRoleNotExist(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Admin role doesn't exist", StatusHTTP: 404, ID: cherry.ErrID{SID: "UserManager", Kind: 0x5a}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrUnableGetAdminRoles(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Unable to get admin roles", StatusHTTP: 500, ID: cherry.ErrID{SID: "UserManager", Kind: 0x5b}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrUnableSetAdminRole(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Unable to save admin role", StatusHTTP: 500, ID: cherry.ErrID{SID: "UserManager", Kind: 0x5c}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrUnableDeleteAdminRole(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Unable to delete admin role", StatusHTTP: 500, ID: cherry.ErrID{SID: "UserManager", Kind: 0x5d}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrUnableGrantAdminRole(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Unable to grant admin role", StatusHTTP: 500, ID: cherry.ErrID{SID: "UserManager", Kind: 0x5e}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrAdminRoleNotGranted(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Admin role is not granted to user", StatusHTTP: 404, ID: cherry.ErrID{SID: "UserManager", Kind: 0x5f}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrImpersonationReasonRequired(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Impersonation reason is required", StatusHTTP: 400, ID: cherry.ErrID{SID: "UserManager", Kind: 0x60}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrUnableImpersonate(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Unable to impersonate user", StatusHTTP: 500, ID: cherry.ErrID{SID: "UserManager", Kind: 0x61}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrImpersonatedSession(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Action is not allowed in impersonated session", StatusHTTP: 403, ID: cherry.ErrID{SID: "UserManager", Kind: 0x62}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrUnableGetImpersonations(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Unable to get impersonations", StatusHTTP: 500, ID: cherry.ErrID{SID: "UserManager", Kind: 0x63}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrImpersonateAdmin(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Admin can not be impersonated", StatusHTTP: 403, ID: cherry.ErrID{SID: "UserManager", Kind: 0x64}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrPasswordPolicyViolated(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Password does not satisfy password policy", StatusHTTP: 400, ID: cherry.ErrID{SID: "UserManager", Kind: 0x65}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrPasswordChangeRequired(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Password must be changed", StatusHTTP: 403, ID: cherry.ErrID{SID: "UserManager", Kind: 0x66}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrCaptchaRequired(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Captcha is required", StatusHTTP: 403, ID: cherry.ErrID{SID: "UserManager", Kind: 0x67}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrDomainNotAllowed(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Registration with email of this domain is not allowed", StatusHTTP: 403, ID: cherry.ErrID{SID: "UserManager", Kind: 0x68}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrUnableImportDomains(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Unable to import domains", StatusHTTP: 500, ID: cherry.ErrID{SID: "UserManager", Kind: 0x69}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrIPBlocked(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Access from this IP address is not allowed", StatusHTTP: 403, ID: cherry.ErrID{SID: "UserManager", Kind: 0x6a}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrUnableGetIPRules(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Unable to get IP rules", StatusHTTP: 500, ID: cherry.ErrID{SID: "UserManager", Kind: 0x6b}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrUnableAddIPRule(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Unable to add IP rule", StatusHTTP: 500, ID: cherry.ErrID{SID: "UserManager", Kind: 0x6c}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrUnableDeleteIPRule(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Unable to delete IP rule", StatusHTTP: 500, ID: cherry.ErrID{SID: "UserManager", Kind: 0x6d}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrIPRuleNotFound(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "IP rule not found", StatusHTTP: 404, ID: cherry.ErrID{SID: "UserManager", Kind: 0x6e}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrTooManyRequests(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Too many requests, try again later", StatusHTTP: 429, ID: cherry.ErrID{SID: "UserManager", Kind: 0x6f}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrServiceRequired(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Service account is required", StatusHTTP: 403, ID: cherry.ErrID{SID: "UserManager", Kind: 0x70}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrInternalAuthRequired(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Internal request authentication required", StatusHTTP: 401, ID: cherry.ErrID{SID: "UserManager", Kind: 0x71}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrInvalidToken(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Invalid access token", StatusHTTP: 401, ID: cherry.ErrID{SID: "UserManager", Kind: 0x72}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrAdminRoleSelfGrant(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Admin role can not be granted to yourself", StatusHTTP: 403, ID: cherry.ErrID{SID: "UserManager", Kind: 0x73}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrPasswordChangeNotRequired(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Password change is not required", StatusHTTP: 409, ID: cherry.ErrID{SID: "UserManager", Kind: 0x74}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrUserOwnsGroups(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "User owns groups of other organization", StatusHTTP: 409, ID: cherry.ErrID{SID: "UserManager", Kind: 0x75}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrAdminRoleSelfEdit(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Admin role granted to yourself can not be changed", StatusHTTP: 403, ID: cherry.ErrID{SID: "UserManager", Kind: 0x76}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
 func renderTemplate(templText string) string {
 	buf := &bytes.Buffer{}
 	templ, err := template.New("").Parse(templText)
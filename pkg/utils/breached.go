@@ -0,0 +1,145 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maximal length of breached passwords file line: hash, count and line break
+const breachedLineMaxLength = 128
+
+// rangePrefixLength is a length of hash prefix used as range file name in k-anonymity range format
+const rangePrefixLength = 5
+
+// BreachedPasswords checks passwords against local list of breached passwords SHA-1 hashes.
+// List is either a text file with one uppercase hex SHA-1 hash per line optionally followed by ":count", sorted by hash
+// (Have I Been Pwned "ordered by hash" format), or a directory of range files (k-anonymity range API format):
+// file named by first 5 hash characters with ".txt" extension contains remaining 35 characters of hashes.
+// Single file is not loaded to memory, lookup is a binary search over file.
+type BreachedPasswords struct {
+	file *os.File
+	size int64
+	// rangesDir is set if list is a directory of range files
+	rangesDir string
+}
+
+// OpenBreachedPasswords opens breached passwords list. Empty path produces nil list which means that check is disabled.
+func OpenBreachedPasswords(path string) (*BreachedPasswords, error) {
+	if path == "" {
+		return nil, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if info.IsDir() {
+		file.Close()
+		return &BreachedPasswords{rangesDir: path}, nil
+	}
+	return &BreachedPasswords{file: file, size: info.Size()}, nil
+}
+
+// Contains checks if password hash is in list.
+func (b *BreachedPasswords) Contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	target := strings.ToUpper(hex.EncodeToString(sum[:]))
+	if b.rangesDir != "" {
+		return b.rangeContains(target)
+	}
+
+	// lo is always a start of line, lines before lo are less than target, lines starting at hi or later are greater
+	lo, hi := int64(0), b.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		start, line, err := b.lineAfter(mid)
+		if err != nil {
+			return false, err
+		}
+		if start >= hi {
+			hi = mid
+			continue
+		}
+		hash := strings.ToUpper(strings.TrimSpace(strings.SplitN(line, ":", 2)[0]))
+		switch {
+		case hash == target:
+			return true, nil
+		case hash < target:
+			lo = start + int64(len(line)) + 1
+		default:
+			hi = mid
+		}
+	}
+	return false, nil
+}
+
+// rangeContains looks for hash suffix in range file of hash prefix. Missing range file means that no hashes have such prefix.
+func (b *BreachedPasswords) rangeContains(target string) (bool, error) {
+	data, err := ioutil.ReadFile(filepath.Join(b.rangesDir, target[:rangePrefixLength]+".txt"))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	suffix := target[rangePrefixLength:]
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.ToUpper(strings.TrimSpace(strings.SplitN(line, ":", 2)[0])) == suffix {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// lineAfter returns first line starting at offset or later.
+func (b *BreachedPasswords) lineAfter(offset int64) (int64, string, error) {
+	start := offset
+	if offset > 0 {
+		// previous byte is read to find out if offset is a line start
+		start = offset - 1
+	}
+	buf := make([]byte, 2*breachedLineMaxLength)
+	n, err := b.file.ReadAt(buf, start)
+	if err != nil && err != io.EOF {
+		return 0, "", err
+	}
+	buf = buf[:n]
+	if offset > 0 {
+		idx := bytes.IndexByte(buf, '\n')
+		if idx < 0 {
+			return b.size, "", nil
+		}
+		start += int64(idx) + 1
+		buf = buf[idx+1:]
+	}
+	if len(buf) == 0 {
+		return b.size, "", nil
+	}
+	end := bytes.IndexByte(buf, '\n')
+	if end < 0 {
+		if start+int64(len(buf)) < b.size {
+			return 0, "", fmt.Errorf("breached passwords file line at %d is too long", start)
+		}
+		end = len(buf)
+	}
+	return start, string(buf[:end]), nil
+}
+
+// Close closes list file.
+func (b *BreachedPasswords) Close() error {
+	if b == nil || b.file == nil {
+		return nil
+	}
+	return b.file.Close()
+}
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	"git.containerum.net/ch/user-manager/pkg/models"
+)
+
+// ParseDomainList reads domains list in format used by public disposable domains lists:
+// one domain per line, empty lines and lines starting with "#" are ignored.
+func ParseDomainList(r io.Reader) ([]string, error) {
+	var domains []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		domains = append(domains, line)
+	}
+	return domains, scanner.Err()
+}
+
+// NormalizeDomainRule converts "*.example.com" to suffix rule for "example.com" and lowercases non-regex rules.
+func NormalizeDomainRule(domain, kind string) (string, string) {
+	if kind == models.DomainRuleRegex {
+		return domain, kind
+	}
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	if strings.HasPrefix(domain, "*.") {
+		return strings.TrimPrefix(domain, "*."), models.DomainRuleSuffix
+	}
+	if kind == "" {
+		kind = models.DomainRuleExact
+	}
+	return domain, kind
+}
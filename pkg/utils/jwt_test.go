@@ -0,0 +1,266 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var testSharedKey = []byte("test-shared-key")
+
+func encodeSegment(t *testing.T, v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func signedPart(t *testing.T, alg, kid string, claims map[string]interface{}) string {
+	header := map[string]string{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	return encodeSegment(t, header) + "." + encodeSegment(t, claims)
+}
+
+func hsToken(t *testing.T, key []byte, claims map[string]interface{}) string {
+	signed := signedPart(t, "HS256", "", claims)
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func rsToken(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	signed := signedPart(t, "RS256", kid, claims)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// esToken signs token with ES256, resize changes signature length keeping its prefix.
+func esToken(t *testing.T, key *ecdsa.PrivateKey, kid string, resize int, claims map[string]interface{}) string {
+	signed := signedPart(t, "ES256", kid, claims)
+	digest := sha256.Sum256([]byte(signed))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := make([]byte, 64)
+	rBytes, sBytes := r.Bytes(), s.Bytes()
+	copy(sig[32-len(rBytes):32], rBytes)
+	copy(sig[64-len(sBytes):], sBytes)
+	if resize < 0 {
+		sig = sig[:len(sig)+resize]
+	} else {
+		sig = append(sig, make([]byte, resize)...)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func encodeBigInt(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
+
+// jwksServer serves public keys of rsaKey and ecKey and counts requests.
+func jwksServer(t *testing.T, rsaKey *rsa.PrivateKey, ecKey *ecdsa.PrivateKey, fetches *int32) *httptest.Server {
+	set := map[string]interface{}{"keys": []map[string]string{
+		{
+			"kid": "rsa", "kty": "RSA", "use": "sig",
+			"n": encodeBigInt(rsaKey.N), "e": encodeBigInt(big.NewInt(int64(rsaKey.E))),
+		},
+		{
+			"kid": "ec", "kty": "EC", "crv": "P-256",
+			"x": encodeBigInt(ecKey.X), "y": encodeBigInt(ecKey.Y),
+		},
+	}}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(fetches, 1)
+		json.NewEncoder(w).Encode(set)
+	}))
+}
+
+func validClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"sub": "user",
+		"exp": float64(time.Now().Add(time.Hour).Unix()),
+	}
+}
+
+func withClaim(name string, value interface{}) map[string]interface{} {
+	claims := validClaims()
+	if value == nil {
+		delete(claims, name)
+	} else {
+		claims[name] = value
+	}
+	return claims
+}
+
+func TestJWTVerifierSharedKey(t *testing.T) {
+	now := time.Now()
+	v := NewSharedKeyJWTVerifier(testSharedKey)
+	v.Issuer = "auth"
+	v.Audience = "user-manager"
+	v.Leeway = 30 * time.Second
+
+	claims := func(extra map[string]interface{}) map[string]interface{} {
+		ret := map[string]interface{}{
+			"iss": "auth",
+			"aud": "user-manager",
+			"exp": float64(now.Add(time.Hour).Unix()),
+		}
+		for k, val := range extra {
+			if val == nil {
+				delete(ret, k)
+			} else {
+				ret[k] = val
+			}
+		}
+		return ret
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{"valid", hsToken(t, testSharedKey, claims(nil)), true},
+		{"wrong key", hsToken(t, []byte("other-key"), claims(nil)), false},
+		{"alg none", signedPart(t, "none", "", claims(nil)) + ".", false},
+		{"no exp", hsToken(t, testSharedKey, claims(map[string]interface{}{"exp": nil})), false},
+		{"expired", hsToken(t, testSharedKey, claims(map[string]interface{}{"exp": float64(now.Add(-time.Minute).Unix())})), false},
+		{"expired within leeway", hsToken(t, testSharedKey, claims(map[string]interface{}{"exp": float64(now.Add(-10 * time.Second).Unix())})), true},
+		{"nbf in future", hsToken(t, testSharedKey, claims(map[string]interface{}{"nbf": float64(now.Add(time.Minute).Unix())})), false},
+		{"nbf within leeway", hsToken(t, testSharedKey, claims(map[string]interface{}{"nbf": float64(now.Add(10 * time.Second).Unix())})), true},
+		{"wrong issuer", hsToken(t, testSharedKey, claims(map[string]interface{}{"iss": "other"})), false},
+		{"no issuer", hsToken(t, testSharedKey, claims(map[string]interface{}{"iss": nil})), false},
+		{"wrong audience", hsToken(t, testSharedKey, claims(map[string]interface{}{"aud": "other"})), false},
+		{"audience list", hsToken(t, testSharedKey, claims(map[string]interface{}{"aud": []string{"other", "user-manager"}})), true},
+		{"audience list without service", hsToken(t, testSharedKey, claims(map[string]interface{}{"aud": []string{"other"}})), false},
+		{"malformed", "not.a.token", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := v.Verify(tt.token)
+			if tt.valid && err != nil {
+				t.Errorf("expected valid token, got %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("expected token to be rejected")
+			}
+		})
+	}
+}
+
+func TestJWTVerifierJWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fetches int32
+	srv := jwksServer(t, rsaKey, ecKey, &fetches)
+	defer srv.Close()
+
+	v, err := NewJWKSVerifier(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// public key of JWKS used as HMAC shared key
+	rsaPublic := []byte(encodeBigInt(rsaKey.N))
+
+	tests := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{"RS256", rsToken(t, rsaKey, "rsa", validClaims()), true},
+		{"ES256", esToken(t, ecKey, "ec", 0, validClaims()), true},
+		{"RS256 expired", rsToken(t, rsaKey, "rsa", withClaim("exp", float64(time.Now().Add(-time.Hour).Unix()))), false},
+		{"RS256 with EC key", rsToken(t, rsaKey, "ec", validClaims()), false},
+		{"ES256 with RSA key", esToken(t, ecKey, "rsa", 0, validClaims()), false},
+		{"ES256 short signature", esToken(t, ecKey, "ec", -1, validClaims()), false},
+		{"ES256 long signature", esToken(t, ecKey, "ec", 1, validClaims()), false},
+		{"HS256 with public key", hsToken(t, rsaPublic, validClaims()), false},
+		{"alg none", signedPart(t, "none", "rsa", validClaims()) + ".", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := v.Verify(tt.token)
+			if tt.valid && err != nil {
+				t.Errorf("expected valid token, got %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("expected token to be rejected")
+			}
+		})
+	}
+}
+
+func TestJWTVerifierRejectsAsymmetricWithSharedKey(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := NewSharedKeyJWTVerifier(testSharedKey)
+	if _, err := v.Verify(rsToken(t, rsaKey, "rsa", validClaims())); err == nil {
+		t.Error("expected RS256 token to be rejected by shared key verifier")
+	}
+}
+
+func TestJWTVerifierUnknownKeyRefetch(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fetches int32
+	srv := jwksServer(t, rsaKey, ecKey, &fetches)
+	defer srv.Close()
+
+	v, err := NewJWKSVerifier(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// allow refresh
+	v.fetchedAt = time.Now().Add(-2 * jwksRefreshInterval)
+
+	token := rsToken(t, rsaKey, "unknown", validClaims())
+	done := make(chan struct{})
+	for i := 0; i < 10; i++ {
+		go func() {
+			v.Verify(token)
+			done <- struct{}{}
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		<-done
+	}
+	// initial fetch and one refresh
+	if n := atomic.LoadInt32(&fetches); n != 2 {
+		t.Errorf("expected 2 JWKS fetches, got %d", n)
+	}
+}
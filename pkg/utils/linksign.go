@@ -0,0 +1,135 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidLinkSignature returned if signed link is malformed or signed by unknown key.
+	ErrInvalidLinkSignature = errors.New("invalid link signature")
+	// ErrSignedLinkExpired returned if signed link has valid signature but expired.
+	ErrSignedLinkExpired = errors.New("signed link expired")
+)
+
+// LinkClaims is a data carried by signed link.
+type LinkClaims struct {
+	UserID    string
+	Type      string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+	Nonce     int64
+}
+
+// LinkKeyring holds keys used to sign and verify links.
+// First key signs new links, all keys are used for verification so keys can be rotated without invalidating issued links.
+type LinkKeyring struct {
+	activeID string
+	keys     map[string][]byte
+}
+
+// NewLinkKeyring parses keyring from comma-separated list of "key_id:secret" pairs.
+// Empty spec produces nil keyring which means that signed links are disabled.
+func NewLinkKeyring(spec string) (*LinkKeyring, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+	ret := &LinkKeyring{keys: make(map[string][]byte)}
+	for _, pair := range strings.Split(spec, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid link signing key %q, expected key_id:secret", pair)
+		}
+		if strings.Contains(parts[0], ".") {
+			return nil, fmt.Errorf("link signing key id %q must not contain dots", parts[0])
+		}
+		if _, exists := ret.keys[parts[0]]; exists {
+			return nil, fmt.Errorf("duplicate link signing key id %q", parts[0])
+		}
+		if ret.activeID == "" {
+			ret.activeID = parts[0]
+		}
+		ret.keys[parts[0]] = []byte(parts[1])
+	}
+	return ret, nil
+}
+
+// IsSignedLink checks if link has signed link format (key_id.payload.signature).
+// Legacy links are hex strings so they never contain dots.
+func IsSignedLink(link string) bool {
+	return strings.Count(link, ".") == 2
+}
+
+// Sign produces signed link carrying claims using active key.
+func (k *LinkKeyring) Sign(claims LinkClaims) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(strings.Join([]string{
+		claims.UserID,
+		claims.Type,
+		strconv.FormatInt(claims.IssuedAt.Unix(), 10),
+		strconv.FormatInt(claims.ExpiresAt.Unix(), 10),
+		strconv.FormatInt(claims.Nonce, 10),
+	}, "|")))
+	signed := k.activeID + "." + payload
+	return signed + "." + base64.RawURLEncoding.EncodeToString(k.mac(k.keys[k.activeID], signed))
+}
+
+// Verify checks link signature and expiration and returns carried claims.
+func (k *LinkKeyring) Verify(link string) (*LinkClaims, error) {
+	parts := strings.Split(link, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidLinkSignature
+	}
+	key, ok := k.keys[parts[0]]
+	if !ok {
+		return nil, ErrInvalidLinkSignature
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(sig, k.mac(key, parts[0]+"."+parts[1])) {
+		return nil, ErrInvalidLinkSignature
+	}
+
+	rawPayload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidLinkSignature
+	}
+	fields := strings.Split(string(rawPayload), "|")
+	if len(fields) != 5 {
+		return nil, ErrInvalidLinkSignature
+	}
+	issuedAt, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidLinkSignature
+	}
+	expiresAt, err := strconv.ParseInt(fields[3], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidLinkSignature
+	}
+	nonce, err := strconv.ParseInt(fields[4], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidLinkSignature
+	}
+
+	claims := &LinkClaims{
+		UserID:    fields[0],
+		Type:      fields[1],
+		IssuedAt:  time.Unix(issuedAt, 0).UTC(),
+		ExpiresAt: time.Unix(expiresAt, 0).UTC(),
+		Nonce:     nonce,
+	}
+	if !time.Now().Before(claims.ExpiresAt) {
+		return nil, ErrSignedLinkExpired
+	}
+	return claims, nil
+}
+
+func (k *LinkKeyring) mac(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
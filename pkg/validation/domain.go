@@ -2,18 +2,59 @@ package validation
 
 import (
 	"fmt"
+	"regexp"
+	"time"
 
 	"git.containerum.net/ch/user-manager/pkg/models"
 )
 
-//ValidateUserCreateRequest validates simple send mail request
-func ValidateDomain(login models.Domain) []error {
+//ValidateDomain validates domain rule
+func ValidateDomain(domain models.Domain) []error {
 	var errs []error
-	if login.Domain == "" {
+	if domain.Domain == "" {
 		errs = append(errs, fmt.Errorf(isRequired, "Domain"))
 	}
+	errs = append(errs, validateDomainRule(domain.Kind, domain.List)...)
+	if domain.Kind == models.DomainRuleRegex {
+		if _, err := regexp.Compile(domain.Domain); err != nil {
+			errs = append(errs, fmt.Errorf("invalid domain regex: %v", err))
+		}
+	}
+	if domain.ExpiresAt != nil && !domain.ExpiresAt.After(time.Now()) {
+		errs = append(errs, fmt.Errorf(inPast, "expires_at"))
+	}
 	if len(errs) > 0 {
 		return errs
 	}
 	return nil
 }
+
+//ValidateDomainImport validates domain rules import request
+func ValidateDomainImport(request models.DomainImportRequest) []error {
+	errs := validateDomainRule(request.Kind, request.List)
+	if request.Kind == models.DomainRuleRegex {
+		errs = append(errs, fmt.Errorf("regex rules can not be imported"))
+	}
+	if len(request.Domains) == 0 {
+		errs = append(errs, fmt.Errorf(isRequired, "domains"))
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func validateDomainRule(kind, list string) []error {
+	var errs []error
+	switch kind {
+	case "", models.DomainRuleExact, models.DomainRuleSuffix, models.DomainRuleRegex:
+	default:
+		errs = append(errs, fmt.Errorf("unknown domain rule kind %v", kind))
+	}
+	switch list {
+	case "", models.DomainListBlock, models.DomainListAllow:
+	default:
+		errs = append(errs, fmt.Errorf("unknown domain list %v", list))
+	}
+	return errs
+}
@@ -7,6 +7,8 @@ import (
 const (
 	isRequired      = "field %v is required"
 	isRequiredSlice = "field %v is required in element %v"
+	inPast          = "field %v must be in future"
+	inPastSlice     = "field %v must be in future in element %v"
 )
 
 var (
@@ -1,9 +1,13 @@
 package validation
 
 import (
+	"errors"
 	"fmt"
+	"time"
 
+	"git.containerum.net/ch/user-manager/pkg/models"
 	kube_types "github.com/containerum/kube-client/pkg/model"
+	"github.com/goware/emailx"
 )
 
 //ValidateCreateGroup validates create group request
@@ -22,8 +26,11 @@ func ValidateCreateGroup(group kube_types.UserGroup) []error {
 func ValidateAddMembers(members kube_types.UserGroupMembers) []error {
 	var errs []error
 	for i, m := range members.Members {
-		if m.Access == "" {
+		switch kube_types.UserGroupAccess(m.Access) {
+		case "":
 			errs = append(errs, fmt.Errorf(isRequiredSlice, "access", i+1))
+		case kube_types.OwnerAccess, kube_types.NoAccess:
+			errs = append(errs, fmt.Errorf("access %v can't be granted in element %v", m.Access, i+1))
 		}
 		if m.Username == "" {
 			errs = append(errs, fmt.Errorf(isRequiredSlice, "username", i+1))
@@ -35,11 +42,164 @@ func ValidateAddMembers(members kube_types.UserGroupMembers) []error {
 	return nil
 }
 
+//ValidateGroupMembers validates add group members request with access expiration
+func ValidateGroupMembers(members models.GroupMembers) []error {
+	var errs []error
+	for i, m := range members.Members {
+		switch kube_types.UserGroupAccess(m.Access) {
+		case "":
+			errs = append(errs, fmt.Errorf(isRequiredSlice, "access", i+1))
+		case kube_types.OwnerAccess, kube_types.NoAccess:
+			errs = append(errs, fmt.Errorf("access %v can't be granted in element %v", m.Access, i+1))
+		}
+		if m.Username == "" {
+			errs = append(errs, fmt.Errorf(isRequiredSlice, "username", i+1))
+		}
+		if m.ExpiresAt != nil && !m.ExpiresAt.After(time.Now()) {
+			errs = append(errs, fmt.Errorf(inPastSlice, "expires_at", i+1))
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+//ValidateUpdateGroupMember validates update group member request with access expiration
+func ValidateUpdateGroupMember(member models.GroupMember) []error {
+	errs := ValidateUpdateMember(member.UserGroupMember)
+	if member.ExpiresAt != nil && !member.ExpiresAt.After(time.Now()) {
+		errs = append(errs, fmt.Errorf(inPast, "expires_at"))
+	}
+	if member.ExpiresAt != nil && member.RemoveExpiration {
+		errs = append(errs, errors.New("fields expires_at and remove_expiration are mutually exclusive"))
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
 //ValidateAddMembers validates add group members request
 func ValidateUpdateMember(member kube_types.UserGroupMember) []error {
 	var errs []error
-	if member.Access == "" {
+	switch kube_types.UserGroupAccess(member.Access) {
+	case "":
+		errs = append(errs, fmt.Errorf(isRequired, "access"))
+	case kube_types.OwnerAccess, kube_types.NoAccess:
+		errs = append(errs, fmt.Errorf("access %v can't be granted to member", member.Access))
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+//ValidateGroupOwnerTransfer validates group ownership transfer request
+func ValidateGroupOwnerTransfer(request models.GroupOwnerTransferRequest) []error {
+	var errs []error
+	if request.Login == "" {
+		errs = append(errs, fmt.Errorf(isRequired, "login"))
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+//ValidateGroupInvite validates group invite request
+func ValidateGroupInvite(request models.GroupInviteRequest) []error {
+	var errs []error
+	if request.Email == "" {
+		errs = append(errs, fmt.Errorf(isRequired, "email"))
+	} else if err := emailx.ValidateFast(request.Email); err != nil {
+		errs = append(errs, err)
+	}
+	switch kube_types.UserGroupAccess(request.Access) {
+	case "":
 		errs = append(errs, fmt.Errorf(isRequired, "access"))
+	case kube_types.OwnerAccess, kube_types.NoAccess:
+		errs = append(errs, fmt.Errorf("access %v can't be granted by invite", request.Access))
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+//ValidateGroupInviteToken validates group invite accept or decline request
+func ValidateGroupInviteToken(request models.GroupInviteToken) []error {
+	var errs []error
+	if request.Token == "" {
+		errs = append(errs, fmt.Errorf(isRequired, "token"))
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+//ValidateAddSubgroup validates add subgroup request
+func ValidateAddSubgroup(request models.SubgroupAddRequest) []error {
+	var errs []error
+	if request.Label == "" {
+		errs = append(errs, fmt.Errorf(isRequired, "label"))
+	}
+	switch kube_types.UserGroupAccess(request.Access) {
+	case "":
+		errs = append(errs, fmt.Errorf(isRequired, "access"))
+	case kube_types.OwnerAccess, kube_types.NoAccess:
+		errs = append(errs, fmt.Errorf("access %v can't be granted to subgroup", request.Access))
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+//ValidateUpdateGroup validates update group request
+func ValidateUpdateGroup(request models.GroupUpdateRequest) []error {
+	var errs []error
+	if request.Label != nil {
+		switch *request.Label {
+		case "":
+			errs = append(errs, fmt.Errorf(isRequired, "label"))
+		case "labelid", "labelidfull": // used as routes
+			errs = append(errs, fmt.Errorf("label %v is reserved", *request.Label))
+		}
+	}
+	if request.Label == nil && request.Description == nil && request.Labels == nil {
+		errs = append(errs, errors.New("nothing to update"))
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+//ValidateSyncGroupMembers validates group members sync request
+func ValidateSyncGroupMembers(members models.GroupMembers) []error {
+	errs := ValidateGroupMembers(members)
+	seen := make(map[string]bool)
+	for i, m := range members.Members {
+		if seen[m.Username] {
+			errs = append(errs, fmt.Errorf("duplicate username %v in element %v", m.Username, i+1))
+		}
+		seen[m.Username] = true
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+//ValidateGroupMembersFilter validates group members list filter
+func ValidateGroupMembersFilter(access string) []error {
+	var errs []error
+	switch kube_types.UserGroupAccess(access) {
+	case "", kube_types.GuestAccess, kube_types.MemberAccess, kube_types.MasterAccess, kube_types.AdminAccess, kube_types.OwnerAccess:
+	default:
+		errs = append(errs, fmt.Errorf("unknown access %v", access))
 	}
 	if len(errs) > 0 {
 		return errs
@@ -0,0 +1,31 @@
+package validation
+
+import (
+	"fmt"
+	"time"
+
+	"git.containerum.net/ch/user-manager/pkg/models"
+	"git.containerum.net/ch/user-manager/pkg/utils"
+)
+
+// ValidateIPRule validates IP rule
+func ValidateIPRule(rule models.IPRule) []error {
+	var errs []error
+	if rule.CIDR == "" {
+		errs = append(errs, fmt.Errorf(isRequired, "cidr"))
+	} else if _, err := utils.ParseNetwork(rule.CIDR); err != nil {
+		errs = append(errs, err)
+	}
+	switch rule.List {
+	case "", models.IPListBlock, models.IPListAllow:
+	default:
+		errs = append(errs, fmt.Errorf("unknown IP rules list %v", rule.List))
+	}
+	if rule.ExpiresAt != nil && !rule.ExpiresAt.After(time.Now()) {
+		errs = append(errs, fmt.Errorf(inPast, "expires_at"))
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
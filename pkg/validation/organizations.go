@@ -0,0 +1,32 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+
+	"git.containerum.net/ch/user-manager/pkg/models"
+)
+
+func ValidateOrganizationCreateRequest(req models.OrganizationCreateRequest) []error {
+	var errs []error
+	if strings.TrimSpace(req.Name) == "" {
+		errs = append(errs, fmt.Errorf(isRequired, "Name"))
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func ValidateUserOrganizationRequest(req models.UserOrganizationRequest) []error {
+	var errs []error
+	if req.OrgID == "" {
+		errs = append(errs, fmt.Errorf(isRequired, "OrgID"))
+	} else if !IsValidUUID(req.OrgID) {
+		errs = append(errs, errInvalidID)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
@@ -33,3 +33,20 @@ func ValidatePasswordRestoreRequest(link models.PasswordRestoreRequest) []error
 	}
 	return nil
 }
+
+func ValidateExpiredPasswordChangeRequest(request models.ExpiredPasswordChangeRequest) []error {
+	var errs []error
+	if request.Login == "" {
+		errs = append(errs, fmt.Errorf(isRequired, "Login"))
+	}
+	if request.CurrentPassword == "" {
+		errs = append(errs, fmt.Errorf(isRequired, "Current password"))
+	}
+	if request.NewPassword == "" {
+		errs = append(errs, fmt.Errorf(isRequired, "New password"))
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Password policy rules. Rule name is used as a key of error field, so client can show each violation separately.
+const (
+	PasswordRuleMinLength = "min_length"
+	PasswordRuleLower     = "lowercase"
+	PasswordRuleUpper     = "uppercase"
+	PasswordRuleDigit     = "digit"
+	PasswordRuleSymbol    = "symbol"
+	PasswordRuleNotLogin  = "not_login"
+	PasswordRuleNotReused = "not_reused"
+	PasswordRuleBreached  = "not_breached"
+)
+
+// PasswordRuleError describes violated password policy rule.
+type PasswordRuleError struct {
+	Rule    string
+	Message string
+}
+
+func (e *PasswordRuleError) Error() string {
+	return e.Message
+}
+
+// PasswordPolicy describes requirements for user passwords.
+type PasswordPolicy struct {
+	// MinLength is a minimal number of characters in password.
+	MinLength     int
+	RequireLower  bool
+	RequireUpper  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// HistorySize is a number of last user passwords which can't be reused. Zero value disables check.
+	HistorySize int
+}
+
+// Check returns violations of rules which can be checked without password history and breached passwords list.
+func (p PasswordPolicy) Check(login, password string) []error {
+	var errs []error
+	if utf8.RuneCountInString(password) < p.MinLength {
+		errs = append(errs, &PasswordRuleError{
+			Rule:    PasswordRuleMinLength,
+			Message: fmt.Sprintf("password should contain at least %d characters", p.MinLength),
+		})
+	}
+	var hasLower, hasUpper, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+	if p.RequireLower && !hasLower {
+		errs = append(errs, &PasswordRuleError{Rule: PasswordRuleLower, Message: "password should contain lowercase letter"})
+	}
+	if p.RequireUpper && !hasUpper {
+		errs = append(errs, &PasswordRuleError{Rule: PasswordRuleUpper, Message: "password should contain uppercase letter"})
+	}
+	if p.RequireDigit && !hasDigit {
+		errs = append(errs, &PasswordRuleError{Rule: PasswordRuleDigit, Message: "password should contain digit"})
+	}
+	if p.RequireSymbol && !hasSymbol {
+		errs = append(errs, &PasswordRuleError{Rule: PasswordRuleSymbol, Message: "password should contain special character"})
+	}
+	if login != "" && strings.EqualFold(password, login) {
+		errs = append(errs, &PasswordRuleError{Rule: PasswordRuleNotLogin, Message: "password should not be equal to login"})
+	}
+	return errs
+}
@@ -0,0 +1,39 @@
+package validation
+
+import (
+	"fmt"
+	"regexp"
+
+	"git.containerum.net/ch/user-manager/pkg/models"
+)
+
+var adminRoleNameRegexp = regexp.MustCompile(`^[a-z][a-z0-9-]{1,63}$`)
+
+func ValidateAdminRoleRequest(name string, req models.AdminRoleRequest) []error {
+	var errs []error
+	switch {
+	case !adminRoleNameRegexp.MatchString(name):
+		errs = append(errs, fmt.Errorf("role name %q should be 2-64 characters long and contain only lowercase latin letters, digits and '-'", name))
+	case name == "admin" || name == "user" || name == "service": // users.role values
+		errs = append(errs, fmt.Errorf("role name %v is reserved", name))
+	}
+	if len(req.Permissions) == 0 {
+		errs = append(errs, fmt.Errorf(isRequired, "Permissions"))
+	}
+	for i, perm := range req.Permissions {
+		known := false
+		for _, v := range models.Permissions {
+			if perm == v {
+				known = true
+				break
+			}
+		}
+		if !known {
+			errs = append(errs, fmt.Errorf("unknown permission %v in element %v", perm, i+1))
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
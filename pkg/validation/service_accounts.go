@@ -0,0 +1,56 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"git.containerum.net/ch/user-manager/pkg/models"
+)
+
+// service account names must not look like emails to avoid collisions with human users logins
+var serviceAccountNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]{2,63}$`)
+
+var (
+	errInvalidServiceAccountName = errors.New("Name should be 3-64 characters long and contain only latin letters, digits, '.', '_' and '-'")
+	errExpirationInPast          = errors.New("Expiration time should be in future")
+)
+
+func ValidateServiceAccountCreateRequest(req models.ServiceAccountCreateRequest) []error {
+	var errs []error
+	if req.Name == "" {
+		errs = append(errs, fmt.Errorf(isRequired, "Name"))
+	} else if !serviceAccountNameRegexp.MatchString(req.Name) {
+		errs = append(errs, errInvalidServiceAccountName)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func ValidateAPIKeyCreateRequest(req models.APIKeyCreateRequest) []error {
+	var errs []error
+	if req.Name == "" {
+		errs = append(errs, fmt.Errorf(isRequired, "Name"))
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		errs = append(errs, errExpirationInPast)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func ValidateAPIKeyLoginRequest(req models.APIKeyLoginRequest) []error {
+	var errs []error
+	if req.APIKey == "" {
+		errs = append(errs, fmt.Errorf(isRequired, "API key"))
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}